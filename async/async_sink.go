@@ -11,7 +11,7 @@ import (
 // AsyncSink wraps a Sink and processes trails asynchronously
 type AsyncSink struct {
 	sink       sink.Sink
-	queue      chan *gotrails.Trail
+	queue      chan *gotrails.TrailRecord
 	wg         sync.WaitGroup
 	closed     bool
 	closeMu    sync.Mutex
@@ -54,7 +54,7 @@ func NewAsyncSink(s sink.Sink, queueSize int, opts ...AsyncOption) *AsyncSink {
 
 	async := &AsyncSink{
 		sink:    s,
-		queue:   make(chan *gotrails.Trail, queueSize),
+		queue:   make(chan *gotrails.TrailRecord, queueSize),
 		workers: 1,
 	}
 
@@ -75,8 +75,8 @@ func NewAsyncSink(s sink.Sink, queueSize int, opts ...AsyncOption) *AsyncSink {
 func (a *AsyncSink) worker() {
 	defer a.wg.Done()
 
-	for trail := range a.queue {
-		if err := a.sink.Write(context.Background(), trail); err != nil {
+	for record := range a.queue {
+		if err := a.sink.Write(context.Background(), record); err != nil {
 			if a.onError != nil {
 				a.onError(err)
 			}
@@ -84,8 +84,8 @@ func (a *AsyncSink) worker() {
 	}
 }
 
-// Write queues a trail for async processing
-func (a *AsyncSink) Write(ctx context.Context, trail *gotrails.Trail) error {
+// Write queues a trail record for async processing
+func (a *AsyncSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
 	a.closeMu.Lock()
 	if a.closed {
 		a.closeMu.Unlock()
@@ -93,18 +93,15 @@ func (a *AsyncSink) Write(ctx context.Context, trail *gotrails.Trail) error {
 	}
 	a.closeMu.Unlock()
 
-	// Clone the trail to avoid race conditions
-	cloned := trail.Clone()
-
 	if a.dropOnFull {
 		select {
-		case a.queue <- cloned:
+		case a.queue <- record:
 		default:
 			// Queue full, drop the trail
 		}
 	} else {
 		select {
-		case a.queue <- cloned:
+		case a.queue <- record:
 		case <-ctx.Done():
 			return ctx.Err()
 		}
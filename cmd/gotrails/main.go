@@ -0,0 +1,276 @@
+// Command gotrails inspects stored audit trails.
+//
+//	gotrails verify [flags] <archive>...
+//
+// verify reads NDJSON archives (one trail per line) and segment sink
+// directories or files in the order given, recomputes every trail hash and
+// checks the hash chains, the signatures, the segment chains and, with -keys,
+// the signed segment manifests; without -keys it warns about segment
+// directories whose manifests go unverified. It exits 1 on any tamper
+// indication and 2 when the archives cannot be read, so it can gate nightly
+// integrity jobs. SQLite and other database archives are not supported;
+// export their trails to NDJSON first.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/sink"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitTampered = 1
+	exitError    = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(stderr, "usage: gotrails verify [flags] <archive>...")
+		return exitError
+	}
+	return verify(args[1:], stdout, stderr)
+}
+
+func verify(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keysFile := fs.String("keys", "", "file of `key-id=hex-secret` lines to verify signatures with")
+	allowUnsigned := fs.Bool("allow-unsigned", false, "do not report unsigned trails")
+	partition := fs.String("partition", "service", "how trails are split into chains: service or none")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: gotrails verify [flags] <archive>...")
+		return exitError
+	}
+
+	var opts []gotrails.IntegrityOption
+	var lookup func(id string) ([]byte, bool)
+	if *keysFile != "" {
+		keys, err := readKeys(*keysFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "gotrails: %v\n", err)
+			return exitError
+		}
+		lookup = func(id string) ([]byte, bool) {
+			key, ok := keys[id]
+			return key, ok
+		}
+		opts = append(opts, gotrails.WithIntegrityKeys(lookup))
+	}
+	if *allowUnsigned {
+		opts = append(opts, gotrails.WithIntegrityAllowUnsigned())
+	}
+	switch *partition {
+	case "service":
+	case "none":
+		opts = append(opts, gotrails.WithIntegrityPartition(func(*gotrails.Trail) string { return "" }))
+	default:
+		fmt.Fprintf(stderr, "gotrails: unknown partition %q\n", *partition)
+		return exitError
+	}
+
+	var trails []*gotrails.Trail
+	var problems []string
+	for _, path := range fs.Args() {
+		read, err := readArchive(path)
+		trails = append(trails, read...)
+		if err == nil {
+			err = verifyManifests(path, lookup, stderr)
+		}
+		switch {
+		case errors.Is(err, sink.ErrSegmentCorrupt), errors.Is(err, sink.ErrManifestInvalid), errors.Is(err, errMalformed):
+			problems = append(problems, err.Error())
+		case err != nil:
+			fmt.Fprintf(stderr, "gotrails: %v\n", err)
+			return exitError
+		}
+	}
+
+	i := 0
+	report := gotrails.CheckIntegrity(func() (*gotrails.Trail, bool) {
+		if i == len(trails) {
+			return nil, false
+		}
+		i++
+		return trails[i-1], true
+	}, opts...)
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(struct {
+			gotrails.IntegrityReport
+			Archives []string `json:"archive_issues,omitempty"`
+		}{report, problems})
+	} else {
+		printReport(stdout, report, problems)
+	}
+	if !report.Valid() || len(problems) > 0 {
+		return exitTampered
+	}
+	return exitOK
+}
+
+func printReport(w io.Writer, report gotrails.IntegrityReport, problems []string) {
+	for _, p := range problems {
+		fmt.Fprintf(w, "archive: %s\n", p)
+	}
+	for _, is := range report.Issues {
+		fmt.Fprintf(w, "trail %d (%s/%s) chain %q: %s", is.Index, is.TraceID, is.RequestID, is.Chain, is.Kind)
+		if is.Expected != "" || is.Actual != "" {
+			fmt.Fprintf(w, " expected %q, got %q", is.Expected, is.Actual)
+		}
+		fmt.Fprintln(w)
+	}
+	status := "OK"
+	if !report.Valid() || len(problems) > 0 {
+		status = "TAMPERED"
+	}
+	fmt.Fprintf(w, "%s: %d trails in %d chains, %d hash mismatches, %d gaps, %d unsigned, %d invalid signatures, %d archive issues\n",
+		status, report.Checked, len(report.Chains), report.HashMismatches, report.Gaps, report.Unsigned, report.InvalidSignatures, len(problems))
+}
+
+// errMalformed marks an archive line that is not a trail
+var errMalformed = errors.New("malformed archive")
+
+// readArchive reads the trails of an NDJSON file, a segment file or a
+// segment directory. Trails read before a segment or line problem are
+// returned along with the error.
+func readArchive(path string) ([]*gotrails.Trail, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		if _, err := sink.VerifySegments(path); err != nil {
+			trails, _ := readSegmentDir(path)
+			return trails, err
+		}
+		return readSegmentDir(path)
+	}
+	if strings.HasSuffix(path, ".seg") {
+		if _, err := sink.VerifySegment(path); err != nil {
+			trails, _ := readLines(path, segmentTrail)
+			return trails, err
+		}
+		return readLines(path, segmentTrail)
+	}
+	return readLines(path, func(line []byte) (json.RawMessage, bool) { return line, true })
+}
+
+// verifyManifests checks the signed manifests of a segment directory, if it
+// has any, warning on stderr when there are no keys to check them with
+func verifyManifests(path string, keys func(id string) ([]byte, bool), stderr io.Writer) error {
+	manifests, err := filepath.Glob(filepath.Join(path, "*.manifest.json"))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(path, "head.json")); len(manifests) == 0 && err != nil {
+		return nil
+	}
+	if keys == nil {
+		fmt.Fprintf(stderr, "gotrails: warning: %s: segment manifests not verified, pass -keys\n", path)
+		return nil
+	}
+	_, err = sink.VerifyManifests(path, keys)
+	return err
+}
+
+func readSegmentDir(dir string) ([]*gotrails.Trail, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var trails []*gotrails.Trail
+	for _, path := range paths {
+		read, err := readLines(path, segmentTrail)
+		trails = append(trails, read...)
+		if err != nil {
+			return trails, err
+		}
+	}
+	return trails, nil
+}
+
+// segmentTrail returns the trail of a segment record line, skipping the
+// header and seal lines
+func segmentTrail(line []byte) (json.RawMessage, bool) {
+	var l struct {
+		Trail json.RawMessage `json:"trail"`
+	}
+	if json.Unmarshal(line, &l) != nil || len(l.Trail) == 0 {
+		return nil, false
+	}
+	return l.Trail, true
+}
+
+func readLines(path string, extract func([]byte) (json.RawMessage, bool)) ([]*gotrails.Trail, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var trails []*gotrails.Trail
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<30)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+		data, ok := extract(line)
+		if !ok {
+			continue
+		}
+		t, err := gotrails.UnmarshalTrail(data)
+		if err != nil {
+			return trails, fmt.Errorf("%w: %s line %d: %v", errMalformed, path, n, err)
+		}
+		trails = append(trails, t)
+	}
+	return trails, scanner.Err()
+}
+
+// readKeys reads key-id=hex-secret lines, skipping blanks and # comments
+func readKeys(path string) (map[string][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string][]byte)
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, secret, ok := strings.Cut(line, "=")
+		key, err := hex.DecodeString(strings.TrimSpace(secret))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%s line %d: expected key-id=hex-secret", path, n+1)
+		}
+		keys[strings.TrimSpace(id)] = key
+	}
+	return keys, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/sink"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	cfg := gotrails.NewConfig(
+		gotrails.WithSigningKey("k1", key),
+		gotrails.WithChainManager(gotrails.NewChainManager(nil)),
+	)
+	keys := filepath.Join(dir, "keys")
+	if err := os.WriteFile(keys, []byte("# signing keys\nk1=3031323334353637383961626364656630313233343536373839616263646566\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	segments := filepath.Join(dir, "segments")
+	s, err := sink.NewSegmentSink(segments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var lines [][]byte
+	for _, id := range []string{"t1", "t2", "t3"} {
+		record := gotrails.NewTrail(id, "req", cfg).Finalize()
+		_ = s.Write(context.Background(), record)
+		data, _ := json.Marshal(record)
+		lines = append(lines, data)
+	}
+	_ = s.Close()
+
+	archive := filepath.Join(dir, "trails.ndjson")
+	if err := os.WriteFile(archive, append(bytes.Join(lines, []byte("\n")), '\n'), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	verify := func(args ...string) (int, string) {
+		var out bytes.Buffer
+		code := run(append([]string{"verify"}, args...), &out, &out)
+		return code, out.String()
+	}
+	if code, out := verify("-keys", keys, archive); code != exitOK || !strings.HasPrefix(out, "OK: 3 trails") {
+		t.Fatalf("expected intact archive, got %d: %s", code, out)
+	}
+	if code, out := verify("-keys", keys, segments); code != exitOK {
+		t.Fatalf("expected intact segments, got %d: %s", code, out)
+	}
+
+	// Dropping a trail breaks the chain
+	if err := os.WriteFile(archive, append(bytes.Join([][]byte{lines[0], lines[2]}, []byte("\n")), '\n'), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code, out := verify("-keys", keys, archive); code != exitTampered || !strings.Contains(out, gotrails.ChainBreakPrevHash) {
+		t.Fatalf("expected chain gap reported, got %d: %s", code, out)
+	}
+
+	// Editing a record breaks its hash and the segment chain
+	seg := filepath.Join(segments, "00000001.seg")
+	data, _ := os.ReadFile(seg)
+	_ = os.Chmod(seg, 0o600)
+	if err := os.WriteFile(seg, bytes.Replace(data, []byte(`"trace_id":"t2"`), []byte(`"trace_id":"tX"`), 1), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code, out := verify("-keys", keys, "-json", segments); code != exitTampered || !strings.Contains(out, "archive_issues") {
+		t.Fatalf("expected tampered segments, got %d: %s", code, out)
+	}
+
+	if code, _ := verify(filepath.Join(dir, "missing.ndjson")); code != exitError {
+		t.Fatalf("expected read error, got %d", code)
+	}
+}
+
+func TestVerifyWarnsAboutUnverifiedManifests(t *testing.T) {
+	segments := t.TempDir()
+	s, err := sink.NewSegmentSink(segments, sink.WithSegmentManifest("m1", []byte("manifest-key")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = s.Write(context.Background(), gotrails.NewTrail("t1", "req", gotrails.NewConfig()).Finalize())
+	_ = s.Close()
+
+	var out, errOut bytes.Buffer
+	if code := run([]string{"verify", "-allow-unsigned", segments}, &out, &errOut); code != exitOK {
+		t.Fatalf("expected intact segments, got %d: %s%s", code, out.String(), errOut.String())
+	}
+	if !strings.Contains(errOut.String(), "segment manifests not verified") {
+		t.Fatalf("expected a warning about the manifests, got %q", errOut.String())
+	}
+}
@@ -0,0 +1,85 @@
+package consumer
+
+import (
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/header"
+	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
+	"github.com/aizacoders/gotrails/sink"
+)
+
+// Consumer creates one trail per consumed message
+type Consumer struct {
+	cfg          *gotrails.Config
+	sink         sink.Sink
+	masker       *masker.Masker
+	headerFilter *header.Filter
+}
+
+// Option is an option for Consumer
+type Option func(*Consumer)
+
+// WithConfig sets the config
+func WithConfig(cfg *gotrails.Config) Option {
+	return func(c *Consumer) {
+		c.cfg = cfg
+	}
+}
+
+// WithSink sets the sink
+func WithSink(s sink.Sink) Option {
+	return func(c *Consumer) {
+		c.sink = s
+	}
+}
+
+// WithMasker sets the masker
+func WithMasker(msk *masker.Masker) Option {
+	return func(c *Consumer) {
+		c.masker = msk
+	}
+}
+
+// New creates a new Consumer. It panics if the config is invalid, see
+// gotrails.Config.Validate.
+func New(opts ...Option) *Consumer {
+	c := &Consumer{
+		cfg:  gotrails.DefaultConfig(),
+		sink: sink.NewStdoutSink(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	if err := c.cfg.Validate(); err != nil {
+		panic(err)
+	}
+
+	if c.masker == nil {
+		c.masker = masker.New(c.cfg.MaskerOptions()...)
+	}
+
+	// Initialize header filter with config
+	c.headerFilter = newHeaderFilter(c.cfg, gotrails.HeaderDirectionRequest)
+
+	return c
+}
+
+// parsePayload size-limits, parses and masks a message payload
+func (c *Consumer) parsePayload(data []byte) any {
+	if !c.cfg.EnableMasking {
+		return payload.JSON(data, nil, c.cfg.MaxRequestBodySize)
+	}
+	return payload.JSON(data, c.masker, c.cfg.MaxRequestBodySize)
+}
+
+// newHeaderFilter creates the header filter of the config for a direction
+func newHeaderFilter(cfg *gotrails.Config, dir gotrails.HeaderDirection) *header.Filter {
+	p := cfg.HeaderPolicy(dir)
+	return header.NewPolicyFilter(p.Include, p.Drop, p.Mask,
+		header.WithMaskValue(cfg.MaskValue),
+		header.WithEncrypter(cfg.Encrypter),
+		header.WithHashKey(cfg.MaskHashKey),
+		header.WithCredentialMasking(cfg.MaskHeaderCredentials),
+	)
+}
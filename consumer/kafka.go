@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/payload"
+)
+
+// KafkaHeader is a single Kafka record header
+type KafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+// KafkaMessage is a client-agnostic view of a consumed Kafka message
+type KafkaMessage struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []KafkaHeader
+}
+
+// KafkaHandler processes a consumed Kafka message
+type KafkaHandler func(ctx context.Context, msg *KafkaMessage) error
+
+// WrapHandler wraps a KafkaHandler so every consumed message gets its own trail
+func (c *Consumer) WrapHandler(next KafkaHandler) KafkaHandler {
+	return func(ctx context.Context, msg *KafkaMessage) error {
+		headers := make(http.Header, len(msg.Headers))
+		for _, h := range msg.Headers {
+			headers.Add(h.Key, string(h.Value))
+		}
+
+		// Extract trace and request IDs from message headers
+		traceID := gotrails.ExtractTraceIDFromHeader(headers, c.cfg)
+		requestID := gotrails.ExtractRequestIDFromHeader(headers, c.cfg)
+
+		// Create new trail
+		trail := gotrails.NewTrail(traceID, requestID, c.cfg)
+		if trail == nil {
+			return next(ctx, msg)
+		}
+
+		partition, offset := msg.Partition, msg.Offset
+		message := &gotrails.Message{
+			System:    "kafka",
+			Topic:     msg.Topic,
+			Partition: &partition,
+			Offset:    &offset,
+			BodySize:  int64(len(msg.Value)),
+		}
+		if !c.cfg.IsMetadataOnly() {
+			message.Key = payload.Key(msg.Key, c.masker)
+			message.Headers = c.headerFilter.Filter(headers)
+			message.Body = c.parsePayload(msg.Value)
+		}
+		trail.SetMessage(message)
+
+		// Add trail to context
+		ctx = gotrails.WithTrail(ctx, trail)
+		ctx = gotrails.WithConfig(ctx, c.cfg)
+		ctx = gotrails.WithFlush(ctx, c.sink.Write)
+
+		// Process message
+		err := next(ctx, msg)
+		if err != nil {
+			trail.AddError("consumer", err.Error())
+		}
+		trail.RecordContext(ctx)
+
+		// Finalize and flush trail
+		if record := trail.Finalize(); trail.Sampled() {
+			_ = c.sink.Write(context.Background(), record)
+		}
+		trail.Release()
+
+		return err
+	}
+}
+
+// WrapHandler wraps a KafkaHandler with a Consumer built from the given options
+func WrapHandler(next KafkaHandler, opts ...Option) KafkaHandler {
+	return New(opts...).WrapHandler(next)
+}
@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+type captureSink struct {
+	mu     sync.Mutex
+	trails []*gotrails.Trail
+}
+
+func (s *captureSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record != nil {
+		s.trails = append(s.trails, record.Trail)
+	}
+	return nil
+}
+
+func (s *captureSink) Close() error { return nil }
+func (s *captureSink) Name() string { return "capture" }
+
+func (s *captureSink) last() *gotrails.Trail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.trails) == 0 {
+		return nil
+	}
+	return s.trails[len(s.trails)-1]
+}
+
+func TestWrapHandlerCreatesTrailPerMessage(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	sink := &captureSink{}
+
+	var seen *gotrails.Trail
+	handler := WrapHandler(func(ctx context.Context, msg *KafkaMessage) error {
+		seen = gotrails.GetTrail(ctx)
+		return errors.New("boom")
+	}, WithConfig(cfg), WithSink(sink))
+
+	err := handler(context.Background(), &KafkaMessage{
+		Topic:     "payment.created",
+		Partition: 3,
+		Offset:    98123,
+		Key:       []byte("pay-123"),
+		Value:     []byte(`{"payment_id":"pay-123","token":"abc"}`),
+		Headers:   []KafkaHeader{{Key: "x-trace-id", Value: []byte("trace-abc")}},
+	})
+	if err == nil || err.Error() != "boom" {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+	if seen == nil {
+		t.Fatal("expected trail in handler context")
+	}
+
+	trail := sink.last()
+	if trail == nil {
+		t.Fatal("expected trail in sink")
+	}
+	if trail.TraceID != "trace-abc" {
+		t.Fatalf("expected trace id from header, got %s", trail.TraceID)
+	}
+	if trail.Message == nil || trail.Message.Topic != "payment.created" || *trail.Message.Offset != 98123 {
+		t.Fatalf("unexpected message: %+v", trail.Message)
+	}
+	body := trail.Message.Body.(map[string]any)
+	if body["token"] != cfg.MaskValue {
+		t.Fatalf("expected masked token, got %v", body["token"])
+	}
+	if len(trail.Errors) != 1 || trail.Errors[0].Message != "boom" {
+		t.Fatalf("expected handler error recorded, got %+v", trail.Errors)
+	}
+}
+
+func TestWrapHandlerRecordsFirstPartitionAndOffset(t *testing.T) {
+	sink := &captureSink{}
+	handler := WrapHandler(func(ctx context.Context, msg *KafkaMessage) error { return nil }, WithConfig(gotrails.NewConfig()), WithSink(sink))
+	_ = handler(context.Background(), &KafkaMessage{Topic: "payment.created"})
+
+	data, err := json.Marshal(sink.last().Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"partition":0`) || !strings.Contains(string(data), `"offset":0`) {
+		t.Fatalf("expected partition and offset 0 recorded, got %s", data)
+	}
+}
+
+func TestNewRejectsInvalidConfig(t *testing.T) {
+	cfg := gotrails.DefaultConfig()
+	cfg.SamplingRate = 2
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "SamplingRate") {
+			t.Fatalf("expected a panic naming the setting, got %v", r)
+		}
+	}()
+	New(WithConfig(cfg))
+}
@@ -0,0 +1,88 @@
+package consumer
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// QueueMessage is a broker-agnostic view of a message received from a queue
+type QueueMessage struct {
+	System            string // e.g. "sqs"
+	Queue             string
+	ID                string
+	Body              []byte
+	Attributes        map[string]string
+	ReceiveCount      int // number of times the message has been received, including this one
+	SentAt            time.Time
+	ReceivedAt        time.Time
+	VisibilityTimeout time.Duration
+}
+
+// QueueHandler processes a message received from a queue
+type QueueHandler func(ctx context.Context, msg *QueueMessage) error
+
+// WrapQueueHandler wraps a QueueHandler so every received message gets its own trail
+func (c *Consumer) WrapQueueHandler(next QueueHandler) QueueHandler {
+	return func(ctx context.Context, msg *QueueMessage) error {
+		receivedAt := msg.ReceivedAt
+		if receivedAt.IsZero() {
+			receivedAt = gotrails.ClockOf(c.cfg).Now()
+		}
+
+		headers := make(http.Header, len(msg.Attributes))
+		for k, v := range msg.Attributes {
+			headers.Add(k, v)
+		}
+
+		// Extract trace and request IDs from message attributes
+		traceID := gotrails.ExtractTraceIDFromHeader(headers, c.cfg)
+		requestID := gotrails.ExtractRequestIDFromHeader(headers, c.cfg)
+
+		// Create new trail
+		trail := gotrails.NewTrail(traceID, requestID, c.cfg)
+		if trail == nil {
+			return next(ctx, msg)
+		}
+
+		message := &gotrails.Message{
+			System:              msg.System,
+			Queue:               msg.Queue,
+			ID:                  msg.ID,
+			BodySize:            int64(len(msg.Body)),
+			RetryCount:          max(msg.ReceiveCount-1, 0),
+			VisibilityTimeoutMs: msg.VisibilityTimeout.Milliseconds(),
+		}
+		if !msg.SentAt.IsZero() {
+			message.QueueLatencyMs = receivedAt.Sub(msg.SentAt).Milliseconds()
+		}
+		if !c.cfg.IsMetadataOnly() {
+			message.Headers = c.headerFilter.Filter(headers)
+			message.Body = c.parsePayload(msg.Body)
+		}
+		trail.SetMessage(message)
+
+		// Add trail to context
+		ctx = gotrails.WithTrail(ctx, trail)
+		ctx = gotrails.WithConfig(ctx, c.cfg)
+		ctx = gotrails.WithFlush(ctx, c.sink.Write)
+
+		// Process message
+		err := next(ctx, msg)
+		if err != nil {
+			trail.AddError("consumer", err.Error())
+		}
+		trail.CompleteMessage(receivedAt)
+		trail.RecordContext(ctx)
+
+		// Finalize and flush trail
+		if record := trail.Finalize(); trail.Sampled() {
+			_ = c.sink.Write(context.Background(), record)
+		}
+		trail.Release()
+
+		return err
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/aizacoders/gotrails/consumer/sqs
+
+go 1.24.0
+
+require (
+	github.com/aizacoders/gotrails v0.0.0
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.52.1
+)
+
+require (
+	github.com/aws/smithy-go v1.28.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	go.opentelemetry.io/otel v1.39.0 // indirect
+	go.opentelemetry.io/otel/trace v1.39.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	google.golang.org/protobuf v1.36.10 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aizacoders/gotrails => ../..
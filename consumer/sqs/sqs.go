@@ -0,0 +1,68 @@
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aizacoders/gotrails/consumer"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SQSHandler processes a message received from SQS
+type SQSHandler func(ctx context.Context, msg types.Message) error
+
+// FromSQSMessage converts an SQS message into a QueueMessage. ReceivedAt is
+// left zero, so the message is considered received when the wrapped handler
+// starts, by the config's clock; set it on the result for batch receives.
+func FromSQSMessage(msg types.Message, queueURL string, visibilityTimeout time.Duration) *consumer.QueueMessage {
+	qm := &consumer.QueueMessage{
+		System:            "sqs",
+		Queue:             queueURL,
+		Attributes:        make(map[string]string, len(msg.MessageAttributes)+1),
+		VisibilityTimeout: visibilityTimeout,
+	}
+	if msg.MessageId != nil {
+		qm.ID = *msg.MessageId
+	}
+	if msg.Body != nil {
+		qm.Body = []byte(*msg.Body)
+	}
+
+	for k, v := range msg.MessageAttributes {
+		if v.StringValue != nil {
+			qm.Attributes[k] = *v.StringValue
+		}
+	}
+	if traceHeader, ok := msg.Attributes[string(types.MessageSystemAttributeNameAWSTraceHeader)]; ok {
+		qm.Attributes[string(types.MessageSystemAttributeNameAWSTraceHeader)] = traceHeader
+	}
+
+	if v, ok := msg.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)]; ok {
+		qm.ReceiveCount, _ = strconv.Atoi(v)
+	}
+	if v, ok := msg.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)]; ok {
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			qm.SentAt = time.UnixMilli(ms)
+		}
+	}
+
+	return qm
+}
+
+// WrapConsumerSQSHandler wraps an SQSHandler so every received message gets its own trail.
+// Request the ApproximateReceiveCount and SentTimestamp system attributes when
+// receiving to populate retry count and queue latency.
+func WrapConsumerSQSHandler(c *consumer.Consumer, queueURL string, visibilityTimeout time.Duration, next SQSHandler) SQSHandler {
+	return func(ctx context.Context, msg types.Message) error {
+		handler := c.WrapQueueHandler(func(ctx context.Context, _ *consumer.QueueMessage) error {
+			return next(ctx, msg)
+		})
+		return handler(ctx, FromSQSMessage(msg, queueURL, visibilityTimeout))
+	}
+}
+
+// WrapSQSHandler wraps an SQSHandler with a Consumer built from the given options
+func WrapSQSHandler(queueURL string, visibilityTimeout time.Duration, next SQSHandler, opts ...consumer.Option) SQSHandler {
+	return WrapConsumerSQSHandler(consumer.New(opts...), queueURL, visibilityTimeout, next)
+}
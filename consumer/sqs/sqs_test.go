@@ -0,0 +1,113 @@
+package sqs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/consumer"
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+type captureSink struct {
+	mu     sync.Mutex
+	trails []*gotrails.Trail
+}
+
+func (s *captureSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record != nil {
+		s.trails = append(s.trails, record.Trail)
+	}
+	return nil
+}
+
+func (s *captureSink) Close() error { return nil }
+func (s *captureSink) Name() string { return "capture" }
+
+func (s *captureSink) last() *gotrails.Trail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.trails) == 0 {
+		return nil
+	}
+	return s.trails[len(s.trails)-1]
+}
+
+func TestWrapSQSHandlerRecordsDelivery(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	sink := &captureSink{}
+
+	handler := WrapSQSHandler("https://sqs.local/orders", time.Millisecond, func(ctx context.Context, msg types.Message) error {
+		if !gotrails.HasTrail(ctx) {
+			t.Error("expected trail in handler context")
+		}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}, consumer.WithConfig(cfg), consumer.WithSink(sink))
+
+	id, body, traceID := "msg-1", `{"order_id":"ord-1","password":"secret"}`, "trace-sqs"
+	sent := time.Now().Add(-time.Second)
+	err := handler(context.Background(), types.Message{
+		MessageId: &id,
+		Body:      &body,
+		Attributes: map[string]string{
+			"ApproximateReceiveCount": "3",
+			"SentTimestamp":           strconv.FormatInt(sent.UnixMilli(), 10),
+		},
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"X-Trace-ID": {StringValue: &traceID},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trail := sink.last()
+	if trail == nil {
+		t.Fatal("expected trail in sink")
+	}
+	if trail.TraceID != traceID {
+		t.Fatalf("expected trace id from attributes, got %s", trail.TraceID)
+	}
+	msg := trail.Message
+	if msg == nil || msg.System != "sqs" || msg.ID != "msg-1" || msg.Queue != "https://sqs.local/orders" {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if msg.RetryCount != 2 {
+		t.Fatalf("expected retry count 2, got %d", msg.RetryCount)
+	}
+	if msg.QueueLatencyMs < 900 {
+		t.Fatalf("expected queue latency of about 1s, got %d", msg.QueueLatencyMs)
+	}
+	if !msg.VisibilityTimeoutExceeded {
+		t.Fatalf("expected visibility timeout breach, receive_to_complete_ms=%d", msg.ReceiveToCompleteMs)
+	}
+	if msg.Body.(map[string]any)["password"] != cfg.MaskValue {
+		t.Fatalf("expected masked password, got %v", msg.Body)
+	}
+}
+
+func TestWrapSQSHandlerUsesConfigClock(t *testing.T) {
+	clock := gotrails.NewManualClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	sink := &captureSink{}
+
+	handler := WrapSQSHandler("https://sqs.local/orders", 0, func(ctx context.Context, msg types.Message) error {
+		clock.Advance(250 * time.Millisecond)
+		return nil
+	}, consumer.WithConfig(gotrails.NewConfig(gotrails.WithClock(clock))), consumer.WithSink(sink))
+
+	body, sent := `{}`, strconv.FormatInt(clock.Now().Add(-2*time.Second).UnixMilli(), 10)
+	if err := handler(context.Background(), types.Message{Body: &body, Attributes: map[string]string{"SentTimestamp": sent}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := sink.last().Message
+	if msg.QueueLatencyMs != 2000 || msg.ReceiveToCompleteMs != 250 {
+		t.Fatalf("expected timings from the config clock, got queue=%d complete=%d", msg.QueueLatencyMs, msg.ReceiveToCompleteMs)
+	}
+}
@@ -48,11 +48,11 @@ func main() {
 	trail.SetMetadata("user_id", "u-123")
 
 	// Finalize
-	trail.Finalize()
+	record := trail.Finalize()
 
 	// Test JSON marshal directly
 	fmt.Println("=== Direct JSON Marshal ===")
-	data, err := json.MarshalIndent(trail, "", "  ")
+	data, err := json.MarshalIndent(record, "", "  ")
 	if err != nil {
 		fmt.Printf("Marshal error: %v\n", err)
 	} else {
@@ -62,7 +62,7 @@ func main() {
 	// Test with StdoutSink
 	fmt.Println("\n=== StdoutSink Output ===")
 	stdoutSink := sink.NewStdoutSink(sink.WithPrettyPrint(true))
-	err = stdoutSink.Write(context.Background(), trail)
+	err = stdoutSink.Write(context.Background(), record)
 	if err != nil {
 		fmt.Printf("Sink error: %v\n", err)
 	}
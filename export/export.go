@@ -0,0 +1,177 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// Record is the flattened, payload-free view of a trail handed to auditors:
+// who did what, when, where and with which outcome
+type Record struct {
+	Timestamp     time.Time `json:"timestamp"`
+	TraceID       string    `json:"trace_id"`
+	RequestID     string    `json:"request_id"`
+	Service       string    `json:"service"`
+	Environment   string    `json:"environment"`
+	ActorID       string    `json:"actor_id"`
+	ActorType     string    `json:"actor_type"`
+	ActorName     string    `json:"actor_name"`
+	ActorIP       string    `json:"actor_ip"`
+	AuthMethod    string    `json:"auth_method"`
+	Action        string    `json:"action"`         // e.g. "POST /v1/payments", "grpc /pay.Payments/Create"
+	Resources     string    `json:"resources"`      // "type:id:action" entries separated by "; "
+	ChangedFields string    `json:"changed_fields"` // changed field paths, values are never exported
+	Status        string    `json:"status"`         // HTTP status or gRPC code
+	Outcome       string    `json:"outcome"`
+	ErrorCategory string    `json:"error_category"`
+	ErrorCount    int       `json:"error_count"`
+	LatencyMs     int64     `json:"latency_ms"`
+	Hash          string    `json:"hash"`
+}
+
+// Columns are the CSV header names, in Record field order
+var Columns = []string{
+	"timestamp", "trace_id", "request_id", "service", "environment",
+	"actor_id", "actor_type", "actor_name", "actor_ip", "auth_method",
+	"action", "resources", "changed_fields", "status", "outcome",
+	"error_category", "error_count", "latency_ms", "hash",
+}
+
+// Flatten converts a finalized trail into a Record
+func Flatten(t *gotrails.Trail) Record {
+	r := Record{
+		Timestamp:     t.Timestamp,
+		TraceID:       t.TraceID,
+		RequestID:     t.RequestID,
+		Service:       t.Service,
+		Environment:   t.Environment,
+		Outcome:       string(t.Outcome),
+		ErrorCategory: string(t.ErrorCategory),
+		ErrorCount:    len(t.Errors),
+		LatencyMs:     t.LatencyMs,
+		Hash:          t.Hash,
+	}
+	if a := t.Actor; a != nil {
+		r.ActorID, r.ActorType, r.ActorName, r.ActorIP, r.AuthMethod = a.ID, a.Type, a.Name, a.IP, a.AuthMethod
+	}
+
+	switch {
+	case t.Request != nil:
+		r.Action = t.Request.Method + " " + t.Request.Path
+	case t.RPC != nil:
+		r.Action = "grpc " + t.RPC.Method
+	case t.Message != nil:
+		r.Action = strings.TrimSpace(t.Message.System + " " + t.Message.Topic + t.Message.Queue)
+	}
+	switch {
+	case t.Response != nil:
+		r.Status = strconv.Itoa(t.Response.Status)
+	case t.RPC != nil:
+		r.Status = t.RPC.Code
+	}
+
+	var resources, changed []string
+	for _, res := range t.Resources {
+		resources = append(resources, res.Type+":"+res.ID+":"+res.Action)
+		for _, c := range res.Changes {
+			changed = append(changed, res.Type+"."+c.Path)
+		}
+	}
+	r.Resources = strings.Join(resources, "; ")
+	r.ChangedFields = strings.Join(changed, ", ")
+	return r
+}
+
+// values returns the record as CSV fields in Columns order
+func (r Record) values() []string {
+	ts := ""
+	if !r.Timestamp.IsZero() {
+		ts = r.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	return []string{
+		ts, r.TraceID, r.RequestID, r.Service, r.Environment,
+		r.ActorID, r.ActorType, r.ActorName, r.ActorIP, r.AuthMethod,
+		r.Action, r.Resources, r.ChangedFields, r.Status, r.Outcome,
+		r.ErrorCategory, strconv.Itoa(r.ErrorCount), strconv.FormatInt(r.LatencyMs, 10), r.Hash,
+	}
+}
+
+// Writer writes flattened trails in an export format
+type Writer interface {
+	Write(t *gotrails.Trail) error
+	Close() error // flushes buffered output, the underlying writer is not closed
+}
+
+// CSVWriter writes flattened trails as CSV with a header row
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter creates a new CSVWriter
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes one trail as a CSV row
+func (c *CSVWriter) Write(t *gotrails.Trail) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(Columns); err != nil {
+			return err
+		}
+	}
+	return c.w.Write(Flatten(t).values())
+}
+
+// Close flushes the CSV output
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// JSONLWriter writes flattened trails as JSON lines
+type JSONLWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLWriter creates a new JSONLWriter
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes one trail as a JSON line
+func (j *JSONLWriter) Write(t *gotrails.Trail) error {
+	return j.enc.Encode(Flatten(t))
+}
+
+// Close does nothing, JSON lines are not buffered
+func (j *JSONLWriter) Close() error {
+	return nil
+}
+
+// Export writes every trail returned by next to w and closes w, returning
+// the number of trails written
+func Export(w Writer, next func() (*gotrails.Trail, bool)) (int, error) {
+	n := 0
+	for {
+		t, ok := next()
+		if !ok {
+			break
+		}
+		if t == nil {
+			continue
+		}
+		if err := w.Write(t); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, w.Close()
+}
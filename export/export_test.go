@@ -0,0 +1,78 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func exportTrails(t *testing.T) []*gotrails.Trail {
+	t.Helper()
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig(gotrails.WithServiceName("payments")))
+	trail.SetActor(gotrails.Actor{ID: "user-1", Type: gotrails.ActorTypeUser, IP: "10.0.0.1"})
+	trail.SetRequest(&gotrails.HTTPRequest{Method: "PATCH", Path: "/v1/payments/pay-1", Body: map[string]any{"amount": 100}})
+	trail.RecordChange(gotrails.Resource{Type: "payment", ID: "pay-1"}, map[string]any{"amount": 50}, map[string]any{"amount": 100})
+	trail.SetResponse(&gotrails.HTTPResponse{Status: 200, Body: map[string]any{"secret": "s3cr3t"}})
+	return []*gotrails.Trail{trail.Finalize().Trail}
+}
+
+func iterate(trails []*gotrails.Trail) func() (*gotrails.Trail, bool) {
+	i := 0
+	return func() (*gotrails.Trail, bool) {
+		if i == len(trails) {
+			return nil, false
+		}
+		i++
+		return trails[i-1], true
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	trails := exportTrails(t)
+	var buf bytes.Buffer
+	n, err := Export(NewCSVWriter(&buf), iterate(trails))
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 trail exported, got %d (%v)", n, err)
+	}
+	if strings.Contains(buf.String(), "s3cr3t") {
+		t.Fatalf("expected no payloads in export, got %s", buf.String())
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 2 || len(rows[1]) != len(Columns) {
+		t.Fatalf("unexpected rows: %v (%v)", rows, err)
+	}
+	row := make(map[string]string)
+	for i, col := range rows[0] {
+		row[col] = rows[1][i]
+	}
+	want := map[string]string{
+		"actor_id":       "user-1",
+		"action":         "PATCH /v1/payments/pay-1",
+		"resources":      "payment:pay-1:update",
+		"changed_fields": "payment.amount",
+		"status":         "200",
+		"outcome":        "success",
+		"hash":           trails[0].Hash,
+	}
+	for col, v := range want {
+		if row[col] != v {
+			t.Fatalf("expected %s=%q, got %q", col, v, row[col])
+		}
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Export(NewJSONLWriter(&buf), iterate(exportTrails(t))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var r Record
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil || r.TraceID != "trace-1" || r.Service != "payments" || r.ActorIP != "10.0.0.1" {
+		t.Fatalf("unexpected record %+v (%v)", r, err)
+	}
+}
@@ -0,0 +1,51 @@
+package gotrails
+
+// Actor identifies who performed the action recorded by a trail
+type Actor struct {
+	ID         string `json:"id"`
+	Type       string `json:"type,omitempty"`        // e.g. "user", "service", "system"
+	Name       string `json:"name,omitempty"`        // display name or username
+	AuthMethod string `json:"auth_method,omitempty"` // e.g. "password", "oauth", "api_key", "mtls"
+	IP         string `json:"ip,omitempty"`
+	SessionID  string `json:"session_id,omitempty"`
+}
+
+// Actor types
+const (
+	ActorTypeUser    = "user"
+	ActorTypeService = "service"
+	ActorTypeSystem  = "system"
+)
+
+// SetActor sets who performed the action. Fields whose JSON name is in the
+// configured mask fields (e.g. "ip" or "session_id") are masked. With a
+// Config.PseudonymKey the ID, and the fields named in PseudonymFields, are
+// replaced by their pseudonyms.
+func (t *Trail) SetActor(actor Actor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetActor") {
+		return
+	}
+	if t.cfg != nil {
+		msk := t.cfg.Masker()
+		for _, f := range []struct {
+			name  string
+			value *string
+		}{
+			{"id", &actor.ID},
+			{"name", &actor.Name},
+			{"ip", &actor.IP},
+			{"session_id", &actor.SessionID},
+		} {
+			switch {
+			case *f.value == "":
+			case msk.ShouldMask(f.name):
+				*f.value = msk.MaskedValue(f.name, *f.value)
+			case f.name == "id" || t.cfg.shouldPseudonymize(f.name):
+				*f.value = t.cfg.Pseudonym(*f.value)
+			}
+		}
+	}
+	t.Actor = &actor
+}
@@ -0,0 +1,260 @@
+package gotrails
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// Controls are runtime overrides of a config, changed while the service runs
+// (e.g. by AdminHandler during an incident) without a redeploy. They also
+// count the sampling decisions. A nil *Controls overrides nothing.
+type Controls struct {
+	samplingRate atomic.Pointer[float64] // overrides every configured rate, nil for none
+	bodiesOff    atomic.Bool
+
+	sampled    atomic.Int64
+	sampledOut atomic.Int64
+	kept       atomic.Int64
+}
+
+// ControlStats are the counters of Controls
+type ControlStats struct {
+	Sampled    int64 `json:"trails_sampled"`     // trails created
+	SampledOut int64 `json:"trails_sampled_out"` // trails skipped by sampling or the Sampler
+	Kept       int64 `json:"trails_kept"`        // skipped trails kept by the ErrorBias
+}
+
+// NewControls creates Controls overriding nothing
+func NewControls() *Controls {
+	return &Controls{}
+}
+
+// WithControls sets the runtime controls of the config
+func WithControls(c *Controls) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Controls = c
+	}
+}
+
+// SetSamplingRate overrides the sampling rate of every trail, including the
+// per-method rates. It rejects a rate outside 0.0 to 1.0.
+func (c *Controls) SetSamplingRate(rate float64) error {
+	if !(rate >= 0 && rate <= 1) {
+		return fmt.Errorf("gotrails: sampling rate %v is not between 0 and 1", rate)
+	}
+	c.samplingRate.Store(&rate)
+	return nil
+}
+
+// ResetSamplingRate removes the sampling rate override
+func (c *Controls) ResetSamplingRate() {
+	c.samplingRate.Store(nil)
+}
+
+// SamplingRate returns the overriding sampling rate, if any
+func (c *Controls) SamplingRate() (float64, bool) {
+	if c == nil {
+		return 0, false
+	}
+	if p := c.samplingRate.Load(); p != nil {
+		return *p, true
+	}
+	return 0, false
+}
+
+// SetBodyCapture turns body capture on or off. Off forces metadata-only
+// capture, see Config.IsMetadataOnly.
+func (c *Controls) SetBodyCapture(enabled bool) {
+	c.bodiesOff.Store(!enabled)
+}
+
+// BodyCapture reports whether body capture is left to the config
+func (c *Controls) BodyCapture() bool {
+	return c == nil || !c.bodiesOff.Load()
+}
+
+// Stats returns the counters
+func (c *Controls) Stats() ControlStats {
+	if c == nil {
+		return ControlStats{}
+	}
+	return ControlStats{Sampled: c.sampled.Load(), SampledOut: c.sampledOut.Load(), Kept: c.kept.Load()}
+}
+
+// count counts a sampling decision
+func (c *Controls) count(kept bool) {
+	switch {
+	case c == nil:
+	case kept:
+		c.sampled.Add(1)
+	default:
+		c.sampledOut.Add(1)
+	}
+}
+
+// countKept counts a dropped trail kept by the ErrorBias
+func (c *Controls) countKept() {
+	if c != nil {
+		c.kept.Add(1)
+	}
+}
+
+// QueueStats is a sink queue reported by AdminHandler, e.g. an async.AsyncSink
+type QueueStats interface {
+	QueueLength() int
+	QueueCapacity() int
+}
+
+// AdminOption is an option for AdminHandler
+type AdminOption func(*admin)
+
+// WithAdminToken sets the bearer token required by every admin request
+func WithAdminToken(token string) AdminOption {
+	return func(a *admin) {
+		a.token = token
+	}
+}
+
+// WithAdminQueue reports the length and capacity of a sink queue in the stats
+func WithAdminQueue(q QueueStats) AdminOption {
+	return func(a *admin) {
+		a.queue = q
+	}
+}
+
+type admin struct {
+	cfg   *Config
+	token string
+	queue QueueStats
+}
+
+// AdminHandler returns an HTTP handler controlling cfg at runtime through
+// its Controls, which it creates if cfg has none. Build it before serving
+// traffic. Every request needs "Authorization: Bearer <token>", see
+// WithAdminToken; without a token every request is refused. Endpoints,
+// relative to where the handler is mounted (see http.StripPrefix):
+//
+//	GET    /sampling   {"rate": 0.1, "override": true}
+//	PUT    /sampling   {"rate": 0.1} overrides every configured rate
+//	DELETE /sampling   removes the override
+//	GET    /capture    {"bodies": true}
+//	PUT    /capture    {"bodies": false} forces metadata-only capture
+//	GET    /stats      sampling counters, settings, adaptive rate and sink queue
+func AdminHandler(cfg *Config, opts ...AdminOption) http.Handler {
+	if cfg.Controls == nil {
+		cfg.Controls = NewControls()
+	}
+	a := &admin{cfg: cfg}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sampling", a.getSampling)
+	mux.HandleFunc("PUT /sampling", a.putSampling)
+	mux.HandleFunc("DELETE /sampling", a.deleteSampling)
+	mux.HandleFunc("GET /capture", a.getCapture)
+	mux.HandleFunc("PUT /capture", a.putCapture)
+	mux.HandleFunc("GET /stats", a.getStats)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAdminError(w, http.StatusUnauthorized, "missing or invalid admin token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized checks the bearer token in constant time
+func (a *admin) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && a.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+// samplingRate returns the overriding rate, or else the configured one
+func (a *admin) samplingRate() (rate float64, override bool) {
+	if rate, ok := a.cfg.Controls.SamplingRate(); ok {
+		return rate, true
+	}
+	return a.cfg.SamplingRate, false
+}
+
+func (a *admin) getSampling(w http.ResponseWriter, r *http.Request) {
+	rate, override := a.samplingRate()
+	writeAdminJSON(w, map[string]any{"rate": rate, "override": override})
+}
+
+func (a *admin) putSampling(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Rate *float64 `json:"rate"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Rate == nil {
+		writeAdminError(w, http.StatusBadRequest, `expected {"rate": <0.0 to 1.0>}`)
+		return
+	}
+	if err := a.cfg.Controls.SetSamplingRate(*req.Rate); err != nil {
+		writeAdminError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.getSampling(w, r)
+}
+
+func (a *admin) deleteSampling(w http.ResponseWriter, r *http.Request) {
+	a.cfg.Controls.ResetSamplingRate()
+	a.getSampling(w, r)
+}
+
+func (a *admin) getCapture(w http.ResponseWriter, r *http.Request) {
+	writeAdminJSON(w, map[string]any{"bodies": !a.cfg.IsMetadataOnly()})
+}
+
+func (a *admin) putCapture(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Bodies *bool `json:"bodies"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Bodies == nil {
+		writeAdminError(w, http.StatusBadRequest, `expected {"bodies": true or false}`)
+		return
+	}
+	a.cfg.Controls.SetBodyCapture(*req.Bodies)
+	a.getCapture(w, r)
+}
+
+func (a *admin) getStats(w http.ResponseWriter, r *http.Request) {
+	rate, _ := a.samplingRate()
+	counts := a.cfg.Controls.Stats()
+	stats := map[string]any{
+		"service":            a.cfg.ServiceName,
+		"environment":        a.cfg.Environment,
+		"sampling_rate":      rate,
+		"bodies":             !a.cfg.IsMetadataOnly(),
+		"trails_sampled":     counts.Sampled,
+		"trails_sampled_out": counts.SampledOut,
+		"trails_kept":        counts.Kept,
+	}
+	if r, ok := a.cfg.Sampler.(interface{ Rate() float64 }); ok {
+		stats["sampler_rate"] = r.Rate()
+	}
+	if a.queue != nil {
+		stats["queue_length"] = a.queue.QueueLength()
+		stats["queue_capacity"] = a.queue.QueueCapacity()
+	}
+	writeAdminJSON(w, stats)
+}
+
+func writeAdminJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
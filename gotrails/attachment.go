@@ -0,0 +1,59 @@
+package gotrails
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrNoAttachmentUploader is returned by Attach when the config has no AttachmentUploader
+var ErrNoAttachmentUploader = errors.New("gotrails: no attachment uploader configured")
+
+// Attachment references a payload stored outside the trail
+type Attachment struct {
+	Name        string `json:"name"`
+	URI         string `json:"uri"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+// AttachmentUploader stores an attachment payload (e.g. in blob storage) and
+// returns the URI it can be retrieved from
+type AttachmentUploader func(ctx context.Context, trail *Trail, name, contentType string, data []byte) (string, error)
+
+// Attach uploads data with the configured AttachmentUploader and references it
+// in the trail by URI, size and digest, keeping large payloads out of the
+// trail itself while preserving them for audit
+func (t *Trail) Attach(ctx context.Context, name, contentType string, data []byte) (Attachment, error) {
+	if t.cfg == nil || t.cfg.AttachmentUploader == nil {
+		return Attachment{}, ErrNoAttachmentUploader
+	}
+
+	sum := sha256.Sum256(data)
+	attachment := Attachment{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	uri, err := t.cfg.AttachmentUploader(ctx, t, name, contentType, data)
+	if err != nil {
+		return Attachment{}, err
+	}
+	attachment.URI = uri
+
+	t.AddAttachment(attachment)
+	return attachment, nil
+}
+
+// AddAttachment references a payload that has already been stored elsewhere
+func (t *Trail) AddAttachment(attachment Attachment) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddAttachment") {
+		return
+	}
+	t.Attachments = append(t.Attachments, attachment)
+}
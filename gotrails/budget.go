@@ -0,0 +1,133 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Truncation records a trail component dropped to fit the trail size budget
+type Truncation struct {
+	Path string `json:"path"` // e.g. "response.body", "integrations[2].request"
+	Size int    `json:"size"` // encoded size of the dropped component in bytes
+}
+
+// budgetCandidate is a droppable trail component
+type budgetCandidate struct {
+	path string
+	size int
+	drop func()
+}
+
+// enforceBudgetLocked drops the largest components of the trail until its
+// encoded size fits MaxTrailSize: bodies first, then integration payloads,
+// then step payloads. Dropped components are recorded in Truncated.
+func (t *Trail) enforceBudgetLocked() {
+	if t.cfg == nil || t.cfg.MaxTrailSize <= 0 {
+		return
+	}
+	total := encodedSize(t)
+	if total <= t.cfg.MaxTrailSize {
+		return
+	}
+
+	for _, phase := range [][]budgetCandidate{t.bodyCandidates(), t.integrationCandidates(), t.stepCandidates()} {
+		sort.SliceStable(phase, func(i, j int) bool { return phase[i].size > phase[j].size })
+		for _, c := range phase {
+			if total <= t.cfg.MaxTrailSize {
+				return
+			}
+			c.drop()
+			t.Truncated = append(t.Truncated, Truncation{Path: c.path, Size: c.size})
+			total -= c.size
+		}
+	}
+}
+
+// bodyCandidates returns the request, response, RPC and message bodies
+func (t *Trail) bodyCandidates() []budgetCandidate {
+	var candidates []budgetCandidate
+	if t.Request != nil && t.Request.Body != nil {
+		candidates = append(candidates, budgetCandidate{"request.body", encodedSize(t.Request.Body), func() {
+			r := *t.Request
+			r.Body = nil
+			t.Request = &r
+		}})
+	}
+	if t.Response != nil && t.Response.Body != nil {
+		candidates = append(candidates, budgetCandidate{"response.body", encodedSize(t.Response.Body), func() {
+			r := *t.Response
+			r.Body = nil
+			t.Response = &r
+		}})
+	}
+	if t.RPC != nil && t.RPC.Request != nil {
+		candidates = append(candidates, budgetCandidate{"rpc.request", encodedSize(t.RPC.Request), func() {
+			r := *t.RPC
+			r.Request = nil
+			t.RPC = &r
+		}})
+	}
+	if t.RPC != nil && t.RPC.Response != nil {
+		candidates = append(candidates, budgetCandidate{"rpc.response", encodedSize(t.RPC.Response), func() {
+			r := *t.RPC
+			r.Response = nil
+			t.RPC = &r
+		}})
+	}
+	if t.Message != nil && t.Message.Body != nil {
+		candidates = append(candidates, budgetCandidate{"message.body", encodedSize(t.Message.Body), func() {
+			m := *t.Message
+			m.Body = nil
+			t.Message = &m
+		}})
+	}
+	return candidates
+}
+
+// integrationCandidates returns the integration request and response payloads
+func (t *Trail) integrationCandidates() []budgetCandidate {
+	var candidates []budgetCandidate
+	for i := range t.Integrations {
+		integration := &t.Integrations[i]
+		if integration.Request != nil {
+			candidates = append(candidates, budgetCandidate{fmt.Sprintf("integrations[%d].request", i), encodedSize(integration.Request), func() {
+				integration.Request = nil
+			}})
+		}
+		if integration.Response != nil {
+			candidates = append(candidates, budgetCandidate{fmt.Sprintf("integrations[%d].response", i), encodedSize(integration.Response), func() {
+				integration.Response = nil
+			}})
+		}
+	}
+	return candidates
+}
+
+// stepCandidates returns the internal step request and response payloads
+func (t *Trail) stepCandidates() []budgetCandidate {
+	var candidates []budgetCandidate
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		if step.Request != nil {
+			candidates = append(candidates, budgetCandidate{fmt.Sprintf("internal_steps[%d].request", i), encodedSize(step.Request), func() {
+				step.Request = nil
+			}})
+		}
+		if step.Response != nil {
+			candidates = append(candidates, budgetCandidate{fmt.Sprintf("internal_steps[%d].response", i), encodedSize(step.Response), func() {
+				step.Response = nil
+			}})
+		}
+	}
+	return candidates
+}
+
+// encodedSize returns the JSON encoded size of v
+func encodedSize(v any) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
@@ -0,0 +1,112 @@
+package gotrails
+
+import "time"
+
+// Builder constructs a trail without middleware, for batch processors and
+// internal tools. Build finalizes the trail the same way the middlewares do.
+type Builder struct {
+	trail *Trail
+}
+
+// NewBuilder starts a trail with generated trace and request IDs. A nil cfg
+// uses DefaultConfig. Sampling is not applied.
+func NewBuilder(cfg *Config) *Builder {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	return &Builder{trail: newTrail(GenerateTraceID(), GenerateRequestID(), cfg)}
+}
+
+// TraceID sets the trace ID
+func (b *Builder) TraceID(id string) *Builder {
+	b.trail.TraceID = id
+	return b
+}
+
+// RequestID sets the request ID
+func (b *Builder) RequestID(id string) *Builder {
+	b.trail.RequestID = id
+	return b
+}
+
+// Timestamp sets when the work started, latency is measured from it
+func (b *Builder) Timestamp(ts time.Time) *Builder {
+	b.trail.Timestamp = ts.UTC()
+	b.trail.startTime = ts
+	return b
+}
+
+// Parent links the trail to the trail that caused it
+func (b *Builder) Parent(traceID, requestID string) *Builder {
+	b.trail.SetParent(traceID, requestID)
+	return b
+}
+
+// Actor sets who performed the action
+func (b *Builder) Actor(actor Actor) *Builder {
+	b.trail.SetActor(actor)
+	return b
+}
+
+// Resource declares a domain entity touched by the work
+func (b *Builder) Resource(resource Resource) *Builder {
+	b.trail.AddResource(resource)
+	return b
+}
+
+// Change records the before/after diff of an entity
+func (b *Builder) Change(resource Resource, before, after any) *Builder {
+	b.trail.RecordChange(resource, before, after)
+	return b
+}
+
+// Message sets the consumed message
+func (b *Builder) Message(msg *Message) *Builder {
+	b.trail.SetMessage(msg)
+	return b
+}
+
+// Step adds an internal step, generating its ID if unset
+func (b *Builder) Step(step InternalStep) *Builder {
+	if step.ID == "" {
+		step.ID = GenerateRequestID()
+	}
+	b.trail.AddInternalStep(step)
+	return b
+}
+
+// Integration adds an external call
+func (b *Builder) Integration(integration Integration) *Builder {
+	b.trail.AddIntegration(integration)
+	return b
+}
+
+// Error adds an error
+func (b *Builder) Error(source, message string) *Builder {
+	b.trail.AddError(source, message)
+	return b
+}
+
+// Event adds an event
+func (b *Builder) Event(name string, attrs map[string]any) *Builder {
+	b.trail.AddEvent(name, attrs)
+	return b
+}
+
+// Tag labels the trail
+func (b *Builder) Tag(tags ...string) *Builder {
+	b.trail.AddTag(tags...)
+	return b
+}
+
+// Metadata sets a metadata value
+func (b *Builder) Metadata(key string, value any) *Builder {
+	b.trail.SetMetadata(key, value)
+	return b
+}
+
+// Build finalizes the trail and returns its record, ready for a sink. The
+// builder must not be used afterwards.
+func (b *Builder) Build() *TrailRecord {
+	return b.trail.Finalize()
+}
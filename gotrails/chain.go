@@ -0,0 +1,152 @@
+package gotrails
+
+import (
+	"context"
+	"sync"
+)
+
+// ChainStore persists the head (last hash) of each trail chain
+type ChainStore interface {
+	LoadHead(ctx context.Context, chain string) (string, error)
+	SaveHead(ctx context.Context, chain, hash string) error
+}
+
+// MemoryChainStore is an in-process ChainStore, chains restart with the process
+type MemoryChainStore struct {
+	mu    sync.Mutex
+	heads map[string]string
+}
+
+// NewMemoryChainStore creates a new MemoryChainStore
+func NewMemoryChainStore() *MemoryChainStore {
+	return &MemoryChainStore{heads: make(map[string]string)}
+}
+
+// LoadHead returns the head of chain, "" for a new chain
+func (s *MemoryChainStore) LoadHead(ctx context.Context, chain string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.heads[chain], nil
+}
+
+// SaveHead sets the head of chain
+func (s *MemoryChainStore) SaveHead(ctx context.Context, chain, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heads[chain] = hash
+	return nil
+}
+
+// ChainManager links finalized trails into tamper-evident chains: each trail
+// gets the hash of the previous trail of its chain as prev_hash. Trails of the
+// same chain are finalized one at a time so that the chain never forks.
+type ChainManager struct {
+	store     ChainStore
+	partition func(*Trail) string
+	onError   func(error)
+
+	mu     sync.Mutex
+	chains map[string]*chainState
+}
+
+// chainState is the cached head of one chain
+type chainState struct {
+	mu     sync.Mutex
+	head   string
+	loaded bool
+}
+
+// ChainOption is an option for ChainManager
+type ChainOption func(*ChainManager)
+
+// WithChainPartition sets how trails are split into chains, by default one
+// chain per service
+func WithChainPartition(fn func(*Trail) string) ChainOption {
+	return func(m *ChainManager) {
+		m.partition = fn
+	}
+}
+
+// WithChainErrorHandler sets the handler for chain store errors. Chaining
+// continues from the in-memory head when the store fails.
+func WithChainErrorHandler(fn func(error)) ChainOption {
+	return func(m *ChainManager) {
+		m.onError = fn
+	}
+}
+
+// NewChainManager creates a ChainManager persisting chain heads in store, nil
+// uses a MemoryChainStore
+func NewChainManager(store ChainStore, opts ...ChainOption) *ChainManager {
+	if store == nil {
+		store = NewMemoryChainStore()
+	}
+	m := &ChainManager{
+		store:     store,
+		partition: func(t *Trail) string { return t.Service },
+		chains:    make(map[string]*chainState),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Head returns the current head of chain
+func (m *ChainManager) Head(chain string) string {
+	state := m.chain(chain)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	m.loadLocked(chain, state)
+	return state.head
+}
+
+func (m *ChainManager) chain(name string) *chainState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	state, ok := m.chains[name]
+	if !ok {
+		state = &chainState{}
+		m.chains[name] = state
+	}
+	return state
+}
+
+// loadLocked loads the chain head from the store once
+func (m *ChainManager) loadLocked(chain string, state *chainState) {
+	if state.loaded {
+		return
+	}
+	head, err := m.store.LoadHead(context.Background(), chain)
+	if err != nil {
+		m.handleError(err)
+		return
+	}
+	state.head = head
+	state.loaded = true
+}
+
+// finalize finalizes t as the next link of its chain
+func (m *ChainManager) finalize(t *Trail) *TrailRecord {
+	chain := m.partition(t)
+	state := m.chain(chain)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	m.loadLocked(chain, state)
+
+	t.SetPrevHash(state.head)
+	record := t.finalize()
+	record.encode() // the next link needs the hash now, even for lazy records
+
+	state.head = record.Hash
+	if err := m.store.SaveHead(context.Background(), chain, record.Hash); err != nil {
+		m.handleError(err)
+	}
+	return record
+}
+
+func (m *ChainManager) handleError(err error) {
+	if m.onError != nil {
+		m.onError(err)
+	}
+}
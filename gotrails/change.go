@@ -0,0 +1,196 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// FieldChange is a single field difference between two versions of an entity
+type FieldChange struct {
+	Path   string `json:"path"` // dotted field path, e.g. "address.city" or "items[2]"
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// RecordChange records the structural diff between two versions of an entity
+// as a resource of the trail. before and after are compared in their JSON
+// form; a nil before records a create and a nil after a delete, unless
+// resource.Action is set. Values of masked fields are masked and the diff is
+// capped at Config.MaxChangeSize.
+func (t *Trail) RecordChange(resource Resource, before, after any) {
+	b, a := normalizeJSON(before), normalizeJSON(after)
+	// Diff creates and deletes field by field against an empty entity
+	if _, ok := a.(map[string]any); ok && b == nil {
+		b = map[string]any{}
+	}
+	if _, ok := b.(map[string]any); ok && a == nil {
+		a = map[string]any{}
+	}
+	changes := diffValues("", b, a, nil)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("RecordChange") {
+		return
+	}
+
+	if resource.Action == "" {
+		switch {
+		case before == nil:
+			resource.Action = ResourceActionCreate
+		case after == nil:
+			resource.Action = ResourceActionDelete
+		default:
+			resource.Action = ResourceActionUpdate
+		}
+	}
+
+	budget := 0
+	if t.cfg != nil {
+		budget = t.cfg.MaxChangeSize
+	}
+	for _, c := range changes {
+		c.Before = t.maskChangeValue(c.Path, c.Before)
+		c.After = t.maskChangeValue(c.Path, c.After)
+		if budget > 0 {
+			size := encodedSize(c)
+			if size > budget {
+				resource.ChangesTruncated = true
+				break
+			}
+			budget -= size
+		}
+		resource.Changes = append(resource.Changes, c)
+	}
+	t.Resources = append(t.Resources, resource)
+}
+
+// maskChangeValue masks v as it sits at path in the entity
+func (t *Trail) maskChangeValue(path string, v any) any {
+	if t.cfg == nil || v == nil {
+		return v
+	}
+	return maskChange(t.cfg.Masker(), path, v)
+}
+
+// maskChange masks v by msk as it sits at change path, so that field, parent
+// and path rules apply to changes as they do to bodies
+func maskChange(msk *masker.Masker, path string, v any) any {
+	steps := changePathSteps(path)
+	wrapped := v
+	for i := len(steps) - 1; i >= 0; i-- {
+		if steps[i] == "" {
+			wrapped = []any{wrapped}
+		} else {
+			wrapped = map[string]any{steps[i]: wrapped}
+		}
+	}
+	masked := msk.MaskAny(wrapped)
+	for _, step := range steps {
+		switch m := masked.(type) {
+		case map[string]any:
+			masked = m[step]
+		case []any:
+			masked = m[0]
+		default:
+			// a parent of the changed field was masked as a whole
+			return masked
+		}
+	}
+	return masked
+}
+
+// changePathSteps splits a change path into field names, with "" standing for
+// an array index
+func changePathSteps(path string) []string {
+	var steps []string
+	for _, part := range strings.Split(path, ".") {
+		field, rest, _ := strings.Cut(part, "[")
+		if field != "" {
+			steps = append(steps, field)
+		}
+		if rest != "" {
+			steps = append(steps, make([]string, strings.Count(part, "["))...)
+		}
+	}
+	return steps
+}
+
+// lastPathField returns the last field name of a change path
+func lastPathField(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		switch path[i] {
+		case '.':
+			return path[i+1:]
+		case '[':
+			return lastPathField(path[:i])
+		}
+	}
+	return path
+}
+
+// normalizeJSON converts v to its decoded JSON form so that structs, maps and
+// pointers compare alike
+func normalizeJSON(v any) any {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// diffValues appends the differences between two decoded JSON values, in
+// field order, to changes
+func diffValues(path string, before, after any, changes []FieldChange) []FieldChange {
+	beforeMap, beforeIsMap := before.(map[string]any)
+	afterMap, afterIsMap := after.(map[string]any)
+	if beforeIsMap && afterIsMap {
+		keys := make([]string, 0, len(beforeMap)+len(afterMap))
+		for k := range beforeMap {
+			keys = append(keys, k)
+		}
+		for k := range afterMap {
+			if _, ok := beforeMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			changes = diffValues(joinPath(path, k), beforeMap[k], afterMap[k], changes)
+		}
+		return changes
+	}
+
+	beforeSlice, beforeIsSlice := before.([]any)
+	afterSlice, afterIsSlice := after.([]any)
+	if beforeIsSlice && afterIsSlice && len(beforeSlice) == len(afterSlice) {
+		for i := range beforeSlice {
+			changes = diffValues(path+"["+strconv.Itoa(i)+"]", beforeSlice[i], afterSlice[i], changes)
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(before, after) {
+		return changes
+	}
+	return append(changes, FieldChange{Path: path, Before: before, After: after})
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
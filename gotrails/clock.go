@@ -0,0 +1,78 @@
+package gotrails
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock is the time source used for trail timestamps and latencies
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+}
+
+// SystemClock is the Clock backed by the time package
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                  { return time.Now() }
+func (systemClock) Since(t time.Time) time.Duration { return time.Since(t) }
+
+// ManualClock is a Clock that only moves when advanced, for deterministic
+// tests and replays
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a ManualClock set to now
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the current time of the clock
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Since returns the time elapsed on the clock since t
+func (c *ManualClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// clock returns the configured clock or the system clock
+func (c *Config) clock() Clock {
+	if c != nil && c.Clock != nil {
+		return c.Clock
+	}
+	return SystemClock
+}
+
+// ClockOf returns the clock of cfg, or the system clock when cfg or its
+// Clock is nil
+func ClockOf(cfg *Config) Clock {
+	return cfg.clock()
+}
+
+// ClockFromContext returns the clock of the config in context, falling back
+// to the config of the trail in context and then to the system clock
+func ClockFromContext(ctx context.Context) Clock {
+	if cfg := GetConfig(ctx); cfg != nil {
+		return cfg.clock()
+	}
+	if trail := GetTrail(ctx); trail != nil {
+		return trail.cfg.clock()
+	}
+	return SystemClock
+}
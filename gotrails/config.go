@@ -1,37 +1,164 @@
 package gotrails
 
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// HeaderDirection identifies which headers a HeaderPolicy applies to
+type HeaderDirection string
+
+const (
+	HeaderDirectionRequest     HeaderDirection = "request"     // inbound request headers
+	HeaderDirectionResponse    HeaderDirection = "response"    // outbound response headers
+	HeaderDirectionIntegration HeaderDirection = "integration" // outgoing integration headers
+)
+
+// HeaderPolicy controls which headers are captured for one direction
+type HeaderPolicy struct {
+	Include []string // whitelist, nil means include all
+	Drop    []string // left out of the trail, unlike Config.ExcludeHeaders
+	Mask    []string // kept with the value masked
+}
+
 // Config holds the configuration for gotrails
 type Config struct {
 	// Service identification
 	ServiceName string
 	Environment string
+	EnvProfiles []EnvProfile // option layers per environment, applied by NewConfig
 
 	// Trace header configuration
 	TraceIDHeader   string
 	RequestIDHeader string
 
-	// Body size limits
+	// Body size limits, 0 captures no bodies
 	MaxRequestBodySize  int
 	MaxResponseBodySize int
+	MaxTrailSize        int  // encoded size budget of a whole trail in bytes, 0 means unlimited
+	MaxChangeSize       int  // encoded size budget of the field changes of one RecordChange, 0 means unlimited
+	DigestBodies        bool // record the digest and length of bodies larger than the size limits
 
 	// Masking configuration
-	MaskFields    []string
-	MaskValue     string
-	EnableMasking bool
+	MaskFields     []string
+	MaskPaths      []string                   // JSON paths masked whatever the field name, e.g. "items[].sku_secret"
+	MaskStrategies map[string]masker.Strategy // partial masking per field, e.g. masker.KeepLast(4)
+	MaskHashKey    []byte                     // replaces masked values by their keyed hash instead of MaskValue
+	MaskDetectors  []masker.Detector          // masks values by content whatever the field name, e.g. card numbers in notes
+	MaskEmbedded   bool                       // masks inside string values holding JSON documents or URLs
+	MaskExemptions []string                   // fields never masked by name, e.g. "token_count" despite "*token*"
+	MaskParents    []string                   // fields whose nested values are all masked, keeping arrays and objects
+	MaskProfiles   []*MaskProfile             // masking rules of specific routes and outbound hosts
+	MaskValue      string
+	EnableMasking  bool
+	Encrypter      masker.Encrypter // encrypts masked values instead of replacing them, see masker.AESEncrypter
+	Shredder       *Shredder        // encrypts personal fields with per-subject keys
+	PIIDetector    *PIIDetector     // finds personal data that escaped masking
+	SecretScanner  *SecretScanner   // masks credentials whatever their field name
+
+	// Masking limits against deeply nested or huge payloads, 0 disables a limit
+	MaskMaxDepth        int
+	MaskMaxElements     int
+	MaskMaxStringLength int // string values are cut to this many bytes
 
-	// Header filtering
+	// Anonymization: the actor ID and the values of PseudonymFields are
+	// replaced by keyed HMAC pseudonyms, nil key disables it
+	PseudonymKey    []byte
+	PseudonymFields []string
+
+	// Header filtering. ExcludeHeaders are kept with the value masked, like
+	// HeaderPolicy.Mask; use HeaderPolicy.Drop to leave headers out.
 	ExcludeHeaders []string
 	IncludeHeaders []string
 
+	// MaskHeaderCredentials masks headers named like credentials (e.g.
+	// X-Internal-Token) and Bearer/Basic credentials and JWTs in any header
+	MaskHeaderCredentials bool
+
+	// Per-direction header policies, nil falls back to IncludeHeaders/ExcludeHeaders
+	RequestHeaderPolicy     *HeaderPolicy
+	ResponseHeaderPolicy    *HeaderPolicy
+	IntegrationHeaderPolicy *HeaderPolicy
+
+	// Privacy configuration
+	MetadataOnly             bool     // If true, no header values or bodies are captured
+	MetadataOnlyEnvironments []string // Environments where metadata-only mode is forced on
+
 	// Sink configuration
 	EnableAsync    bool
 	AsyncQueueSize int
+	Sink           string // sinks built by sink.FromConfig, comma-separated: "stdout", "stdout:pretty", "noop" or "segment:<dir>"
 
 	// Sampling configuration
-	SamplingRate float64 // 0.0 = none, 1.0 = all, 0.5 = 50%
+	SamplingRate float64    // 0.0 = none, 1.0 = all, 0.5 = 50%
+	Sampler      Sampler    // decides on the trails kept by the rate, e.g. RateLimitSampler; nil keeps them all
+	ErrorBias    *ErrorBias // keeps the failures among the dropped trails, nil drops them up front
+
+	// Path filtering, a trailing * matches any suffix
+	ExcludePaths []string // paths that never create a trail
+	IncludePaths []string // paths that are always trailed, even if excluded
+
+	// Method filtering
+	SkipMethods         []string           // HTTP methods that never create a trail (e.g. OPTIONS, HEAD)
+	MethodSamplingRates map[string]float64 // per-method sampling rate, overrides SamplingRate
+
+	// Error classification, nil uses DefaultErrorClassifier
+	ErrorClassifier ErrorClassifier
+
+	// Outcome classification, nil uses DefaultOutcomeClassifier
+	OutcomeClassifier OutcomeClassifier
+
+	// Time source for timestamps and latencies, nil uses SystemClock
+	Clock Clock
+
+	// Out-of-band storage for attachments, nil disables Trail.Attach
+	AttachmentUploader AttachmentUploader
+
+	// Conversion of typed request, response and step bodies, nil stores them as given
+	BodySerializer BodySerializer
 
 	// Immutability flag
 	Immutable bool // If true, trail cannot be modified after Finalize
+
+	// Called with every write rejected by an immutable trail. It runs with
+	// the trail locked, so it must not call the trail's methods.
+	OnImmutableViolation func(ImmutabilityViolation)
+
+	// Trail hash algorithm, see HashSHA256; empty or unregistered uses SHA-256
+	HashAlgorithm string
+
+	// HMAC-SHA256 signing of the trail hash, nil key disables signing
+	SigningKey   []byte
+	SigningKeyID string
+	Keyring      *Keyring // rotating signing keys, takes precedence over SigningKey
+
+	// Trusted timestamping of the trail hash, nil disables timestamping
+	Timestamper Timestamper
+
+	// Tamper-evident chaining of finalized trails, nil disables chaining
+	ChainManager *ChainManager
+
+	// Reuse trails through a pool, see Trail.Release
+	EnablePooling bool
+
+	// Defer body parsing, masking, size budgeting and hashing until the
+	// record returned by Finalize is serialized
+	LazyBodyParsing bool
+
+	// JSON bodies of at least this many bytes are masked token by token into
+	// a json.RawMessage instead of a decoded tree, 0 disables streaming
+	StreamMaskThreshold int
+
+	// Runtime overrides of sampling and body capture, see AdminHandler
+	Controls *Controls
+
+	masker *configMasker // see Masker
 }
 
 // DefaultConfig returns the default configuration
@@ -43,6 +170,8 @@ func DefaultConfig() *Config {
 		RequestIDHeader:     "X-Request-ID",
 		MaxRequestBodySize:  64 * 1024, // 64KB
 		MaxResponseBodySize: 64 * 1024, // 64KB
+		MaxChangeSize:       16 * 1024, // 16KB
+		StreamMaskThreshold: 32 * 1024, // 32KB
 		MaskFields: []string{
 			"password",
 			"token",
@@ -55,19 +184,33 @@ func DefaultConfig() *Config {
 			"cvv",
 			"pin",
 		},
-		MaskValue:     "***MASKED***",
-		EnableMasking: true,
+		MaskValue:       "***MASKED***",
+		EnableMasking:   true,
+		MaskMaxDepth:    masker.DefaultMaxDepth,
+		MaskMaxElements: masker.DefaultMaxElements,
 		ExcludeHeaders: []string{
 			"authorization",
 			"cookie",
 			"set-cookie",
 			"x-api-key",
 		},
-		IncludeHeaders: nil, // nil means include all (except excluded)
-		EnableAsync:    true,
-		AsyncQueueSize: 1000,
-		SamplingRate:   1.0, // default to 100% sampling
-		Immutable:      false,
+		IncludeHeaders:        nil, // nil means include all (except excluded)
+		MaskHeaderCredentials: true,
+		MetadataOnly:          false,
+		EnableAsync:           true,
+		AsyncQueueSize:        1000,
+		SamplingRate:          1.0, // default to 100% sampling
+		ExcludePaths: []string{
+			"/health",
+			"/health/*",
+			"/healthz",
+			"/livez",
+			"/readyz",
+			"/metrics",
+			"/favicon.ico",
+		},
+		Immutable: false,
+		masker:    &configMasker{},
 	}
 }
 
@@ -116,13 +259,126 @@ func WithMaxResponseBodySize(size int) ConfigOption {
 	}
 }
 
-// WithMaskFields sets the fields to mask
+// WithBodyDigest records the digest and length of the complete body when a
+// request or response body exceeds its size limit and only a prefix is
+// stored. Request bodies the handler leaves unread are read to the end.
+func WithBodyDigest(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.DigestBodies = enabled
+	}
+}
+
+// WithMaxTrailSize sets the encoded size budget of a whole trail. Larger
+// trails have their biggest bodies and payloads dropped at Finalize.
+func WithMaxTrailSize(size int) ConfigOption {
+	return func(c *Config) {
+		c.MaxTrailSize = size
+	}
+}
+
+// WithMaxChangeSize sets the encoded size budget of the field changes
+// recorded by one RecordChange
+func WithMaxChangeSize(size int) ConfigOption {
+	return func(c *Config) {
+		c.MaxChangeSize = size
+	}
+}
+
+// WithMaskFields sets the fields to mask. A field containing * is a pattern
+// matching any run of characters in its place, e.g. "*password*" or "cc_*".
 func WithMaskFields(fields []string) ConfigOption {
 	return func(c *Config) {
 		c.MaskFields = fields
 	}
 }
 
+// WithMaskPaths masks the values at the given JSON paths of captured bodies,
+// so a field can be masked in one place and kept in others. Keys are
+// separated by dots, [] matches any array element and * any key, e.g.
+// "customer.contact.email" or "items[].sku_secret".
+func WithMaskPaths(paths ...string) ConfigOption {
+	return func(c *Config) {
+		c.MaskPaths = paths
+	}
+}
+
+// WithMaskStrategy masks the named field, or fields matching a * pattern,
+// with s instead of the mask value, e.g. masker.KeepLast(4) for card numbers
+func WithMaskStrategy(field string, s masker.Strategy) ConfigOption {
+	return func(c *Config) {
+		if c.MaskStrategies == nil {
+			c.MaskStrategies = make(map[string]masker.Strategy)
+		}
+		c.MaskStrategies[field] = s
+	}
+}
+
+// WithHashMasking replaces masked values by a keyed HMAC of the original
+// instead of the mask value, so the same email or user ID maps to the same
+// token across trails and stays correlatable
+func WithHashMasking(key []byte) ConfigOption {
+	return func(c *Config) {
+		c.MaskHashKey = key
+	}
+}
+
+// WithContentMasking masks values recognized by their content in any field,
+// e.g. a card number typed into "notes". Without detectors it uses
+// masker.DefaultDetectors: Luhn-valid card numbers, emails, phone numbers and
+// SSNs.
+func WithContentMasking(detectors ...masker.Detector) ConfigOption {
+	return func(c *Config) {
+		if len(detectors) == 0 {
+			detectors = masker.DefaultDetectors()
+		}
+		c.MaskDetectors = detectors
+	}
+}
+
+// WithEmbeddedMasking masks inside string values: double-encoded JSON
+// payloads are parsed and masked like bodies, and secrets in the query of
+// URLs within text are masked
+func WithEmbeddedMasking(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.MaskEmbedded = enabled
+	}
+}
+
+// WithMaskExemptions keeps fields that broad mask patterns would otherwise
+// catch, e.g. "token_count" or "pin_code_length". Exemptions may be patterns.
+func WithMaskExemptions(fields ...string) ConfigOption {
+	return func(c *Config) {
+		c.MaskExemptions = append(c.MaskExemptions, fields...)
+	}
+}
+
+// WithMaskParents masks every value nested under fields such as
+// "card_numbers" one by one, keeping the shape of their arrays and objects.
+// Parents may be patterns.
+func WithMaskParents(fields ...string) ConfigOption {
+	return func(c *Config) {
+		c.MaskParents = append(c.MaskParents, fields...)
+	}
+}
+
+// WithMaskLimits bounds masking work per body: maps and slices nested deeper
+// than maxDepth are replaced by a marker, and keys and elements beyond the
+// first maxElements are dropped. 0 disables a limit.
+func WithMaskLimits(maxDepth, maxElements int) ConfigOption {
+	return func(c *Config) {
+		c.MaskMaxDepth, c.MaskMaxElements = maxDepth, maxElements
+	}
+}
+
+// WithMaxStringLength cuts captured string values longer than n bytes, e.g. a
+// base64 blob in an otherwise small body, with a "…[truncated N bytes]"
+// suffix. 0 disables the limit.
+func WithMaxStringLength(n int) ConfigOption {
+	return func(c *Config) {
+		c.MaskMaxStringLength = n
+	}
+}
+
 // WithMaskValue sets the mask replacement value
 func WithMaskValue(value string) ConfigOption {
 	return func(c *Config) {
@@ -137,7 +393,7 @@ func WithMaskingEnabled(enabled bool) ConfigOption {
 	}
 }
 
-// WithExcludeHeaders sets headers to exclude from logging
+// WithExcludeHeaders sets headers whose values are masked
 func WithExcludeHeaders(headers []string) ConfigOption {
 	return func(c *Config) {
 		c.ExcludeHeaders = headers
@@ -151,6 +407,49 @@ func WithIncludeHeaders(headers []string) ConfigOption {
 	}
 }
 
+// WithHeaderCredentialMasking enables or disables masking of credentials in
+// headers outside the exclude lists
+func WithHeaderCredentialMasking(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.MaskHeaderCredentials = enabled
+	}
+}
+
+// WithRequestHeaderPolicy sets the header policy for inbound request headers
+func WithRequestHeaderPolicy(p HeaderPolicy) ConfigOption {
+	return func(c *Config) {
+		c.RequestHeaderPolicy = &p
+	}
+}
+
+// WithResponseHeaderPolicy sets the header policy for outbound response headers
+func WithResponseHeaderPolicy(p HeaderPolicy) ConfigOption {
+	return func(c *Config) {
+		c.ResponseHeaderPolicy = &p
+	}
+}
+
+// WithIntegrationHeaderPolicy sets the header policy for outgoing integration headers
+func WithIntegrationHeaderPolicy(p HeaderPolicy) ConfigOption {
+	return func(c *Config) {
+		c.IntegrationHeaderPolicy = &p
+	}
+}
+
+// WithMetadataOnly enables or disables metadata-only capture
+func WithMetadataOnly(enabled bool) ConfigOption {
+	return func(c *Config) {
+		c.MetadataOnly = enabled
+	}
+}
+
+// WithMetadataOnlyEnvironments forces metadata-only capture in the given environments
+func WithMetadataOnlyEnvironments(envs []string) ConfigOption {
+	return func(c *Config) {
+		c.MetadataOnlyEnvironments = envs
+	}
+}
+
 // WithAsyncEnabled enables or disables async processing
 func WithAsyncEnabled(enabled bool) ConfigOption {
 	return func(c *Config) {
@@ -165,6 +464,13 @@ func WithAsyncQueueSize(size int) ConfigOption {
 	}
 }
 
+// WithSink selects the sink built by sink.FromConfig
+func WithSink(spec string) ConfigOption {
+	return func(c *Config) {
+		c.Sink = spec
+	}
+}
+
 // WithSamplingRate sets the trace sampling rate
 func WithSamplingRate(rate float64) ConfigOption {
 	return func(c *Config) {
@@ -172,11 +478,393 @@ func WithSamplingRate(rate float64) ConfigOption {
 	}
 }
 
-// NewConfig creates a new Config with the given options
+// WithExcludePaths sets the paths that never create a trail
+func WithExcludePaths(paths []string) ConfigOption {
+	return func(c *Config) {
+		c.ExcludePaths = paths
+	}
+}
+
+// WithIncludePaths sets the paths that are always trailed, even if excluded
+func WithIncludePaths(paths []string) ConfigOption {
+	return func(c *Config) {
+		c.IncludePaths = paths
+	}
+}
+
+// WithSkipMethods sets HTTP methods that never create a trail
+func WithSkipMethods(methods []string) ConfigOption {
+	return func(c *Config) {
+		c.SkipMethods = methods
+	}
+}
+
+// WithMethodSamplingRate sets the sampling rate for a single HTTP method
+func WithMethodSamplingRate(method string, rate float64) ConfigOption {
+	return func(c *Config) {
+		if c.MethodSamplingRates == nil {
+			c.MethodSamplingRates = make(map[string]float64)
+		}
+		c.MethodSamplingRates[strings.ToUpper(method)] = rate
+	}
+}
+
+// WithErrorClassifier sets the classifier mapping errors and status codes to categories
+func WithErrorClassifier(c ErrorClassifier) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ErrorClassifier = c
+	}
+}
+
+// WithOutcomeClassifier sets the function deriving the trail outcome at Finalize
+func WithOutcomeClassifier(c OutcomeClassifier) ConfigOption {
+	return func(cfg *Config) {
+		cfg.OutcomeClassifier = c
+	}
+}
+
+// WithClock sets the time source used for timestamps and latencies
+func WithClock(c Clock) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Clock = c
+	}
+}
+
+// WithAttachmentUploader sets where Trail.Attach stores attachment payloads
+func WithAttachmentUploader(u AttachmentUploader) ConfigOption {
+	return func(cfg *Config) {
+		cfg.AttachmentUploader = u
+	}
+}
+
+// WithHashAlgorithm sets the trail hash algorithm, recorded in the trail as
+// hash_alg so verifiers recompute with the same algorithm
+func WithHashAlgorithm(name string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.HashAlgorithm = name
+	}
+}
+
+// WithSigningKey signs the hash of every finalized trail with HMAC-SHA256.
+// keyID is written to the trail so verifiers can pick the key, e.g. after a
+// rotation.
+func WithSigningKey(keyID string, key []byte) ConfigOption {
+	return func(cfg *Config) {
+		cfg.SigningKeyID = keyID
+		cfg.SigningKey = key
+	}
+}
+
+// WithFieldEncryption encrypts the values of masked fields instead of
+// replacing them with the mask value, e.g. with masker.NewAESEncrypter
+func WithFieldEncryption(e masker.Encrypter) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Encrypter = e
+	}
+}
+
+// WithCryptoShredding encrypts the personal fields of every finalized trail
+// with a data key of its subject, see Shredder
+func WithCryptoShredding(s *Shredder) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Shredder = s
+	}
+}
+
+// WithPIIDetection scans every finalized trail for personal data that
+// escaped masking, see PIIDetector
+func WithPIIDetection(d *PIIDetector) ConfigOption {
+	return func(cfg *Config) {
+		cfg.PIIDetector = d
+	}
+}
+
+// WithImmutable makes trails reject every write once finalized
+func WithImmutable(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Immutable = enabled
+	}
+}
+
+// WithImmutableViolationHandler sets a handler called with every write an
+// immutable trail rejects, e.g. to log or count handlers touching finalized
+// trails. It runs with the trail locked, so it must not call the trail's
+// methods.
+func WithImmutableViolationHandler(fn func(ImmutabilityViolation)) ConfigOption {
+	return func(cfg *Config) {
+		cfg.OnImmutableViolation = fn
+	}
+}
+
+// WithSecretScanning masks credentials found anywhere in every finalized
+// trail, whatever the field or header is named, see SecretScanner
+func WithSecretScanning(s *SecretScanner) ConfigOption {
+	return func(cfg *Config) {
+		cfg.SecretScanner = s
+	}
+}
+
+// WithPseudonymization replaces the actor ID, and the values of the named
+// fields in bodies and metadata, by keyed HMAC pseudonyms, so trails of one
+// user stay linkable without storing the identifier
+func WithPseudonymization(key []byte, fields ...string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.PseudonymKey = key
+		cfg.PseudonymFields = fields
+	}
+}
+
+// WithKeyring signs the hash of every finalized trail with the active version
+// of a rotating key
+func WithKeyring(k *Keyring) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Keyring = k
+	}
+}
+
+// WithTimestamper requests a trusted timestamp for the hash of every
+// finalized trail. The request runs inside Finalize, so prefer anchoring
+// batches (sink.WithAnchorTimestamper) on latency sensitive paths.
+func WithTimestamper(ts Timestamper) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Timestamper = ts
+	}
+}
+
+// WithChainManager links every finalized trail to the previous trail of its
+// chain through prev_hash
+func WithChainManager(m *ChainManager) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ChainManager = m
+	}
+}
+
+// WithPooling reuses trails and their step, integration and error slices
+// across requests. Only enable it when handlers do not touch the trail after
+// they return; background work should use DetachTrail.
+func WithPooling(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.EnablePooling = enabled
+	}
+}
+
+// WithLazyBodyParsing keeps captured bodies as raw bytes and defers their
+// parsing and masking, together with the size budget and the hash, until a
+// sink serializes the trail record. This moves the work off the request path
+// and skips it for records a sink drops. Handlers must not modify values they
+// attached to the trail after it is finalized.
+func WithLazyBodyParsing(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.LazyBodyParsing = enabled
+	}
+}
+
+// WithStreamMasking masks JSON bodies of at least threshold bytes with
+// masker.MaskJSONStream, avoiding the memory spike of decoding large bodies
+// into maps. Such bodies are stored as a json.RawMessage. 0 disables it.
+func WithStreamMasking(threshold int) ConfigOption {
+	return func(cfg *Config) {
+		cfg.StreamMaskThreshold = threshold
+	}
+}
+
+// StreamsMasking reports whether a JSON body of size bytes is masked by
+// streaming
+func (c *Config) StreamsMasking(size int) bool {
+	return c.StreamMaskThreshold > 0 && size >= c.StreamMaskThreshold
+}
+
+// WithBodySerializer sets how typed request, response and step bodies are
+// converted before they are stored in the trail
+func WithBodySerializer(s BodySerializer) ConfigOption {
+	return func(cfg *Config) {
+		cfg.BodySerializer = s
+	}
+}
+
+// HeaderPolicy returns the header policy for the given direction. Directions
+// without a dedicated policy use IncludeHeaders and mask ExcludeHeaders.
+func (c *Config) HeaderPolicy(dir HeaderDirection) HeaderPolicy {
+	var p *HeaderPolicy
+	switch dir {
+	case HeaderDirectionRequest:
+		p = c.RequestHeaderPolicy
+	case HeaderDirectionResponse:
+		p = c.ResponseHeaderPolicy
+	case HeaderDirectionIntegration:
+		p = c.IntegrationHeaderPolicy
+	}
+	if p != nil {
+		return *p
+	}
+	return HeaderPolicy{
+		Include: c.IncludeHeaders,
+		Mask:    c.ExcludeHeaders,
+	}
+}
+
+// IsMetadataOnly reports whether only request metadata (method, path, status,
+// latency, IDs and sizes) should be captured, without header values or
+// bodies. Turning body capture off in the Controls forces it.
+func (c *Config) IsMetadataOnly() bool {
+	if c.MetadataOnly || !c.Controls.BodyCapture() {
+		return true
+	}
+	for _, env := range c.MetadataOnlyEnvironments {
+		if strings.EqualFold(env, c.Environment) {
+			return true
+		}
+	}
+	return false
+}
+
+// configMasker is the masker of a config, built on first use
+type configMasker struct {
+	once   sync.Once
+	masker *masker.Masker
+}
+
+// Masker returns the masker of the config, built from MaskerOptions on first
+// use and shared afterwards. Change the masking settings before using it.
+func (c *Config) Masker() *masker.Masker {
+	if c.masker == nil {
+		return masker.New(c.MaskerOptions()...)
+	}
+	c.masker.once.Do(func() {
+		c.masker.masker = masker.New(c.MaskerOptions()...)
+	})
+	return c.masker.masker
+}
+
+// MaskerOptions returns the options of a masker.Masker masking like the
+// config
+func (c *Config) MaskerOptions() []masker.Option {
+	return []masker.Option{
+		masker.WithFields(c.MaskFields),
+		masker.WithMaskValue(c.MaskValue),
+		masker.WithEncrypter(c.Encrypter),
+		masker.WithPaths(c.MaskPaths),
+		masker.WithStrategies(c.MaskStrategies),
+		masker.WithHashKey(c.MaskHashKey),
+		masker.WithDetectors(c.MaskDetectors),
+		masker.WithEmbedded(c.MaskEmbedded),
+		masker.WithExemptions(c.MaskExemptions),
+		masker.WithParents(c.MaskParents),
+		masker.WithLimits(c.MaskMaxDepth, c.MaskMaxElements),
+		masker.WithMaxStringLength(c.MaskMaxStringLength),
+		masker.WithEnabled(c.EnableMasking),
+	}
+}
+
+// ShouldTracePath reports whether a request path should create a trail
+func (c *Config) ShouldTracePath(path string) bool {
+	for _, p := range c.IncludePaths {
+		if matchPath(p, path) {
+			return true
+		}
+	}
+	for _, p := range c.ExcludePaths {
+		if matchPath(p, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPath matches a path against a pattern, where a trailing * matches any suffix
+func matchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return pattern == path
+}
+
+// SamplingRateForMethod returns the sampling rate for the given HTTP method.
+// Skipped methods have a rate of 0.
+func (c *Config) SamplingRateForMethod(method string) float64 {
+	for _, m := range c.SkipMethods {
+		if strings.EqualFold(m, method) {
+			return 0
+		}
+	}
+	for m, rate := range c.MethodSamplingRates {
+		if strings.EqualFold(m, method) {
+			return rate
+		}
+	}
+	return c.SamplingRate
+}
+
+// NewConfig creates a new Config with the given options, then applies the
+// profile of its environment, which therefore wins over the options. It
+// panics if the result is invalid, see Validate.
 func NewConfig(opts ...ConfigOption) *Config {
+	cfg := newConfig(opts)
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// newConfig applies opts and the environment profile to the defaults
+func newConfig(opts []ConfigOption) *Config {
 	cfg := DefaultConfig()
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	cfg.applyEnvProfile()
 	return cfg
 }
+
+// Validate reports settings that would make gotrails misbehave silently, all
+// of them in one error naming each setting and how to fix it
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	const noLimit, noBodies = "disable the limit", "capture no bodies"
+	for _, size := range []struct {
+		name, option, zero string
+		value              int
+	}{
+		{"MaxRequestBodySize", "WithMaxRequestBodySize", noBodies, c.MaxRequestBodySize},
+		{"MaxResponseBodySize", "WithMaxResponseBodySize", noBodies, c.MaxResponseBodySize},
+		{"MaxTrailSize", "WithMaxTrailSize", noLimit, c.MaxTrailSize},
+		{"MaxChangeSize", "WithMaxChangeSize", noLimit, c.MaxChangeSize},
+		{"MaskMaxDepth", "WithMaskLimits", noLimit, c.MaskMaxDepth},
+		{"MaskMaxElements", "WithMaskLimits", noLimit, c.MaskMaxElements},
+		{"MaskMaxStringLength", "WithMaxStringLength", noLimit, c.MaskMaxStringLength},
+		{"StreamMaskThreshold", "WithStreamMasking", "disable streaming", c.StreamMaskThreshold},
+	} {
+		check(size.value >= 0, "%s is %d: use 0 to %s or a positive size (%s)", size.name, size.value, size.zero, size.option)
+	}
+	check(c.SamplingRate >= 0 && c.SamplingRate <= 1, "SamplingRate is %v: use a rate between 0 and 1 (WithSamplingRate)", c.SamplingRate)
+	for _, method := range slices.Sorted(maps.Keys(c.MethodSamplingRates)) {
+		rate := c.MethodSamplingRates[method]
+		check(rate >= 0 && rate <= 1, "MethodSamplingRates[%s] is %v: use a rate between 0 and 1 (WithMethodSamplingRate)", method, rate)
+	}
+	if b := c.ErrorBias; b != nil {
+		for _, class := range slices.Sorted(maps.Keys(b.StatusClasses)) {
+			rate := b.StatusClasses[class]
+			check(class >= 1 && class <= 5, "ErrorBias.StatusClasses has class %d: use 1 to 5, e.g. 5 for 5xx (WithErrorBias)", class)
+			check(rate >= 0 && rate <= 1, "ErrorBias.StatusClasses[%d] is %v: use a rate between 0 and 1 (WithErrorBias)", class, rate)
+		}
+	}
+	check(!c.EnableAsync || c.AsyncQueueSize > 0, "AsyncQueueSize is %d with async enabled: use a positive size (WithAsyncQueueSize) or disable async", c.AsyncQueueSize)
+	check(c.ServiceName != "" || !isProduction(c.Environment), "ServiceName is empty in %s: trails could not be attributed (WithServiceName)", c.Environment)
+	check(c.TraceIDHeader != "" && c.RequestIDHeader != "", "TraceIDHeader and RequestIDHeader must be set (WithTraceIDHeader, WithRequestIDHeader)")
+	_, registered := newHash(c.HashAlgorithm)
+	check(registered, "HashAlgorithm %q is not registered: use sha256, sha512 or register it first (WithHashAlgorithm, RegisterHashAlgorithm)", c.HashAlgorithm)
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("gotrails: invalid config: %w", errors.Join(errs...))
+}
+
+// isProduction reports whether env names a production environment
+func isProduction(env string) bool {
+	return strings.EqualFold(env, "production") || strings.EqualFold(env, "prod")
+}
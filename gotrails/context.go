@@ -10,6 +10,8 @@ type contextKey string
 const (
 	trailContextKey  contextKey = "gotrails_trail"
 	configContextKey contextKey = "gotrails_config"
+	stepContextKey   contextKey = "gotrails_step"
+	flushContextKey  contextKey = "gotrails_flush"
 )
 
 // WithTrail adds a Trail to the context
@@ -66,6 +68,20 @@ func AddErrorToContext(ctx context.Context, source, message string) {
 	}
 }
 
+// AddEventToContext adds an event to the trail in context
+func AddEventToContext(ctx context.Context, name string, attrs map[string]any) {
+	if trail := GetTrail(ctx); trail != nil {
+		trail.AddEvent(name, attrs)
+	}
+}
+
+// AddTagToContext labels the trail in context with one or more tags
+func AddTagToContext(ctx context.Context, tags ...string) {
+	if trail := GetTrail(ctx); trail != nil {
+		trail.AddTag(tags...)
+	}
+}
+
 // SetMetadataToContext sets metadata to the trail in context
 func SetMetadataToContext(ctx context.Context, key string, value any) {
 	if trail := GetTrail(ctx); trail != nil {
@@ -73,9 +89,36 @@ func SetMetadataToContext(ctx context.Context, key string, value any) {
 	}
 }
 
-// AddInternalStepToContext adds an internal step to the trail in context
+// AddInternalStepToContext adds an internal step to the trail in context. A step
+// without a ParentID becomes a child of the current step in context, if any.
 func AddInternalStepToContext(ctx context.Context, step InternalStep) {
+	if parentID := StepIDFromContext(ctx); step.ParentID == "" && parentID != step.ID {
+		step.ParentID = parentID
+	}
 	if trail := GetTrail(ctx); trail != nil {
 		trail.AddInternalStep(step)
 	}
 }
+
+// AttachToContext uploads data as an attachment of the trail in context
+func AttachToContext(ctx context.Context, name, contentType string, data []byte) (Attachment, error) {
+	trail := GetTrail(ctx)
+	if trail == nil {
+		return Attachment{}, nil
+	}
+	return trail.Attach(ctx, name, contentType, data)
+}
+
+// SetActorToContext sets the actor of the trail in context
+func SetActorToContext(ctx context.Context, actor Actor) {
+	if trail := GetTrail(ctx); trail != nil {
+		trail.SetActor(actor)
+	}
+}
+
+// AddResourceToContext declares a domain entity touched by the trail in context
+func AddResourceToContext(ctx context.Context, resource Resource) {
+	if trail := GetTrail(ctx); trail != nil {
+		trail.AddResource(resource)
+	}
+}
@@ -0,0 +1,53 @@
+package gotrails
+
+import (
+	"context"
+	"sync"
+)
+
+// FlushFunc writes a finalized trail record to its destination, e.g. sink.Sink.Write
+type FlushFunc func(ctx context.Context, record *TrailRecord) error
+
+// WithFlush sets the function detached trails are flushed with. The
+// middlewares and consumers set it to their sink.
+func WithFlush(ctx context.Context, flush FlushFunc) context.Context {
+	return context.WithValue(ctx, flushContextKey, flush)
+}
+
+// GetFlush retrieves the FlushFunc from the context
+func GetFlush(ctx context.Context) FlushFunc {
+	if flush, ok := ctx.Value(flushContextKey).(FlushFunc); ok {
+		return flush
+	}
+	return nil
+}
+
+// DetachTrail starts a child trail for work that outlives the request, such
+// as a goroutine still running after the response is written. The returned
+// context carries the child trail and keeps the values of ctx but not its
+// cancellation. Call done when the work has finished to finalize the child
+// trail and flush it with the FlushFunc in ctx. If ctx has no trail, the
+// returned context only drops cancellation and done is a no-op.
+func DetachTrail(ctx context.Context) (context.Context, func()) {
+	detached := context.WithoutCancel(ctx)
+	parent := GetTrail(ctx)
+	if parent == nil {
+		return detached, func() {}
+	}
+
+	child := NewChildTrail(parent, GetConfig(ctx))
+	detached = WithTrail(detached, child)
+	detached = context.WithValue(detached, stepContextKey, "")
+
+	flush := GetFlush(ctx)
+	var once sync.Once
+	return detached, func() {
+		once.Do(func() {
+			record := child.Finalize()
+			if flush != nil {
+				_ = flush(context.Background(), record)
+			}
+			child.Release()
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package gotrails
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+	"sync"
+)
+
+// BodyDigest hashes a complete body with the configured hash algorithm while
+// the trail stores only the prefix allowed by the body size caps
+type BodyDigest struct {
+	mu       sync.Mutex
+	alg      string
+	h        hash.Hash
+	size     int64
+	complete bool
+}
+
+// NewBodyDigest returns a body digest, nil unless Config.DigestBodies is set
+func (c *Config) NewBodyDigest() *BodyDigest {
+	if c == nil || !c.DigestBodies {
+		return nil
+	}
+	alg := c.hashAlgorithm()
+	h, _ := newHash(alg)
+	return &BodyDigest{alg: alg, h: h, complete: true}
+}
+
+// Write hashes the next part of the body
+func (d *BodyDigest) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.h.Write(p)
+	d.size += int64(len(p))
+	return len(p), nil
+}
+
+// Size returns the number of body bytes hashed so far
+func (d *BodyDigest) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// Sum returns the digest as "<algorithm>:<hex>", or "" when the body could
+// not be read to the end
+func (d *BodyDigest) Sum() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.complete {
+		return ""
+	}
+	return d.alg + ":" + hex.EncodeToString(d.h.Sum(nil))
+}
+
+// Reader returns rc teeing everything read through the digest. Closing it
+// first reads the rest of the body, so the digest covers the whole body even
+// when the handler stops reading early.
+func (d *BodyDigest) Reader(rc io.ReadCloser) io.ReadCloser {
+	return &digestReader{rc: rc, d: d}
+}
+
+// digestReader tees a body through a BodyDigest
+type digestReader struct {
+	rc     io.ReadCloser
+	d      *BodyDigest
+	eof    bool
+	closed bool
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.d.Write(p[:n])
+	switch {
+	case err == io.EOF:
+		r.eof = true
+	case err != nil:
+		r.fail()
+	}
+	return n, err
+}
+
+func (r *digestReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if !r.eof {
+		if _, err := io.Copy(r.d, r.rc); err != nil {
+			r.fail()
+		}
+		r.eof = true
+	}
+	return r.rc.Close()
+}
+
+func (r *digestReader) fail() {
+	r.d.mu.Lock()
+	r.d.complete = false
+	r.d.mu.Unlock()
+}
@@ -0,0 +1,161 @@
+package gotrails
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix prefixes the environment variables read by ConfigFromEnv
+const EnvPrefix = "GOTRAILS_"
+
+// ConfigFromEnv returns the default configuration overridden by GOTRAILS_*
+// environment variables, then by opts and the profile of the environment,
+// see EnvProfile. Unset variables keep the default:
+//
+//	GOTRAILS_SERVICE_NAME, GOTRAILS_ENVIRONMENT
+//	GOTRAILS_SAMPLING_RATE               0.0 to 1.0
+//	GOTRAILS_SAMPLING_MAX_PER_SECOND     trails per second, see RateLimitSampler
+//	GOTRAILS_SAMPLING_BURST
+//	GOTRAILS_SAMPLING_TARGET_PER_MINUTE  trails per minute, see AdaptiveSampler
+//	GOTRAILS_MASKING                     true or false
+//	GOTRAILS_MASK_FIELDS                 comma-separated, replaces the defaults
+//	GOTRAILS_MASK_VALUE
+//	GOTRAILS_METADATA_ONLY               true or false
+//	GOTRAILS_MAX_REQUEST_BODY_SIZE       bytes
+//	GOTRAILS_MAX_RESPONSE_BODY_SIZE      bytes
+//	GOTRAILS_EXCLUDE_PATHS, GOTRAILS_INCLUDE_PATHS, GOTRAILS_EXCLUDE_HEADERS
+//	GOTRAILS_ASYNC                       true or false
+//	GOTRAILS_ASYNC_QUEUE_SIZE
+//	GOTRAILS_IMMUTABLE                   true or false
+//	GOTRAILS_TRACE_ID_HEADER, GOTRAILS_REQUEST_ID_HEADER
+//	GOTRAILS_SINK                        see Config.Sink
+//
+// Malformed values are reported together in the returned error, which names
+// each variable, and so is a resulting config that fails Validate.
+func ConfigFromEnv(opts ...ConfigOption) (*Config, error) {
+	cfg := DefaultConfig()
+	env := envReader{}
+
+	env.string("SERVICE_NAME", &cfg.ServiceName)
+	env.string("ENVIRONMENT", &cfg.Environment)
+	env.float("SAMPLING_RATE", &cfg.SamplingRate)
+	env.bool("MASKING", &cfg.EnableMasking)
+	env.list("MASK_FIELDS", &cfg.MaskFields)
+	env.string("MASK_VALUE", &cfg.MaskValue)
+	env.bool("METADATA_ONLY", &cfg.MetadataOnly)
+	env.int("MAX_REQUEST_BODY_SIZE", &cfg.MaxRequestBodySize)
+	env.int("MAX_RESPONSE_BODY_SIZE", &cfg.MaxResponseBodySize)
+	env.list("EXCLUDE_PATHS", &cfg.ExcludePaths)
+	env.list("INCLUDE_PATHS", &cfg.IncludePaths)
+	env.list("EXCLUDE_HEADERS", &cfg.ExcludeHeaders)
+	env.bool("ASYNC", &cfg.EnableAsync)
+	env.int("ASYNC_QUEUE_SIZE", &cfg.AsyncQueueSize)
+	env.bool("IMMUTABLE", &cfg.Immutable)
+	env.string("TRACE_ID_HEADER", &cfg.TraceIDHeader)
+	env.string("REQUEST_ID_HEADER", &cfg.RequestIDHeader)
+	env.string("SINK", &cfg.Sink)
+	if v, ok := env.lookup("SAMPLING_MAX_PER_SECOND"); ok {
+		var perSecond float64
+		var burst int
+		env.float("SAMPLING_MAX_PER_SECOND", &perSecond)
+		env.int("SAMPLING_BURST", &burst)
+		if perSecond < 0 {
+			env.fail("SAMPLING_MAX_PER_SECOND", v, "a non-negative number")
+		}
+		cfg.Sampler = NewRateLimitSampler(perSecond, burst)
+	}
+	if v, ok := env.lookup("SAMPLING_TARGET_PER_MINUTE"); ok {
+		var perMinute float64
+		env.float("SAMPLING_TARGET_PER_MINUTE", &perMinute)
+		switch {
+		case perMinute < 0:
+			env.fail("SAMPLING_TARGET_PER_MINUTE", v, "a non-negative number")
+		case cfg.Sampler != nil:
+			env.fail("SAMPLING_TARGET_PER_MINUTE", v, "combinable with "+EnvPrefix+"SAMPLING_MAX_PER_SECOND")
+		}
+		cfg.Sampler = NewAdaptiveSampler(perMinute, 0)
+	}
+	if len(env.errs) > 0 {
+		return nil, errors.Join(env.errs...)
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.applyEnvProfile()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envReader reads GOTRAILS_* variables into config fields, collecting the
+// parse errors
+type envReader struct {
+	errs []error
+}
+
+// lookup returns the trimmed value of a set variable
+func (e *envReader) lookup(name string) (string, bool) {
+	v, ok := os.LookupEnv(EnvPrefix + name)
+	return strings.TrimSpace(v), ok
+}
+
+func (e *envReader) fail(name, v, want string) {
+	e.errs = append(e.errs, fmt.Errorf("gotrails: %s%s=%q is not %s", EnvPrefix, name, v, want))
+}
+
+func (e *envReader) string(name string, dst *string) {
+	if v, ok := e.lookup(name); ok {
+		*dst = v
+	}
+}
+
+func (e *envReader) list(name string, dst *[]string) {
+	v, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+	*dst = nil
+	for item := range strings.SplitSeq(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*dst = append(*dst, item)
+		}
+	}
+}
+
+func (e *envReader) bool(name string, dst *bool) {
+	if v, ok := e.lookup(name); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.fail(name, v, "a boolean")
+			return
+		}
+		*dst = b
+	}
+}
+
+func (e *envReader) int(name string, dst *int) {
+	if v, ok := e.lookup(name); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			e.fail(name, v, "an integer")
+			return
+		}
+		*dst = n
+	}
+}
+
+func (e *envReader) float(name string, dst *float64) {
+	if v, ok := e.lookup(name); ok {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			e.fail(name, v, "a number")
+			return
+		}
+		*dst = f
+	}
+}
@@ -0,0 +1,66 @@
+package gotrails
+
+import "strings"
+
+// EnvProfile is a layer of options for one environment, applied by NewConfig
+// on top of the other options when the config's Environment matches. A
+// profile may extend another environment's profile, whose options are
+// applied first, e.g. production extends staging and only adds what differs.
+type EnvProfile struct {
+	Environment string
+	Extends     string // environment whose profile is applied first, "" for none
+	Options     []ConfigOption
+}
+
+// ProductionProfile is a starting point for production: immutable trails,
+// content masking with the default detectors and 16KB body caps
+var ProductionProfile = EnvProfile{
+	Environment: "production",
+	Options: []ConfigOption{
+		WithImmutable(true),
+		WithContentMasking(),
+		WithHeaderCredentialMasking(true),
+		WithMaxRequestBodySize(16 * 1024),
+		WithMaxResponseBodySize(16 * 1024),
+	},
+}
+
+// WithEnvProfile adds an environment profile. A later profile for the same
+// environment replaces an earlier one.
+func WithEnvProfile(p EnvProfile) ConfigOption {
+	return func(c *Config) {
+		c.EnvProfiles = append(c.EnvProfiles, p)
+	}
+}
+
+// envProfile returns the last profile added for env
+func (c *Config) envProfile(env string) (EnvProfile, bool) {
+	for i := len(c.EnvProfiles) - 1; i >= 0; i-- {
+		if strings.EqualFold(c.EnvProfiles[i].Environment, env) {
+			return c.EnvProfiles[i], true
+		}
+	}
+	return EnvProfile{}, false
+}
+
+// applyEnvProfile applies the profile of the config's environment and the
+// profiles it extends, base first. A cycle of extends stops at the first
+// environment seen twice.
+func (c *Config) applyEnvProfile() {
+	var chain []EnvProfile
+	seen := make(map[string]bool)
+	for env := c.Environment; env != "" && !seen[strings.ToLower(env)]; {
+		seen[strings.ToLower(env)] = true
+		p, ok := c.envProfile(env)
+		if !ok {
+			break
+		}
+		chain = append(chain, p)
+		env = p.Extends
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for _, opt := range chain[i].Options {
+			opt(c)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package gotrails
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Severity is the severity level of a TrailError
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// ErrorCategory classifies the cause of a failure
+type ErrorCategory string
+
+const (
+	ErrorCategoryClientError       ErrorCategory = "client_error"
+	ErrorCategoryValidation        ErrorCategory = "validation"
+	ErrorCategoryTimeout           ErrorCategory = "timeout"
+	ErrorCategoryDependencyFailure ErrorCategory = "dependency_failure"
+	ErrorCategoryInternal          ErrorCategory = "internal"
+)
+
+// ErrorClassifier maps an error and/or a response status code to a category.
+// err is nil when classifying a status, status is 0 when classifying an error.
+// An empty category means the classifier has no opinion.
+type ErrorClassifier func(err error, status int) ErrorCategory
+
+// CategorizedError can be implemented by errors that know their category
+type CategorizedError interface {
+	ErrorCategory() ErrorCategory
+}
+
+// DefaultErrorClassifier classifies errors implementing CategorizedError by
+// their own category, timeouts as timeout, and status codes by class:
+// 400/422 validation, 408/504 timeout, other 4xx client_error,
+// 502/503 dependency_failure and other 5xx internal
+func DefaultErrorClassifier(err error, status int) ErrorCategory {
+	if err != nil {
+		var ce CategorizedError
+		if errors.As(err, &ce) {
+			return ce.ErrorCategory()
+		}
+		var te interface{ Timeout() bool }
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &te) && te.Timeout()) {
+			return ErrorCategoryTimeout
+		}
+		return ErrorCategoryInternal
+	}
+
+	switch {
+	case status == 400 || status == 422:
+		return ErrorCategoryValidation
+	case status == 408 || status == 504:
+		return ErrorCategoryTimeout
+	case status >= 400 && status < 500:
+		return ErrorCategoryClientError
+	case status == 502 || status == 503:
+		return ErrorCategoryDependencyFailure
+	case status >= 500:
+		return ErrorCategoryInternal
+	}
+	return ""
+}
+
+// SetErrorCategory sets the error category of the trail, overriding the
+// category derived at Finalize
+func (t *Trail) SetErrorCategory(category ErrorCategory) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetErrorCategory") {
+		return
+	}
+	t.ErrorCategory = category
+}
+
+// classifier returns the configured error classifier
+func (t *Trail) classifier() ErrorClassifier {
+	if t.cfg != nil && t.cfg.ErrorClassifier != nil {
+		return t.cfg.ErrorClassifier
+	}
+	return DefaultErrorClassifier
+}
+
+// classifyLocked derives the trail error category from the response status,
+// the context termination and the recorded errors, in that order
+func (t *Trail) classifyLocked() ErrorCategory {
+	if t.Response != nil && t.Response.Status >= 400 {
+		if category := t.classifier()(nil, t.Response.Status); category != "" {
+			return category
+		}
+	}
+	if t.DeadlineExceeded {
+		return ErrorCategoryTimeout
+	}
+	for _, e := range t.Errors {
+		if e.Category != "" {
+			return e.Category
+		}
+	}
+	if len(t.Errors) > 0 {
+		return ErrorCategoryInternal
+	}
+	return ""
+}
+
+// maxStackDepth is the maximum number of frames captured in a stack trace
+const maxStackDepth = 32
+
+// AddErrorf adds a formatted error message to the trail
+func (t *Trail) AddErrorf(source, format string, args ...any) {
+	t.AddError(source, fmt.Sprintf(format, args...))
+}
+
+// AddErrorWithStack adds err to the trail with its wrapped error chain, the
+// type of the innermost error and the stack trace of the caller
+func (t *Trail) AddErrorWithStack(source string, err error) {
+	if err == nil {
+		return
+	}
+	record := newErrorRecord(source, err, 3)
+	record.Category = t.classifier()(err, 0)
+	t.AddErrorRecord(record)
+}
+
+// AddErrorRecord adds a fully populated error record to the trail
+func (t *Trail) AddErrorRecord(e TrailError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddErrorRecord") {
+		return
+	}
+	t.Errors = append(t.Errors, e)
+}
+
+// AddErrorWithStackToContext adds err with its chain and stack trace to the
+// trail in context, linking it to the current step in context
+func AddErrorWithStackToContext(ctx context.Context, source string, err error) {
+	trail := GetTrail(ctx)
+	if trail == nil || err == nil {
+		return
+	}
+	record := newErrorRecord(source, err, 3)
+	record.Category = trail.classifier()(err, 0)
+	record.StepID = StepIDFromContext(ctx)
+	trail.AddErrorRecord(record)
+}
+
+// newErrorRecord builds an error record for err. skip counts frames as
+// runtime.Callers would from newErrorRecord, so 3 starts the stack trace at
+// the caller of the exported helper.
+func newErrorRecord(source string, err error, skip int) TrailError {
+	record := TrailError{
+		Source:   source,
+		Message:  err.Error(),
+		Severity: SeverityError,
+		Stack:    captureStack(skip + 1),
+	}
+
+	root := err
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		if len(record.Chain) == 0 {
+			record.Chain = append(record.Chain, root.Error())
+		}
+		record.Chain = append(record.Chain, e.Error())
+		root = e
+	}
+	record.Type = fmt.Sprintf("%T", root)
+	return record
+}
+
+// captureStack formats the stack of the calling goroutine, skipping skip frames
+func captureStack(skip int) string {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -1 +1,29 @@
 package gotrails
+
+import "time"
+
+// Event is a discrete occurrence during the request (cache miss, feature flag
+// evaluation, retry) that is neither a step nor an integration
+type Event struct {
+	Name       string         `json:"name"`
+	Timestamp  time.Time      `json:"timestamp"`
+	OffsetMs   int64          `json:"offset_ms"` // since the start of the trail
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// AddEvent records a timestamped event on the trail
+func (t *Trail) AddEvent(name string, attrs map[string]any) {
+	now := t.cfg.clock().Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddEvent") {
+		return
+	}
+	t.Events = append(t.Events, Event{
+		Name:       name,
+		Timestamp:  now.UTC(),
+		OffsetMs:   now.Sub(t.startTime).Milliseconds(),
+		Attributes: attrs,
+	})
+}
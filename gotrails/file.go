@@ -0,0 +1,355 @@
+package gotrails
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
+	"gopkg.in/yaml.v3"
+)
+
+// FileConfig is the layout of a config file read by LoadConfig. Omitted
+// settings keep their default.
+type FileConfig struct {
+	ServiceName     string        `json:"service_name" yaml:"service_name"`
+	Environment     string        `json:"environment" yaml:"environment"`
+	TraceIDHeader   string        `json:"trace_id_header" yaml:"trace_id_header"`
+	RequestIDHeader string        `json:"request_id_header" yaml:"request_id_header"`
+	Sinks           []string      `json:"sinks" yaml:"sinks"` // see Config.Sink
+	Async           *FileAsync    `json:"async" yaml:"async"`
+	Sampling        *FileSampling `json:"sampling" yaml:"sampling"`
+	Bodies          *FileBodies   `json:"bodies" yaml:"bodies"`
+	Headers         *FileHeaders  `json:"headers" yaml:"headers"`
+	Masking         *FileMasking  `json:"masking" yaml:"masking"`
+	Profiles        []FileProfile `json:"profiles" yaml:"profiles"`
+	Immutable       *bool         `json:"immutable" yaml:"immutable"`
+
+	// Overrides per environment, see EnvProfile
+	Environments map[string]*FileEnvironment `json:"environments" yaml:"environments"`
+}
+
+// FileEnvironment overrides the settings of a config file in one environment
+type FileEnvironment struct {
+	Extends    string `json:"extends" yaml:"extends"`
+	FileConfig `yaml:",inline"`
+}
+
+// FileAsync configures asynchronous sink writes
+type FileAsync struct {
+	Enabled   *bool `json:"enabled" yaml:"enabled"`
+	QueueSize *int  `json:"queue_size" yaml:"queue_size"`
+}
+
+// FileSampling configures which requests create a trail
+type FileSampling struct {
+	Rate            *float64           `json:"rate" yaml:"rate"`
+	Methods         map[string]float64 `json:"methods" yaml:"methods"` // per-method rates
+	SkipMethods     []string           `json:"skip_methods" yaml:"skip_methods"`
+	ExcludePaths    []string           `json:"exclude_paths" yaml:"exclude_paths"`
+	IncludePaths    []string           `json:"include_paths" yaml:"include_paths"`
+	MaxPerSecond    *float64           `json:"max_per_second" yaml:"max_per_second"` // see RateLimitSampler
+	Burst           *int               `json:"burst" yaml:"burst"`
+	TargetPerMinute *float64           `json:"target_per_minute" yaml:"target_per_minute"` // see AdaptiveSampler
+	ErrorBias       *FileErrorBias     `json:"error_bias" yaml:"error_bias"`
+}
+
+// FileErrorBias keeps the failures dropped by sampling, see ErrorBias
+type FileErrorBias struct {
+	Errors        bool            `json:"errors" yaml:"errors"`
+	StatusClasses map[int]float64 `json:"status_classes" yaml:"status_classes"` // e.g. {5: 1, 4: 0.5}
+}
+
+// FileBodies configures body capture
+type FileBodies struct {
+	MaxRequestSize  *int  `json:"max_request_size" yaml:"max_request_size"`
+	MaxResponseSize *int  `json:"max_response_size" yaml:"max_response_size"`
+	MaxTrailSize    *int  `json:"max_trail_size" yaml:"max_trail_size"`
+	Digest          *bool `json:"digest" yaml:"digest"`
+	MetadataOnly    *bool `json:"metadata_only" yaml:"metadata_only"`
+}
+
+// FileHeaders configures header capture
+type FileHeaders struct {
+	Exclude         []string `json:"exclude" yaml:"exclude"`
+	Include         []string `json:"include" yaml:"include"`
+	MaskCredentials *bool    `json:"mask_credentials" yaml:"mask_credentials"`
+}
+
+// FileMasking configures masking, of the whole config or of a profile
+type FileMasking struct {
+	Enabled         *bool    `json:"enabled" yaml:"enabled"`
+	Value           *string  `json:"value" yaml:"value"`
+	Fields          []string `json:"fields" yaml:"fields"` // replaces the default fields
+	Paths           []string `json:"paths" yaml:"paths"`
+	Exemptions      []string `json:"exemptions" yaml:"exemptions"`
+	Parents         []string `json:"parents" yaml:"parents"`
+	Detectors       []string `json:"detectors" yaml:"detectors"` // detector kinds, see masker.Detectors
+	Embedded        *bool    `json:"embedded" yaml:"embedded"`
+	MaxDepth        *int     `json:"max_depth" yaml:"max_depth"`
+	MaxElements     *int     `json:"max_elements" yaml:"max_elements"`
+	MaxStringLength *int     `json:"max_string_length" yaml:"max_string_length"`
+}
+
+// FileProfile is a masking profile overriding the masking of routes and
+// outbound hosts, see MaskProfile
+type FileProfile struct {
+	Name    string      `json:"name" yaml:"name"`
+	Routes  []string    `json:"routes" yaml:"routes"`
+	Hosts   []string    `json:"hosts" yaml:"hosts"`
+	Masking FileMasking `json:"masking" yaml:"masking"`
+}
+
+// LoadConfig reads a YAML (.yaml, .yml) or JSON (.json) config file and
+// returns the default configuration with the file applied, then opts.
+// Unknown keys and out-of-range values are reported together, each with its
+// location in the file, and so is a resulting config that fails Validate.
+func LoadConfig(path string, opts ...ConfigOption) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f FileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	default:
+		return nil, fmt.Errorf("gotrails: config %s: unsupported format, want .yaml, .yml or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gotrails: config %s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("gotrails: config %s: %w", path, err)
+	}
+	cfg := newConfig(append(f.Options(), opts...))
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("gotrails: config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Options returns the options applying the file
+func (f *FileConfig) Options() []ConfigOption {
+	return []ConfigOption{func(c *Config) {
+		setString(&c.ServiceName, f.ServiceName)
+		setString(&c.Environment, f.Environment)
+		setString(&c.TraceIDHeader, f.TraceIDHeader)
+		setString(&c.RequestIDHeader, f.RequestIDHeader)
+		if f.Sinks != nil {
+			c.Sink = strings.Join(f.Sinks, ",")
+		}
+		set(&c.Immutable, f.Immutable)
+
+		if a := f.Async; a != nil {
+			set(&c.EnableAsync, a.Enabled)
+			set(&c.AsyncQueueSize, a.QueueSize)
+		}
+		if s := f.Sampling; s != nil {
+			set(&c.SamplingRate, s.Rate)
+			for method, rate := range s.Methods {
+				WithMethodSamplingRate(method, rate)(c)
+			}
+			setList(&c.SkipMethods, s.SkipMethods)
+			setList(&c.ExcludePaths, s.ExcludePaths)
+			setList(&c.IncludePaths, s.IncludePaths)
+			if s.MaxPerSecond != nil {
+				burst := 0
+				set(&burst, s.Burst)
+				c.Sampler = NewRateLimitSampler(*s.MaxPerSecond, burst)
+			}
+			if s.TargetPerMinute != nil {
+				c.Sampler = NewAdaptiveSampler(*s.TargetPerMinute, 0)
+			}
+			if b := s.ErrorBias; b != nil {
+				WithErrorBias(ErrorBias{Errors: b.Errors, StatusClasses: b.StatusClasses})(c)
+			}
+		}
+		if b := f.Bodies; b != nil {
+			set(&c.MaxRequestBodySize, b.MaxRequestSize)
+			set(&c.MaxResponseBodySize, b.MaxResponseSize)
+			set(&c.MaxTrailSize, b.MaxTrailSize)
+			set(&c.DigestBodies, b.Digest)
+			set(&c.MetadataOnly, b.MetadataOnly)
+		}
+		if h := f.Headers; h != nil {
+			setList(&c.ExcludeHeaders, h.Exclude)
+			setList(&c.IncludeHeaders, h.Include)
+			set(&c.MaskHeaderCredentials, h.MaskCredentials)
+		}
+		if f.Masking != nil {
+			f.Masking.apply(c)
+		}
+		for _, p := range f.Profiles {
+			WithMaskProfile(&MaskProfile{
+				Name:    p.Name,
+				Routes:  p.Routes,
+				Hosts:   p.Hosts,
+				Options: []ConfigOption{p.Masking.apply},
+			})(c)
+		}
+		for env, e := range f.Environments {
+			WithEnvProfile(EnvProfile{Environment: env, Extends: e.Extends, Options: e.Options()})(c)
+		}
+	}}
+}
+
+// apply sets the masking settings of m on c
+func (m *FileMasking) apply(c *Config) {
+	set(&c.EnableMasking, m.Enabled)
+	set(&c.MaskValue, m.Value)
+	setList(&c.MaskFields, m.Fields)
+	setList(&c.MaskPaths, m.Paths)
+	setList(&c.MaskExemptions, m.Exemptions)
+	setList(&c.MaskParents, m.Parents)
+	if m.Detectors != nil {
+		c.MaskDetectors = masker.Detectors(m.Detectors...)
+	}
+	set(&c.MaskEmbedded, m.Embedded)
+	set(&c.MaskMaxDepth, m.MaxDepth)
+	set(&c.MaskMaxElements, m.MaxElements)
+	set(&c.MaskMaxStringLength, m.MaxStringLength)
+}
+
+// validate reports the out-of-range values of the file
+func (f *FileConfig) validate() error {
+	var errs []error
+	check := func(ok bool, key string, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+	rate := func(key string, r float64) {
+		check(r >= 0 && r <= 1, key, "%v is outside [0, 1]", r)
+	}
+	size := func(key string, n *int) {
+		if n != nil {
+			check(*n >= 0, key, "%d is negative", *n)
+		}
+	}
+
+	for i, spec := range f.Sinks {
+		check(strings.TrimSpace(spec) != "" && !strings.Contains(spec, ","), fmt.Sprintf("sinks[%d]", i), "%q is not a sink", spec)
+	}
+	if a := f.Async; a != nil && a.QueueSize != nil {
+		check(*a.QueueSize > 0, "async.queue_size", "%d is not positive", *a.QueueSize)
+	}
+	if s := f.Sampling; s != nil {
+		if s.Rate != nil {
+			rate("sampling.rate", *s.Rate)
+		}
+		for method, r := range s.Methods {
+			rate("sampling.methods."+method, r)
+		}
+		if s.MaxPerSecond != nil {
+			check(*s.MaxPerSecond >= 0, "sampling.max_per_second", "%v is negative", *s.MaxPerSecond)
+		}
+		size("sampling.burst", s.Burst)
+		check(s.Burst == nil || s.MaxPerSecond != nil, "sampling.burst", "needs sampling.max_per_second")
+		if s.TargetPerMinute != nil {
+			check(*s.TargetPerMinute >= 0, "sampling.target_per_minute", "%v is negative", *s.TargetPerMinute)
+			check(s.MaxPerSecond == nil, "sampling.target_per_minute", "cannot be combined with sampling.max_per_second")
+		}
+		if b := s.ErrorBias; b != nil {
+			for _, class := range slices.Sorted(maps.Keys(b.StatusClasses)) {
+				key := fmt.Sprintf("sampling.error_bias.status_classes.%d", class)
+				check(class >= 1 && class <= 5, key, "%d is not a status class, want 1 to 5", class)
+				rate(key, b.StatusClasses[class])
+			}
+		}
+	}
+	if b := f.Bodies; b != nil {
+		size("bodies.max_request_size", b.MaxRequestSize)
+		size("bodies.max_response_size", b.MaxResponseSize)
+		size("bodies.max_trail_size", b.MaxTrailSize)
+	}
+	if f.Masking != nil {
+		errs = append(errs, f.Masking.validate("masking")...)
+	}
+	for _, env := range slices.Sorted(maps.Keys(f.Environments)) {
+		e := f.Environments[env]
+		if e == nil {
+			continue
+		}
+		if e.Environments != nil {
+			errs = append(errs, fmt.Errorf("environments.%s.environments: environments cannot be nested", env))
+		}
+		if _, ok := f.Environments[e.Extends]; e.Extends != "" && !ok {
+			errs = append(errs, fmt.Errorf("environments.%s.extends: unknown environment %q", env, e.Extends))
+		}
+		if err := e.validate(); err != nil {
+			errs = append(errs, prefixErrors("environments."+env+".", err))
+		}
+	}
+	for i, p := range f.Profiles {
+		key := fmt.Sprintf("profiles[%d]", i)
+		check(p.Name != "", key+".name", "is required")
+		check(len(p.Routes) > 0 || len(p.Hosts) > 0, key, "needs routes or hosts")
+		errs = append(errs, p.Masking.validate(key+".masking")...)
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports the out-of-range masking values under key
+func (m *FileMasking) validate(key string) []error {
+	var errs []error
+	for _, limit := range []struct {
+		name string
+		n    *int
+	}{{"max_depth", m.MaxDepth}, {"max_elements", m.MaxElements}, {"max_string_length", m.MaxStringLength}} {
+		if limit.n != nil && *limit.n < 0 {
+			errs = append(errs, fmt.Errorf("%s.%s: %d is negative", key, limit.name, *limit.n))
+		}
+	}
+	for i, kind := range m.Detectors {
+		if len(masker.Detectors(kind)) == 0 {
+			errs = append(errs, fmt.Errorf("%s.detectors[%d]: unknown detector %q", key, i, kind))
+		}
+	}
+	return errs
+}
+
+// prefixErrors prefixes the location of each error joined in err
+func prefixErrors(prefix string, err error) error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return fmt.Errorf("%s%w", prefix, err)
+	}
+	var errs []error
+	for _, e := range joined.Unwrap() {
+		errs = append(errs, fmt.Errorf("%s%w", prefix, e))
+	}
+	return errors.Join(errs...)
+}
+
+// set assigns *v to *dst if v is set
+func set[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func setList(dst *[]string, v []string) {
+	if v != nil {
+		*dst = v
+	}
+}
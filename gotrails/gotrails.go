@@ -2,10 +2,10 @@ package gotrails
 
 import (
 	"context"
-	"crypto/sha256"
 	"encoding/hex"
-	"encoding/json"
 	"math/rand"
+	"net/http"
+	"slices"
 	"sync"
 	"time"
 
@@ -21,6 +21,8 @@ const (
 	IntegrationTypeDatabase IntegrationType = "database"
 	IntegrationTypeCache    IntegrationType = "cache"
 	IntegrationTypeGRPC     IntegrationType = "grpc"
+	IntegrationTypeSearch   IntegrationType = "search"
+	IntegrationTypeEmail    IntegrationType = "email"
 	IntegrationTypeCustom   IntegrationType = "custom"
 )
 
@@ -28,6 +30,9 @@ const (
 type Trail struct {
 	mu sync.RWMutex `json:"-"`
 
+	// Serialization format version, see SchemaVersion
+	SchemaVersion int `json:"schema_version"`
+
 	// Core identifiers
 	Timestamp   time.Time `json:"timestamp"`
 	TraceID     string    `json:"trace_id"`
@@ -35,54 +40,147 @@ type Trail struct {
 	Service     string    `json:"service"`
 	Environment string    `json:"environment"`
 
+	// Links to related trails
+	ParentTraceID   string      `json:"parent_trace_id,omitempty"`
+	ParentRequestID string      `json:"parent_request_id,omitempty"`
+	LinkedTrails    []TrailLink `json:"linked_trails,omitempty"`
+
+	// Who performed the action
+	Actor *Actor `json:"actor,omitempty"`
+
+	// Domain entities read or mutated
+	Resources []Resource `json:"resources,omitempty"`
+
 	// HTTP Request/Response
 	Request  *HTTPRequest  `json:"request,omitempty"`
 	Response *HTTPResponse `json:"response,omitempty"`
 
+	// Incoming gRPC call
+	RPC *RPC `json:"rpc,omitempty"`
+
+	// Consumed message (message-driven trails)
+	Message *Message `json:"message,omitempty"`
+
 	// Performance
 	LatencyMs int64     `json:"latency_ms"`
 	startTime time.Time `json:"-"`
 
+	// Context termination
+	TimeoutMs        int64 `json:"timeout_ms,omitempty"`
+	Cancelled        bool  `json:"cancelled,omitempty"`
+	DeadlineExceeded bool  `json:"deadline_exceeded,omitempty"`
+
 	// Trail components
 	InternalSteps []InternalStep `json:"internal_steps,omitempty"`
 	Integrations  []Integration  `json:"integrations,omitempty"`
 	Errors        []TrailError   `json:"errors,omitempty"`
+	ErrorCategory ErrorCategory  `json:"error_category,omitempty"`
+	Outcome       Outcome        `json:"outcome,omitempty"`
+	Events        []Event        `json:"events,omitempty"`
 
-	// Free-form metadata
+	// Payloads stored out-of-band and referenced by URI
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Components dropped to fit Config.MaxTrailSize
+	Truncated []Truncation `json:"truncated,omitempty"`
+
+	// Re-maskings applied after the trail was stored, see Redact
+	Redactions []Redaction `json:"redactions,omitempty"`
+
+	// Unmasked personal data found by Config.PIIDetector
+	PIIFindings []PIIFinding `json:"pii_findings,omitempty"`
+
+	// Secrets masked by Config.SecretScanner
+	SecretFindings []SecretFinding `json:"secret_findings,omitempty"`
+
+	// Labels and free-form metadata
+	Tags     []string       `json:"tags,omitempty"`
 	Metadata map[string]any `json:"metadata,omitempty"`
 
 	immutable bool    // set true after Finalize if config.Immutable
 	cfg       *Config // keep config reference for immutability check
 
+	typedResponseBody bool   // response body set by SetResponseBody
+	unsampled         bool   // dropped by head sampling, pending the ErrorBias
+	finalized         bool   // Finalize was called, see PendingIntegration
+	generation        uint64 // incremented when the trail is reused from the pool
+
 	// Hash chaining
-	Hash     string `json:"hash,omitempty"`
-	prevHash string // not exported, for chaining
+	Hash          string `json:"hash,omitempty"`
+	HashAlgorithm string `json:"hash_alg,omitempty"`  // see Config.HashAlgorithm, empty means sha256
+	PrevHash      string `json:"prev_hash,omitempty"` // hash of the previous trail in the chain
+
+	// HMAC-SHA256 signature of Hash, see Config.SigningKey
+	Signature      string `json:"signature,omitempty"`
+	SignatureKeyID string `json:"signature_key_id,omitempty"`
+
+	// Trusted timestamp of Hash, see Config.Timestamper
+	TrustedTimestamp *TimestampToken `json:"trusted_timestamp,omitempty"`
 }
 
 // HTTPRequest represents the incoming HTTP request
 type HTTPRequest struct {
-	Method  string              `json:"method"`
-	Path    string              `json:"path"`
-	Query   string              `json:"query,omitempty"`
-	Headers map[string][]string `json:"headers,omitempty"`
-	Body    any                 `json:"body,omitempty"`
+	Method     string              `json:"method"`
+	Path       string              `json:"path"`
+	Query      string              `json:"query,omitempty"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       any                 `json:"body,omitempty"`
+	BodySize   int64               `json:"body_size,omitempty"`
+	BodyDigest string              `json:"body_digest,omitempty"` // digest of the complete body when Body is truncated
 }
 
 // HTTPResponse represents the outgoing HTTP response
 type HTTPResponse struct {
-	Status  int                 `json:"status"`
-	Headers map[string][]string `json:"headers,omitempty"`
-	Body    any                 `json:"body,omitempty"`
+	Status     int                 `json:"status"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       any                 `json:"body,omitempty"`
+	BodySize   int64               `json:"body_size,omitempty"`
+	BodyDigest string              `json:"body_digest,omitempty"` // digest of the complete body when Body is truncated
+}
+
+// RPC represents the incoming gRPC call
+type RPC struct {
+	Method   string              `json:"method"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	Request  any                 `json:"request,omitempty"`
+	Response any                 `json:"response,omitempty"`
+	Code     string              `json:"code"`
 }
 
-// InternalStep represents an internal processing step
+// Message represents the consumed message that started the trail
+type Message struct {
+	System    string              `json:"system"`
+	Topic     string              `json:"topic,omitempty"`
+	Queue     string              `json:"queue,omitempty"`
+	ID        string              `json:"id,omitempty"`
+	Partition *int32              `json:"partition,omitempty"` // nil when unknown, e.g. for queues
+	Offset    *int64              `json:"offset,omitempty"`
+	Key       string              `json:"key,omitempty"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Body      any                 `json:"body,omitempty"`
+	BodySize  int64               `json:"body_size,omitempty"`
+
+	// Delivery (queue-driven workloads)
+	RetryCount                int   `json:"retry_count,omitempty"`
+	QueueLatencyMs            int64 `json:"queue_latency_ms,omitempty"`       // sent to received
+	ReceiveToCompleteMs       int64 `json:"receive_to_complete_ms,omitempty"` // received to handler completion
+	VisibilityTimeoutMs       int64 `json:"visibility_timeout_ms,omitempty"`
+	VisibilityTimeoutExceeded bool  `json:"visibility_timeout_exceeded,omitempty"`
+}
+
+// InternalStep represents an internal processing step. Nested steps point to
+// their parent step through ParentID.
 type InternalStep struct {
+	ID        string    `json:"id,omitempty"`
+	ParentID  string    `json:"parent_id,omitempty"`
 	Name      string    `json:"name"`
 	LatencyMs int64     `json:"latency_ms"`
 	Request   any       `json:"request,omitempty"`
 	Response  any       `json:"response,omitempty"`
 	Error     string    `json:"error,omitempty"`
 	StartTime time.Time `json:"-"`
+
+	Attributes map[string]any `json:"attributes,omitempty"`
 }
 
 // Integration represents an external integration call
@@ -94,6 +192,11 @@ type Integration struct {
 	Response  any             `json:"response,omitempty"`
 	Error     string          `json:"error,omitempty"`
 	Metadata  map[string]any  `json:"metadata,omitempty"`
+
+	// Retry grouping: attempts of one logical call share a CallID
+	CallID  string `json:"call_id,omitempty"`
+	Attempt int    `json:"attempt,omitempty"`
+	Final   bool   `json:"final,omitempty"` // last recorded attempt of the call
 }
 
 // TrailError represents an error that occurred during the request
@@ -101,6 +204,22 @@ type TrailError struct {
 	Source  string `json:"source"`
 	Message string `json:"message"`
 	Code    string `json:"code,omitempty"`
+
+	Severity    Severity      `json:"severity,omitempty"`
+	Category    ErrorCategory `json:"category,omitempty"`
+	Type        string        `json:"type,omitempty"`        // Go type of the innermost error, e.g. "*net.OpError"
+	Chain       []string      `json:"chain,omitempty"`       // messages of the wrapped errors, outermost first
+	Stack       string        `json:"stack,omitempty"`       // stack trace where the error was recorded
+	StepID      string        `json:"step_id,omitempty"`     // step the error originated from
+	Integration string        `json:"integration,omitempty"` // integration the error originated from
+}
+
+// randFloat64 is the random source used for sampling decisions
+var randFloat64 = rand.Float64
+
+// sampled reports whether a trail should be kept for the given sampling rate
+func sampled(rate float64) bool {
+	return rate >= 1.0 || randFloat64() <= rate
 }
 
 // NewTrail creates a new Trail with the given trace ID
@@ -109,60 +228,140 @@ func NewTrail(traceID, requestID string, cfg *Config) *Trail {
 		cfg = DefaultConfig()
 	}
 
-	// Sampling logic: skip trail if random > sampling rate
-	if cfg.SamplingRate < 1.0 {
-		if rand.Float64() > cfg.SamplingRate {
-			return nil
-		}
+	return newSampledTrail(traceID, requestID, cfg, cfg.SamplingRate)
+}
+
+// NewRequestTrail creates a new Trail for an incoming HTTP request, applying
+// the path exclusion, method skip and per-method sampling rules of the config.
+// Returns nil if the request should not be trailed.
+func NewRequestTrail(r *http.Request, traceID, requestID string, cfg *Config) *Trail {
+	if cfg == nil {
+		cfg = DefaultConfig()
 	}
 
-	now := time.Now().UTC()
-	return &Trail{
-		Timestamp:     now,
-		TraceID:       traceID,
-		RequestID:     requestID,
-		Service:       cfg.ServiceName,
-		Environment:   cfg.Environment,
-		startTime:     now,
-		InternalSteps: make([]InternalStep, 0),
-		Integrations:  make([]Integration, 0),
-		Errors:        make([]TrailError, 0),
-		Metadata:      make(map[string]any),
-		cfg:           cfg,
+	if !cfg.ShouldTracePath(r.URL.Path) {
+		return nil
 	}
+	return newSampledTrail(traceID, requestID, cfg, cfg.SamplingRateForMethod(r.Method))
+}
+
+// newTrail creates a new Trail without applying sampling
+func newTrail(traceID, requestID string, cfg *Config) *Trail {
+	now := cfg.clock().Now().UTC()
+	t := acquireTrail(cfg)
+	t.SchemaVersion = SchemaVersion
+	t.Timestamp = now
+	t.TraceID = traceID
+	t.RequestID = requestID
+	t.Service = cfg.ServiceName
+	t.Environment = cfg.Environment
+	t.startTime = now
+	t.cfg = cfg
+	return t
 }
 
 // SetRequest sets the incoming HTTP request
 func (t *Trail) SetRequest(req *HTTPRequest) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.rejectLocked("SetRequest") {
+		return
+	}
 	t.Request = req
 }
 
+// SetRequestBodyDigest records the digest and length of the complete request
+// body, known only once the handler has consumed it
+func (t *Trail) SetRequestBodyDigest(digest string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetRequestBodyDigest") || t.Request == nil {
+		return
+	}
+	req := *t.Request
+	req.BodyDigest, req.BodySize = digest, size
+	t.Request = &req
+}
+
+// SetRPC sets the incoming gRPC call
+func (t *Trail) SetRPC(rpc *RPC) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetRPC") {
+		return
+	}
+	t.RPC = rpc
+}
+
+// SetMessage sets the consumed message
+func (t *Trail) SetMessage(msg *Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetMessage") {
+		return
+	}
+	t.Message = msg
+}
+
+// CompleteMessage records delivery timings on the consumed message once the handler returns
+func (t *Trail) CompleteMessage(receivedAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("CompleteMessage") || t.Message == nil || receivedAt.IsZero() {
+		return
+	}
+	elapsed := t.cfg.clock().Since(receivedAt)
+	t.Message.ReceiveToCompleteMs = elapsed.Milliseconds()
+	if t.Message.VisibilityTimeoutMs > 0 && elapsed.Milliseconds() > t.Message.VisibilityTimeoutMs {
+		t.Message.VisibilityTimeoutExceeded = true
+	}
+}
+
 // SetResponse sets the outgoing HTTP response
 func (t *Trail) SetResponse(resp *HTTPResponse) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
+	if t.rejectLocked("SetResponse") {
+		return
+	}
+	if t.typedResponseBody && t.Response != nil && resp != nil {
+		resp.Body = t.Response.Body
+	}
 	t.Response = resp
 }
 
 // AddInternalStep adds an internal processing step
 func (t *Trail) AddInternalStep(step InternalStep) {
+	step.Request = t.serializeBody(step.Request)
+	step.Response = t.serializeBody(step.Response)
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.immutable {
+	if t.rejectLocked("AddInternalStep") {
 		return
 	}
 	t.InternalSteps = append(t.InternalSteps, step)
 }
 
-// AddIntegration adds an external integration call
+// AddIntegration adds an external integration call. A final attempt of a
+// grouped call clears the final flag of the earlier attempts with the same CallID.
+// A typed request or response is converted by the configured BodySerializer.
 func (t *Trail) AddIntegration(integration Integration) {
+	integration.Request = t.serializeTyped(integration.Request)
+	integration.Response = t.serializeTyped(integration.Response)
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.immutable {
+	if t.rejectLocked("AddIntegration") {
 		return
 	}
+	if integration.CallID != "" && integration.Final {
+		for i := range t.Integrations {
+			if t.Integrations[i].CallID == integration.CallID {
+				t.Integrations[i].Final = false
+			}
+		}
+	}
 	t.Integrations = append(t.Integrations, integration)
 }
 
@@ -170,7 +369,7 @@ func (t *Trail) AddIntegration(integration Integration) {
 func (t *Trail) AddError(source, message string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.immutable {
+	if t.rejectLocked("AddError") {
 		return
 	}
 	t.Errors = append(t.Errors, TrailError{
@@ -183,7 +382,7 @@ func (t *Trail) AddError(source, message string) {
 func (t *Trail) AddErrorWithCode(source, message, code string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.immutable {
+	if t.rejectLocked("AddErrorWithCode") {
 		return
 	}
 	t.Errors = append(t.Errors, TrailError{
@@ -197,7 +396,7 @@ func (t *Trail) AddErrorWithCode(source, message, code string) {
 func (t *Trail) SetMetadata(key string, value any) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	if t.immutable {
+	if t.rejectLocked("SetMetadata") {
 		return
 	}
 	if t.Metadata == nil {
@@ -206,11 +405,57 @@ func (t *Trail) SetMetadata(key string, value any) {
 	t.Metadata[key] = value
 }
 
+// AddTag labels the trail with one or more tags, ignoring tags already present
+func (t *Trail) AddTag(tags ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddTag") {
+		return
+	}
+	for _, tag := range tags {
+		if tag != "" && !slices.Contains(t.Tags, tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+}
+
+// HasTag reports whether the trail is labeled with tag
+func (t *Trail) HasTag(tag string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return slices.Contains(t.Tags, tag)
+}
+
+// RecordContext records the deadline and termination state of the request context.
+// The timeout is measured from the start of the trail.
+func (t *Trail) RecordContext(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("RecordContext") {
+		return
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		t.TimeoutMs = deadline.Sub(t.startTime).Milliseconds()
+	}
+	switch ctx.Err() {
+	case context.Canceled:
+		t.Cancelled = true
+	case context.DeadlineExceeded:
+		t.DeadlineExceeded = true
+	}
+}
+
 // SetPrevHash sets the previous hash for hash chaining
 func (t *Trail) SetPrevHash(prev string) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
-	t.prevHash = prev
+	if t.rejectLocked("SetPrevHash") {
+		return
+	}
+	t.PrevHash = prev
 }
 
 // ComputeHash calculates the hash of the trail (excluding Hash field itself)
@@ -220,73 +465,185 @@ func (t *Trail) ComputeHash() string {
 	return t.computeHashLocked()
 }
 
-// Finalize calculates the total latency, prepares the trail for flushing, and sets the hash
-func (t *Trail) Finalize() {
+// Finalize calculates the total latency, prepares the trail for flushing, sets
+// the hash and returns a frozen snapshot of the trail for the sinks. With a
+// Config.ChainManager the trail is linked to the previous trail of its chain.
+// A trail dropped by sampling (see Sampled) is neither chained nor hashed.
+func (t *Trail) Finalize() *TrailRecord {
 	t.mu.Lock()
-	t.LatencyMs = time.Since(t.startTime).Milliseconds()
+	t.finalized = true
+	if t.rejectLocked("Finalize") {
+		defer t.mu.Unlock()
+		return t.recordLocked(false)
+	}
+	if t.unsampled {
+		if !t.cfg.ErrorBias.keepsLocked(t) {
+			defer t.mu.Unlock()
+			return t.recordLocked(false)
+		}
+		t.unsampled = false
+		t.cfg.Controls.countKept()
+	}
+	t.mu.Unlock()
+	if t.cfg != nil && t.cfg.ChainManager != nil {
+		return t.cfg.ChainManager.finalize(t)
+	}
+	return t.finalize()
+}
+
+func (t *Trail) finalize() *TrailRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.LatencyMs = t.cfg.clock().Since(t.startTime).Milliseconds()
+	if t.ErrorCategory == "" {
+		t.ErrorCategory = t.classifyLocked()
+	}
+	t.Outcome = t.outcomeLocked()
 	if t.cfg != nil && t.cfg.Immutable {
 		t.immutable = true
 	}
+	if t.cfg != nil && t.cfg.LazyBodyParsing {
+		return t.recordLocked(true)
+	}
+	t.scanSecretsLocked()
+	t.pseudonymizeLocked()
+	t.shredLocked()
+	t.detectPIILocked()
+	t.enforceBudgetLocked()
+	t.HashAlgorithm = t.cfg.hashAlgorithm()
 	t.Hash = t.computeHashLocked()
-	t.mu.Unlock()
+	t.signLocked()
+	t.timestampLocked()
+	return t.recordLocked(false)
 }
 
 // computeHashLocked calculates the hash of the trail assuming the lock is already held.
 func (t *Trail) computeHashLocked() string {
-	// Prepare a minimal struct for hashing (exclude Hash, prevHash, mu, cfg, immutable)
+	// Prepare a minimal struct for hashing (exclude Hash, Signature, mu, cfg, immutable).
+	// Empty and nil collections hash alike, so decoded trails still verify.
 	tmp := struct {
-		Timestamp     time.Time
-		TraceID       string
-		RequestID     string
-		Service       string
-		Environment   string
-		Request       *HTTPRequest
-		Response      *HTTPResponse
-		LatencyMs     int64
-		InternalSteps []InternalStep
-		Integrations  []Integration
-		Errors        []TrailError
-		Metadata      map[string]any
-		PrevHash      string
+		SchemaVersion    int
+		Timestamp        time.Time
+		TraceID          string
+		RequestID        string
+		Service          string
+		Environment      string
+		ParentTraceID    string
+		ParentRequestID  string
+		LinkedTrails     []TrailLink `json:",omitempty"`
+		Actor            *Actor
+		Resources        []Resource `json:",omitempty"`
+		Request          *HTTPRequest
+		Response         *HTTPResponse
+		RPC              *RPC
+		Message          *Message
+		LatencyMs        int64
+		TimeoutMs        int64
+		Cancelled        bool
+		DeadlineExceeded bool
+		InternalSteps    []InternalStep `json:",omitempty"`
+		Integrations     []Integration  `json:",omitempty"`
+		Errors           []TrailError   `json:",omitempty"`
+		ErrorCategory    ErrorCategory
+		Outcome          Outcome
+		Truncated        []Truncation    `json:",omitempty"`
+		Events           []Event         `json:",omitempty"`
+		Attachments      []Attachment    `json:",omitempty"`
+		PIIFindings      []PIIFinding    `json:",omitempty"`
+		SecretFindings   []SecretFinding `json:",omitempty"`
+		Redactions       []Redaction     `json:",omitempty"`
+		Tags             []string        `json:",omitempty"`
+		Metadata         map[string]any  `json:",omitempty"`
+		HashAlgorithm    string          `json:",omitempty"`
+		PrevHash         string
 	}{
-		Timestamp:     t.Timestamp,
-		TraceID:       t.TraceID,
-		RequestID:     t.RequestID,
-		Service:       t.Service,
-		Environment:   t.Environment,
-		Request:       t.Request,
-		Response:      t.Response,
-		LatencyMs:     t.LatencyMs,
-		InternalSteps: t.InternalSteps,
-		Integrations:  t.Integrations,
-		Errors:        t.Errors,
-		Metadata:      t.Metadata,
-		PrevHash:      t.prevHash,
-	}
-	b, _ := json.Marshal(tmp)
-	h := sha256.Sum256(b)
-	return hex.EncodeToString(h[:])
+		SchemaVersion:    t.SchemaVersion,
+		Timestamp:        t.Timestamp,
+		TraceID:          t.TraceID,
+		RequestID:        t.RequestID,
+		Service:          t.Service,
+		Environment:      t.Environment,
+		ParentTraceID:    t.ParentTraceID,
+		ParentRequestID:  t.ParentRequestID,
+		LinkedTrails:     t.LinkedTrails,
+		Actor:            t.Actor,
+		Resources:        t.Resources,
+		Request:          t.Request,
+		Response:         t.Response,
+		RPC:              t.RPC,
+		Message:          t.Message,
+		LatencyMs:        t.LatencyMs,
+		TimeoutMs:        t.TimeoutMs,
+		Cancelled:        t.Cancelled,
+		DeadlineExceeded: t.DeadlineExceeded,
+		InternalSteps:    t.InternalSteps,
+		Integrations:     t.Integrations,
+		Errors:           t.Errors,
+		ErrorCategory:    t.ErrorCategory,
+		Outcome:          t.Outcome,
+		Truncated:        t.Truncated,
+		Events:           t.Events,
+		Attachments:      t.Attachments,
+		PIIFindings:      t.PIIFindings,
+		SecretFindings:   t.SecretFindings,
+		Redactions:       t.Redactions,
+		Tags:             t.Tags,
+		Metadata:         t.Metadata,
+		HashAlgorithm:    t.HashAlgorithm,
+		PrevHash:         t.PrevHash,
+	}
+	h, ok := newHash(t.HashAlgorithm)
+	if !ok {
+		return ""
+	}
+	b, _ := CanonicalJSON(tmp)
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // Clone creates a deep copy of the trail for safe reading
 func (t *Trail) Clone() *Trail {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
+	return t.cloneLocked()
+}
 
+// cloneLocked creates a deep copy of the trail assuming the lock is already held
+func (t *Trail) cloneLocked() *Trail {
 	clone := &Trail{
-		Timestamp:     t.Timestamp,
-		TraceID:       t.TraceID,
-		RequestID:     t.RequestID,
-		Service:       t.Service,
-		Environment:   t.Environment,
-		Request:       t.Request,
-		Response:      t.Response,
-		LatencyMs:     t.LatencyMs,
-		startTime:     t.startTime,
-		InternalSteps: make([]InternalStep, len(t.InternalSteps)),
-		Integrations:  make([]Integration, len(t.Integrations)),
-		Errors:        make([]TrailError, len(t.Errors)),
-		Metadata:      make(map[string]any),
+		SchemaVersion:    t.SchemaVersion,
+		Timestamp:        t.Timestamp,
+		TraceID:          t.TraceID,
+		RequestID:        t.RequestID,
+		Service:          t.Service,
+		Environment:      t.Environment,
+		ParentTraceID:    t.ParentTraceID,
+		ParentRequestID:  t.ParentRequestID,
+		LinkedTrails:     append([]TrailLink(nil), t.LinkedTrails...),
+		Actor:            t.Actor,
+		Resources:        append([]Resource(nil), t.Resources...),
+		Request:          t.Request,
+		Response:         t.Response,
+		RPC:              t.RPC,
+		Message:          t.Message,
+		LatencyMs:        t.LatencyMs,
+		startTime:        t.startTime,
+		TimeoutMs:        t.TimeoutMs,
+		Cancelled:        t.Cancelled,
+		DeadlineExceeded: t.DeadlineExceeded,
+		InternalSteps:    make([]InternalStep, len(t.InternalSteps)),
+		Integrations:     make([]Integration, len(t.Integrations)),
+		Errors:           make([]TrailError, len(t.Errors)),
+		ErrorCategory:    t.ErrorCategory,
+		Outcome:          t.Outcome,
+		Truncated:        append([]Truncation(nil), t.Truncated...),
+		Events:           append([]Event(nil), t.Events...),
+		Attachments:      append([]Attachment(nil), t.Attachments...),
+		PIIFindings:      append([]PIIFinding(nil), t.PIIFindings...),
+		SecretFindings:   append([]SecretFinding(nil), t.SecretFindings...),
+		Redactions:       append([]Redaction(nil), t.Redactions...),
+		Tags:             append([]string(nil), t.Tags...),
+		Metadata:         make(map[string]any),
 	}
 
 	copy(clone.InternalSteps, t.InternalSteps)
@@ -302,17 +659,26 @@ func (t *Trail) Clone() *Trail {
 
 // StartStep creates a new InternalStep with the given name and start time
 func StartStep(name string, req, resp any) InternalStep {
+	return startStep(SystemClock, name, req, resp)
+}
+
+func startStep(clock Clock, name string, req, resp any) InternalStep {
 	return InternalStep{
+		ID:        GenerateRequestID(),
 		Name:      name,
 		Request:   req,
 		Response:  resp,
-		StartTime: time.Now(),
+		StartTime: clock.Now(),
 	}
 }
 
 // EndStep finalizes an InternalStep, setting latency and optional error/response
 func EndStep(step *InternalStep, resp any, err error) {
-	step.LatencyMs = time.Since(step.StartTime).Milliseconds()
+	endStep(SystemClock, step, resp, err)
+}
+
+func endStep(clock Clock, step *InternalStep, resp any, err error) {
+	step.LatencyMs = clock.Since(step.StartTime).Milliseconds()
 	if resp != nil {
 		step.Response = resp
 	}
@@ -321,11 +687,13 @@ func EndStep(step *InternalStep, resp any, err error) {
 	}
 }
 
-// TraceStep runs a function, captures latency, and adds the step to the trail in context
+// TraceStep runs a function, captures latency, and adds the step to the trail in context.
+// Steps traced inside fn with the context it receives become children of the step.
 func TraceStep(ctx context.Context, name string, req any, fn func(context.Context) (resp any, err error)) (any, error) {
-	step := StartStep(name, req, nil)
-	resp, err := fn(ctx)
-	EndStep(&step, resp, err)
+	clock := ClockFromContext(ctx)
+	step := startStep(clock, name, req, nil)
+	resp, err := fn(WithStep(ctx, &step))
+	endStep(clock, &step, resp, err)
 	AddInternalStepToContext(ctx, step)
 	return resp, err
 }
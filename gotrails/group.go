@@ -0,0 +1,137 @@
+package gotrails
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Fork creates an isolated branch of the trail that can be written from
+// another goroutine without contending on the parent's lock. Fold it back with
+// Merge once the goroutine has finished.
+func (t *Trail) Fork() *Trail {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return &Trail{
+		SchemaVersion: t.SchemaVersion,
+		Timestamp:     t.cfg.clock().Now().UTC(),
+		TraceID:       t.TraceID,
+		RequestID:     t.RequestID,
+		Service:       t.Service,
+		Environment:   t.Environment,
+		startTime:     t.startTime, // event offsets stay relative to the parent
+		InternalSteps: make([]InternalStep, 0),
+		Integrations:  make([]Integration, 0),
+		Errors:        make([]TrailError, 0),
+		Metadata:      make(map[string]any),
+		cfg:           t.cfg,
+	}
+}
+
+// Merge folds the steps, integrations, errors and metadata of a branch created
+// by Fork into the trail. Branches merged in a fixed order produce a trail
+// whose ordering does not depend on goroutine scheduling.
+func (t *Trail) Merge(branch *Trail) {
+	if branch == nil || branch == t {
+		return
+	}
+
+	branch.mu.RLock()
+	defer branch.mu.RUnlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("Merge") {
+		return
+	}
+
+	t.InternalSteps = append(t.InternalSteps, branch.InternalSteps...)
+	t.Integrations = append(t.Integrations, branch.Integrations...)
+	t.Errors = append(t.Errors, branch.Errors...)
+	t.Events = append(t.Events, branch.Events...)
+	t.LinkedTrails = append(t.LinkedTrails, branch.LinkedTrails...)
+	t.Attachments = append(t.Attachments, branch.Attachments...)
+	t.Resources = append(t.Resources, branch.Resources...)
+	if branch.Actor != nil {
+		t.Actor = branch.Actor
+	}
+	for _, tag := range branch.Tags {
+		if !slices.Contains(t.Tags, tag) {
+			t.Tags = append(t.Tags, tag)
+		}
+	}
+	if len(branch.Metadata) > 0 && t.Metadata == nil {
+		t.Metadata = make(map[string]any, len(branch.Metadata))
+	}
+	for k, v := range branch.Metadata {
+		t.Metadata[k] = v
+	}
+}
+
+// ForkContext returns a context carrying an isolated branch of the trail in ctx,
+// for use by a single goroutine. Call merge once the goroutine has finished to
+// fold the branch back into the parent trail. If ctx has no trail, ctx is
+// returned unchanged and merge is a no-op.
+func ForkContext(ctx context.Context) (context.Context, func()) {
+	parent := GetTrail(ctx)
+	if parent == nil {
+		return ctx, func() {}
+	}
+
+	branch := parent.Fork()
+	var once sync.Once
+	return WithTrail(ctx, branch), func() {
+		once.Do(func() { parent.Merge(branch) })
+	}
+}
+
+// Group runs functions in goroutines, each with its own branch of the trail in
+// the group context. Branches are merged back into the parent trail on Wait, in
+// the order the functions were started.
+type Group struct {
+	ctx    context.Context
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	merges []func()
+	err    error
+}
+
+// NewGroup creates a Group for the trail in ctx
+func NewGroup(ctx context.Context) *Group {
+	return &Group{ctx: ctx}
+}
+
+// Go runs fn in a new goroutine with a forked trail context
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	ctx, merge := ForkContext(g.ctx)
+
+	g.mu.Lock()
+	g.merges = append(g.merges, merge)
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(ctx); err != nil {
+			g.mu.Lock()
+			if g.err == nil {
+				g.err = err
+			}
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until all functions have returned, merges their branches into
+// the parent trail and returns the first non-nil error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, merge := range g.merges {
+		merge()
+	}
+	g.merges = nil
+	return g.err
+}
@@ -0,0 +1,60 @@
+package gotrails
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"sync"
+)
+
+// Trail hash algorithms. SHA-256 and SHA-512 are built in; BLAKE3 is
+// available once registered, e.g.
+//
+//	gotrails.RegisterHashAlgorithm(gotrails.HashBLAKE3, func() hash.Hash { return blake3.New() })
+const (
+	HashSHA256 = "sha256"
+	HashSHA512 = "sha512"
+	HashBLAKE3 = "blake3"
+)
+
+var hashAlgorithms = struct {
+	sync.RWMutex
+	m map[string]func() hash.Hash
+}{m: map[string]func() hash.Hash{
+	HashSHA256: sha256.New,
+	HashSHA512: sha512.New,
+}}
+
+// RegisterHashAlgorithm makes a hash algorithm available to
+// Config.HashAlgorithm and to verifiers under name
+func RegisterHashAlgorithm(name string, fn func() hash.Hash) {
+	hashAlgorithms.Lock()
+	defer hashAlgorithms.Unlock()
+	hashAlgorithms.m[name] = fn
+}
+
+// newHash returns a hash of the named algorithm, "" meaning SHA-256
+func newHash(name string) (hash.Hash, bool) {
+	if name == "" {
+		name = HashSHA256
+	}
+	hashAlgorithms.RLock()
+	defer hashAlgorithms.RUnlock()
+	fn, ok := hashAlgorithms.m[name]
+	if !ok {
+		return nil, false
+	}
+	return fn(), true
+}
+
+// hashAlgorithm returns the configured hash algorithm, SHA-256 when unset or
+// not registered
+func (c *Config) hashAlgorithm() string {
+	if c == nil || c.HashAlgorithm == "" {
+		return HashSHA256
+	}
+	if _, ok := newHash(c.HashAlgorithm); !ok {
+		return HashSHA256
+	}
+	return c.HashAlgorithm
+}
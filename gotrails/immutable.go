@@ -0,0 +1,30 @@
+package gotrails
+
+// ImmutabilityViolation describes a write rejected because the trail was
+// finalized with Config.Immutable
+type ImmutabilityViolation struct {
+	TraceID   string
+	RequestID string
+	Op        string // rejected method, e.g. "SetResponse"
+}
+
+// IsImmutable reports whether the trail rejects writes, which it does once
+// finalized with Config.Immutable
+func (t *Trail) IsImmutable() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.immutable
+}
+
+// rejectLocked reports whether the trail is immutable, notifying
+// Config.OnImmutableViolation of the rejected op, assuming the lock is
+// already held
+func (t *Trail) rejectLocked(op string) bool {
+	if !t.immutable {
+		return false
+	}
+	if t.cfg != nil && t.cfg.OnImmutableViolation != nil {
+		t.cfg.OnImmutableViolation(ImmutabilityViolation{TraceID: t.TraceID, RequestID: t.RequestID, Op: op})
+	}
+	return true
+}
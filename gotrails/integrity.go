@@ -0,0 +1,183 @@
+package gotrails
+
+import (
+	"errors"
+	"time"
+)
+
+// Integrity issue kinds, in addition to ChainBreakHash and ChainBreakPrevHash
+const (
+	IntegrityUnsigned         = "unsigned"          // trail carries no signature
+	IntegrityUnknownKey       = "unknown_key"       // signed with a key that is not available
+	IntegrityInvalidSignature = "invalid_signature" // signature does not match the hash
+)
+
+// IntegrityIssue is one problem found by CheckIntegrity
+type IntegrityIssue struct {
+	Index     int    `json:"index"` // position in the sequence, starting at 0
+	Chain     string `json:"chain"`
+	TraceID   string `json:"trace_id"`
+	RequestID string `json:"request_id"`
+	Kind      string `json:"kind"`
+	Expected  string `json:"expected,omitempty"`
+	Actual    string `json:"actual,omitempty"`
+}
+
+// ChainSummary is the state of one chain in an IntegrityReport
+type ChainSummary struct {
+	Chain  string `json:"chain"`
+	Trails int    `json:"trails"`
+	Head   string `json:"head"` // hash of the last trail seen
+	Gaps   int    `json:"gaps"` // trails not linking to the trail before them
+}
+
+// IntegrityReport is the result of CheckIntegrity, meant to be archived by
+// periodic compliance jobs
+type IntegrityReport struct {
+	GeneratedAt       time.Time        `json:"generated_at"`
+	Checked           int              `json:"checked"`
+	Chains            []ChainSummary   `json:"chains"`
+	HashMismatches    int              `json:"hash_mismatches"`
+	Gaps              int              `json:"gaps"`
+	Unsigned          int              `json:"unsigned"`
+	InvalidSignatures int              `json:"invalid_signatures"` // includes unknown keys
+	Issues            []IntegrityIssue `json:"issues,omitempty"`
+}
+
+// Valid reports whether no issue was found
+func (r IntegrityReport) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+// IntegrityOption is an option for CheckIntegrity
+type IntegrityOption func(*integrityCheck)
+
+type integrityCheck struct {
+	keys          func(keyID string) ([]byte, bool)
+	partition     func(*Trail) string
+	clock         Clock
+	allowUnsigned bool
+	maxIssues     int
+}
+
+// WithIntegrityKeys verifies trail signatures against keys, e.g.
+// Keyring.Lookup. Without keys signatures are not checked.
+func WithIntegrityKeys(keys func(keyID string) ([]byte, bool)) IntegrityOption {
+	return func(c *integrityCheck) {
+		c.keys = keys
+	}
+}
+
+// WithIntegrityPartition sets how trails are split into chains, by default
+// one chain per service like ChainManager
+func WithIntegrityPartition(fn func(*Trail) string) IntegrityOption {
+	return func(c *integrityCheck) {
+		c.partition = fn
+	}
+}
+
+// WithIntegrityAllowUnsigned stops reporting unsigned trails
+func WithIntegrityAllowUnsigned() IntegrityOption {
+	return func(c *integrityCheck) {
+		c.allowUnsigned = true
+	}
+}
+
+// WithIntegrityMaxIssues caps the number of issues listed in the report, the
+// counters keep counting. Default 1000.
+func WithIntegrityMaxIssues(n int) IntegrityOption {
+	return func(c *integrityCheck) {
+		c.maxIssues = n
+	}
+}
+
+// WithIntegrityClock sets the clock for the report timestamp
+func WithIntegrityClock(clock Clock) IntegrityOption {
+	return func(c *integrityCheck) {
+		c.clock = clock
+	}
+}
+
+// CheckIntegrity reads trails in order and reports every hash mismatch, chain
+// gap, unsigned trail and invalid signature. Unlike VerifyChain it does not
+// stop at the first problem, and trails of several chains may be interleaved.
+// The prev_hash of the first trail of each chain is not checked.
+func CheckIntegrity(next func() (*Trail, bool), opts ...IntegrityOption) IntegrityReport {
+	c := &integrityCheck{
+		partition: func(t *Trail) string { return t.Service },
+		clock:     SystemClock,
+		maxIssues: 1000,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	report := IntegrityReport{GeneratedAt: c.clock.Now()}
+	chains := make(map[string]int) // index into report.Chains
+	for i := 0; ; i++ {
+		trail, ok := next()
+		if !ok {
+			return report
+		}
+		if trail == nil {
+			continue
+		}
+		report.Checked++
+
+		chain := c.partition(trail)
+		issue := func(kind, expected, actual string) {
+			if len(report.Issues) >= c.maxIssues {
+				return
+			}
+			report.Issues = append(report.Issues, IntegrityIssue{
+				Index:     i,
+				Chain:     chain,
+				TraceID:   trail.TraceID,
+				RequestID: trail.RequestID,
+				Kind:      kind,
+				Expected:  expected,
+				Actual:    actual,
+			})
+		}
+
+		if actual := trail.ComputeHash(); actual != trail.Hash {
+			report.HashMismatches++
+			issue(ChainBreakHash, trail.Hash, actual)
+		}
+
+		idx, seen := chains[chain]
+		if !seen {
+			idx = len(report.Chains)
+			chains[chain] = idx
+			report.Chains = append(report.Chains, ChainSummary{Chain: chain})
+		}
+		summary := &report.Chains[idx]
+		if seen && trail.PrevHash != summary.Head {
+			summary.Gaps++
+			report.Gaps++
+			issue(ChainBreakPrevHash, summary.Head, trail.PrevHash)
+		}
+		// The stored hash becomes the head, so one tampered trail is reported
+		// once and not again as a gap
+		summary.Head = trail.chainHash()
+		summary.Trails++
+
+		switch {
+		case trail.Signature == "":
+			if !c.allowUnsigned {
+				report.Unsigned++
+				issue(IntegrityUnsigned, "", "")
+			}
+		case c.keys != nil:
+			err := trail.VerifySignature(c.keys)
+			switch {
+			case errors.Is(err, ErrUnknownSigningKey):
+				report.InvalidSignatures++
+				issue(IntegrityUnknownKey, "", trail.SignatureKeyID)
+			case errors.Is(err, ErrInvalidSignature):
+				report.InvalidSignatures++
+				issue(IntegrityInvalidSignature, "", trail.SignatureKeyID)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package gotrails
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyVersion is one version of a signing key. It signs new trails between
+// NotBefore and NotAfter (zero means unbounded) and verifies old trails for
+// as long as it stays in the keyring.
+type KeyVersion struct {
+	ID        string
+	Secret    []byte
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// activeAt reports whether the key may sign at t
+func (k KeyVersion) activeAt(t time.Time) bool {
+	return !t.Before(k.NotBefore) && (k.NotAfter.IsZero() || t.Before(k.NotAfter))
+}
+
+// Keyring holds the versions of the trail signing key. New trails are signed
+// with the newest active version; verification accepts any version, so
+// archives stay verifiable after a rotation. Schedule a rotation by adding
+// the next version with a future NotBefore.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[string]KeyVersion
+}
+
+// NewKeyring creates a Keyring with the given key versions
+func NewKeyring(keys ...KeyVersion) *Keyring {
+	k := &Keyring{keys: make(map[string]KeyVersion, len(keys))}
+	for _, key := range keys {
+		k.Add(key)
+	}
+	return k
+}
+
+// Add adds or replaces a key version
+func (k *Keyring) Add(key KeyVersion) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.ID] = key
+}
+
+// Retire stops a key version from signing at the given time; it keeps
+// verifying trails it signed
+func (k *Keyring) Retire(id string, at time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.keys[id]; ok {
+		key.NotAfter = at
+		k.keys[id] = key
+	}
+}
+
+// Remove drops a key version; trails it signed no longer verify
+func (k *Keyring) Remove(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, id)
+}
+
+// Active returns the key version to sign with at t, the active version with
+// the latest NotBefore
+func (k *Keyring) Active(t time.Time) (KeyVersion, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var active KeyVersion
+	found := false
+	for _, key := range k.keys {
+		if !key.activeAt(t) {
+			continue
+		}
+		if !found || key.NotBefore.After(active.NotBefore) ||
+			(key.NotBefore.Equal(active.NotBefore) && key.ID > active.ID) {
+			active, found = key, true
+		}
+	}
+	return active, found
+}
+
+// Lookup returns the secret of a key version, for Trail.VerifySignature
+func (k *Keyring) Lookup(id string) ([]byte, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	return key.Secret, ok
+}
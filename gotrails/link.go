@@ -0,0 +1,63 @@
+package gotrails
+
+// TrailLink references a related trail
+type TrailLink struct {
+	TraceID   string `json:"trace_id"`
+	RequestID string `json:"request_id,omitempty"`
+	Relation  string `json:"relation"` // e.g. "child", "follows_from"
+}
+
+// Link relations
+const (
+	LinkRelationChild       = "child"
+	LinkRelationFollowsFrom = "follows_from"
+)
+
+// NewChildTrail creates a trail for work spawned by parent (an async job, a
+// scheduled follow-up), with its own trace and request IDs. The child records
+// the parent IDs and the parent gets a link to the child, so the trails can
+// be stitched together downstream. Sampling is not applied again: a child of
+// a kept trail is always kept. Returns nil if parent is nil.
+func NewChildTrail(parent *Trail, cfg *Config) *Trail {
+	if parent == nil {
+		return nil
+	}
+	if cfg == nil {
+		cfg = parent.cfg
+	}
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	child := newTrail(GenerateTraceID(), GenerateRequestID(), cfg)
+	child.ParentTraceID = parent.TraceID
+	child.ParentRequestID = parent.RequestID
+	parent.Link(child.TraceID, child.RequestID, LinkRelationChild)
+	return child
+}
+
+// SetParent records the trail that caused this one, e.g. from IDs carried in
+// the headers of a job message
+func (t *Trail) SetParent(traceID, requestID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetParent") {
+		return
+	}
+	t.ParentTraceID = traceID
+	t.ParentRequestID = requestID
+}
+
+// Link adds a reference to a related trail
+func (t *Trail) Link(traceID, requestID, relation string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("Link") {
+		return
+	}
+	t.LinkedTrails = append(t.LinkedTrails, TrailLink{
+		TraceID:   traceID,
+		RequestID: requestID,
+		Relation:  relation,
+	})
+}
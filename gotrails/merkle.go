@@ -0,0 +1,130 @@
+package gotrails
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Anchor is the Merkle root over a batch of trail hashes. Publishing the root
+// to an external, append-only place (a ledger, an object-lock bucket) proves
+// every trail of the batch existed at anchor time.
+type Anchor struct {
+	Root       string    `json:"root"`
+	Leaves     []string  `json:"leaves"` // trail hashes in batch order
+	StartedAt  time.Time `json:"started_at"`
+	AnchoredAt time.Time `json:"anchored_at"`
+
+	// Trusted timestamp of Root, see sink.WithAnchorTimestamper
+	TrustedTimestamp *TimestampToken `json:"trusted_timestamp,omitempty"`
+}
+
+// ProofStep is one sibling hash on the path from a leaf to the Merkle root
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"` // sibling is on the left
+}
+
+// Proof returns the inclusion proof of a trail hash in the anchor
+func (a *Anchor) Proof(hash string) ([]ProofStep, bool) {
+	for i, leaf := range a.Leaves {
+		if leaf == hash {
+			return MerkleProof(a.Leaves, i), true
+		}
+	}
+	return nil, false
+}
+
+// Leaves and nodes are hashed with distinct prefixes so an inner node can
+// never be passed off as a trail hash
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+func merkleLeaf(hash string) []byte {
+	b, err := hex.DecodeString(hash)
+	if err != nil {
+		b = []byte(hash)
+	}
+	h := sha256.Sum256(append([]byte{merkleLeafPrefix}, b...))
+	return h[:]
+}
+
+func merkleNode(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(buf, merkleNodePrefix)
+	buf = append(buf, left...)
+	buf = append(buf, right...)
+	h := sha256.Sum256(buf)
+	return h[:]
+}
+
+// merkleLevels returns every level of the tree, leaves first. An odd node at
+// the end of a level is carried up unchanged.
+func merkleLevels(hashes []string) [][][]byte {
+	level := make([][]byte, len(hashes))
+	for i, h := range hashes {
+		level[i] = merkleLeaf(h)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// MerkleRoot returns the hex Merkle root over trail hashes, "" for none
+func MerkleRoot(hashes []string) string {
+	if len(hashes) == 0 {
+		return ""
+	}
+	levels := merkleLevels(hashes)
+	return hex.EncodeToString(levels[len(levels)-1][0])
+}
+
+// MerkleProof returns the inclusion proof of hashes[index]
+func MerkleProof(hashes []string, index int) []ProofStep {
+	if index < 0 || index >= len(hashes) {
+		return nil
+	}
+	var proof []ProofStep
+	levels := merkleLevels(hashes)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			proof = append(proof, ProofStep{
+				Hash: hex.EncodeToString(level[sibling]),
+				Left: sibling < index,
+			})
+		}
+		index /= 2
+	}
+	return proof
+}
+
+// VerifyMerkleProof reports whether proof links a trail hash to root
+func VerifyMerkleProof(hash string, proof []ProofStep, root string) bool {
+	node := merkleLeaf(hash)
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		if step.Left {
+			node = merkleNode(sibling, node)
+		} else {
+			node = merkleNode(node, sibling)
+		}
+	}
+	return hex.EncodeToString(node) == root
+}
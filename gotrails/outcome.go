@@ -0,0 +1,69 @@
+package gotrails
+
+// Outcome is the canonical result of a trailed request
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomePartial Outcome = "partial" // succeeded, but errors were recorded along the way
+	OutcomeDenied  Outcome = "denied"  // rejected by authentication or authorization
+)
+
+// OutcomeInput is the trail state an OutcomeClassifier decides on
+type OutcomeInput struct {
+	Status           int    // HTTP response status, 0 if none
+	RPCCode          string // gRPC status code, empty if none
+	Errors           []TrailError
+	ErrorCategory    ErrorCategory
+	Cancelled        bool
+	DeadlineExceeded bool
+	Metadata         map[string]any
+}
+
+// OutcomeClassifier derives the outcome of a trail at Finalize
+type OutcomeClassifier func(in OutcomeInput) Outcome
+
+// DefaultOutcomeClassifier reports denied for 401/403 and Unauthenticated/
+// PermissionDenied calls, failure for error statuses, non-OK codes and
+// terminated contexts, partial when errors were recorded next to a successful
+// response and success otherwise
+func DefaultOutcomeClassifier(in OutcomeInput) Outcome {
+	switch {
+	case in.Status == 401 || in.Status == 403,
+		in.RPCCode == "Unauthenticated" || in.RPCCode == "PermissionDenied":
+		return OutcomeDenied
+	case in.Status >= 400,
+		in.RPCCode != "" && in.RPCCode != "OK",
+		in.Cancelled || in.DeadlineExceeded:
+		return OutcomeFailure
+	case len(in.Errors) > 0 && (in.Status > 0 || in.RPCCode != ""):
+		return OutcomePartial
+	case len(in.Errors) > 0:
+		return OutcomeFailure // no response to succeed with, e.g. a consumed message
+	}
+	return OutcomeSuccess
+}
+
+// outcomeLocked runs the configured outcome classifier on the trail
+func (t *Trail) outcomeLocked() Outcome {
+	classify := DefaultOutcomeClassifier
+	if t.cfg != nil && t.cfg.OutcomeClassifier != nil {
+		classify = t.cfg.OutcomeClassifier
+	}
+
+	in := OutcomeInput{
+		Errors:           t.Errors,
+		ErrorCategory:    t.ErrorCategory,
+		Cancelled:        t.Cancelled,
+		DeadlineExceeded: t.DeadlineExceeded,
+		Metadata:         t.Metadata,
+	}
+	if t.Response != nil {
+		in.Status = t.Response.Status
+	}
+	if t.RPC != nil {
+		in.RPCCode = t.RPC.Code
+	}
+	return classify(in)
+}
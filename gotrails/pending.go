@@ -0,0 +1,43 @@
+package gotrails
+
+// PendingIntegration is an integration recorded before its outcome is
+// complete, see Trail.AddPendingIntegration
+type PendingIntegration struct {
+	trail      *Trail
+	generation uint64
+	index      int
+}
+
+// AddPendingIntegration records an integration whose outcome is completed
+// later, e.g. a streamed response body read after the call returned. The
+// integration is part of the trail from now on, even if it is never completed.
+func (t *Trail) AddPendingIntegration(integration Integration) *PendingIntegration {
+	integration.Request = t.serializeTyped(integration.Request)
+	integration.Response = t.serializeTyped(integration.Response)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddPendingIntegration") || t.finalized {
+		return nil
+	}
+	t.Integrations = append(t.Integrations, integration)
+	return &PendingIntegration{trail: t, generation: t.generation, index: len(t.Integrations) - 1}
+}
+
+// Complete applies update to the recorded integration. Updates arriving after
+// the trail was finalized or released are dropped, so they never change a
+// trail record or reach a trail reused from the pool. It reports whether
+// update was applied.
+func (p *PendingIntegration) Complete(update func(*Integration)) bool {
+	if p == nil {
+		return false
+	}
+	t := p.trail
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.finalized || t.generation != p.generation || p.index >= len(t.Integrations) {
+		return false
+	}
+	update(&t.Integrations[p.index])
+	return true
+}
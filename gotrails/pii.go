@@ -0,0 +1,228 @@
+package gotrails
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// PIIKind is a kind of personal data recognized by PIIDetector
+type PIIKind string
+
+// PII kinds
+const (
+	PIIEmail      PIIKind = "email"
+	PIIPhone      PIIKind = "phone"
+	PIICardNumber PIIKind = "card_number"
+	PIISSN        PIIKind = "ssn"
+
+	// Region-specific kinds, detected only when a policy lists them. Any
+	// kind registered with masker.RegisterDetector may be listed too.
+	PIIIBAN          PIIKind = "iban"
+	PIINIK           PIIKind = "nik"  // Indonesian national identity number
+	PIINPWP          PIIKind = "npwp" // Indonesian tax number
+	PIIIDBankAccount PIIKind = "id_bank_account"
+)
+
+// PIIAction is what PIIDetector does with a trail containing unmasked PII
+type PIIAction string
+
+// PII actions
+const (
+	PIIActionFlag   PIIAction = "flag"   // record the findings in pii_findings
+	PIIActionMask   PIIAction = "mask"   // replace the matches with the mask value
+	PIIActionReport PIIAction = "report" // only call the report handler
+)
+
+// PIIFinding locates unmasked PII in a trail. The value itself is never
+// recorded.
+type PIIFinding struct {
+	Kind PIIKind `json:"kind"`
+	Path string  `json:"path"` // e.g. "request.body.contact.email"
+}
+
+// PIIPolicy configures detection for one environment
+type PIIPolicy struct {
+	Action PIIAction
+	Kinds  []PIIKind // kinds to detect, nil detects email, phone, card number and SSN
+}
+
+// PIIDetector scans finalized trails for personal data that escaped masking.
+// Policies are chosen by the trail's environment.
+type PIIDetector struct {
+	policies map[string]PIIPolicy
+	fallback PIIPolicy
+	report   func(t *Trail, findings []PIIFinding)
+}
+
+// PIIOption is an option for PIIDetector
+type PIIOption func(*PIIDetector)
+
+// WithPIIPolicy sets the policy of an environment
+func WithPIIPolicy(environment string, policy PIIPolicy) PIIOption {
+	return func(d *PIIDetector) {
+		d.policies[environment] = policy
+	}
+}
+
+// WithPIIReport sets a handler called with the findings of every trail
+// containing PII, e.g. to increment a violation metric. It runs during
+// Finalize with the trail locked, so it must only read the trail fields.
+func WithPIIReport(fn func(t *Trail, findings []PIIFinding)) PIIOption {
+	return func(d *PIIDetector) {
+		d.report = fn
+	}
+}
+
+// NewPIIDetector creates a PIIDetector applying fallback to environments
+// without a policy of their own
+func NewPIIDetector(fallback PIIPolicy, opts ...PIIOption) *PIIDetector {
+	d := &PIIDetector{policies: make(map[string]PIIPolicy), fallback: fallback}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// policy returns the policy of an environment
+func (d *PIIDetector) policy(environment string) PIIPolicy {
+	if p, ok := d.policies[environment]; ok {
+		return p
+	}
+	return d.fallback
+}
+
+// piiScan walks captured values, collecting findings and masking matches
+type piiScan struct {
+	detectors []masker.Detector
+	mask      string // replacement of matches, "" keeps them
+	findings  []PIIFinding
+}
+
+// value scans v at path and returns it, masked if requested
+func (s *piiScan) value(path string, v any) any {
+	switch val := v.(type) {
+	case nil, bool, float64, int, int64:
+		return v
+	case string:
+		return s.text(path, val)
+	case *RawBody:
+		return s.value(path, val.Value())
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = s.value(path+"."+k, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = s.value(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return out
+	default:
+		switch n := normalizeJSON(v).(type) {
+		case map[string]any, []any, string:
+			if out := s.value(path, n); s.mask != "" {
+				return out
+			}
+		}
+		return v
+	}
+}
+
+// text scans a string value
+func (s *piiScan) text(path, v string) string {
+	for _, p := range s.detectors {
+		kind := PIIKind(p.Kind)
+		found := false
+		v = p.Pattern.ReplaceAllStringFunc(v, func(match string) string {
+			if p.Valid != nil && !p.Valid(match) {
+				return match
+			}
+			found = true
+			if s.mask == "" {
+				return match
+			}
+			return s.mask
+		})
+		if found {
+			s.findings = append(s.findings, PIIFinding{Kind: kind, Path: strings.TrimPrefix(path, ".")})
+		}
+	}
+	return v
+}
+
+// detectPIILocked scans the captured values of the trail for unmasked PII and
+// applies the policy of its environment, assuming the lock is already held
+func (t *Trail) detectPIILocked() {
+	if t.cfg == nil || t.cfg.PIIDetector == nil {
+		return
+	}
+	d := t.cfg.PIIDetector
+	policy := d.policy(t.Environment)
+	if policy.Action == "" {
+		return
+	}
+
+	scan := &piiScan{detectors: masker.DefaultDetectors()}
+	if policy.Kinds != nil {
+		scan.detectors = slices.DeleteFunc(masker.Detectors(), func(d masker.Detector) bool {
+			return !slices.Contains(policy.Kinds, PIIKind(d.Kind))
+		})
+	}
+	if policy.Action == PIIActionMask {
+		scan.mask = t.cfg.MaskValue
+	}
+
+	if t.Request != nil {
+		req := *t.Request
+		req.Query = scan.text("request.query", req.Query)
+		req.Body = scan.value("request.body", req.Body)
+		t.Request = &req
+	}
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Body = scan.value("response.body", resp.Body)
+		t.Response = &resp
+	}
+	if t.RPC != nil {
+		rpc := *t.RPC
+		rpc.Request = scan.value("rpc.request", rpc.Request)
+		rpc.Response = scan.value("rpc.response", rpc.Response)
+		t.RPC = &rpc
+	}
+	if t.Message != nil {
+		msg := *t.Message
+		msg.Body = scan.value("message.body", msg.Body)
+		t.Message = &msg
+	}
+	for i := range t.Integrations {
+		in := &t.Integrations[i]
+		in.Request = scan.value(fmt.Sprintf("integrations[%d].request", i), in.Request)
+		in.Response = scan.value(fmt.Sprintf("integrations[%d].response", i), in.Response)
+	}
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		step.Request = scan.value(fmt.Sprintf("internal_steps[%d].request", i), step.Request)
+		step.Response = scan.value(fmt.Sprintf("internal_steps[%d].response", i), step.Response)
+	}
+	for i := range t.Errors {
+		t.Errors[i].Message = scan.text(fmt.Sprintf("errors[%d].message", i), t.Errors[i].Message)
+	}
+	if t.Metadata != nil {
+		t.Metadata = scan.value("metadata", t.Metadata).(map[string]any)
+	}
+
+	if len(scan.findings) == 0 {
+		return
+	}
+	if policy.Action == PIIActionFlag {
+		t.PIIFindings = scan.findings
+	}
+	if d.report != nil {
+		d.report(t, scan.findings)
+	}
+}
@@ -0,0 +1,61 @@
+package gotrails
+
+import "sync"
+
+var trailPool = sync.Pool{
+	New: func() any { return new(Trail) },
+}
+
+// acquireTrail returns an empty trail, reused from the pool when pooling is enabled
+func acquireTrail(cfg *Config) *Trail {
+	t := new(Trail)
+	if cfg.EnablePooling {
+		t = trailPool.Get().(*Trail)
+	}
+	if t.InternalSteps == nil {
+		t.InternalSteps = make([]InternalStep, 0)
+	}
+	if t.Integrations == nil {
+		t.Integrations = make([]Integration, 0)
+	}
+	if t.Errors == nil {
+		t.Errors = make([]TrailError, 0)
+	}
+	if t.Metadata == nil {
+		t.Metadata = make(map[string]any)
+	}
+	return t
+}
+
+// Release returns the trail to the pool when Config.EnablePooling is set, and
+// is a no-op otherwise. The middlewares and consumers call it once the trail
+// record has been written. The trail must not be used afterwards; records
+// returned by Finalize stay valid.
+func (t *Trail) Release() {
+	if t == nil || t.cfg == nil || !t.cfg.EnablePooling {
+		return
+	}
+	t.reset()
+	trailPool.Put(t)
+}
+
+// reset clears the trail for reuse, keeping the capacity of its step,
+// integration and error slices and dropping references to their payloads
+func (t *Trail) reset() {
+	steps := t.InternalSteps[:0]
+	clear(steps[:cap(steps)])
+	integrations := t.Integrations[:0]
+	clear(integrations[:cap(integrations)])
+	errs := t.Errors[:0]
+	clear(errs[:cap(errs)])
+	metadata := t.Metadata
+	clear(metadata)
+
+	*t = Trail{
+		InternalSteps: steps,
+		Integrations:  integrations,
+		Errors:        errs,
+		Metadata:      metadata,
+		generation:    t.generation + 1,
+	}
+}
@@ -0,0 +1,89 @@
+package gotrails
+
+import (
+	"slices"
+	"strings"
+)
+
+// CompliancePreset is a curated set of fields to mask and headers to drop for
+// a compliance regime, see WithCompliancePreset
+type CompliancePreset struct {
+	Name           string
+	MaskFields     []string // snake_case names, also matched without underscores (e.g. cardNumber)
+	ExcludeHeaders []string
+}
+
+// Compliance presets
+var (
+	// PresetPCI covers cardholder and sensitive authentication data (PCI DSS)
+	PresetPCI = CompliancePreset{
+		Name: "pci",
+		MaskFields: []string{
+			"card_number", "pan", "primary_account_number", "cc_number", "credit_card", "card_no",
+			"cardholder_name", "expiry", "exp_date", "expiration_date", "exp_month", "exp_year",
+			"cvv", "cvv2", "cvc", "cvc2", "cid", "security_code", "card_verification",
+			"track_data", "track1", "track2", "magstripe", "pin", "pin_block",
+		},
+		ExcludeHeaders: []string{"authorization", "cookie", "set-cookie"},
+	}
+
+	// PresetHIPAA covers the HIPAA identifiers of protected health
+	// information and common clinical fields
+	PresetHIPAA = CompliancePreset{
+		Name: "hipaa",
+		MaskFields: []string{
+			"patient_name", "first_name", "last_name", "full_name",
+			"ssn", "social_security_number", "mrn", "medical_record_number",
+			"health_plan_id", "member_id", "insurance_id", "policy_number", "account_number",
+			"date_of_birth", "dob", "birth_date", "admission_date", "discharge_date", "date_of_death",
+			"address", "street", "city", "postal_code", "zip", "zip_code",
+			"phone", "phone_number", "mobile", "fax", "email",
+			"license_number", "vehicle_id", "device_id", "serial_number", "ip_address", "biometric", "photo",
+			"diagnosis", "icd_code", "icd10", "treatment",
+			"medication", "prescription", "lab_result", "clinical_notes",
+		},
+		ExcludeHeaders: []string{"authorization", "cookie", "set-cookie", "x-forwarded-for", "x-real-ip"},
+	}
+
+	// PresetPII covers general personal data (GDPR, CCPA)
+	PresetPII = CompliancePreset{
+		Name: "pii",
+		MaskFields: []string{
+			"first_name", "last_name", "full_name", "middle_name", "maiden_name",
+			"email", "email_address", "phone", "phone_number", "mobile",
+			"address", "street", "address_line1", "address_line2", "postal_code", "zip", "zip_code",
+			"date_of_birth", "dob", "birth_date", "gender",
+			"ssn", "national_id", "tax_id", "passport", "passport_number", "drivers_license",
+			"ip_address", "geolocation", "latitude", "longitude",
+		},
+		ExcludeHeaders: []string{"authorization", "cookie", "set-cookie", "x-forwarded-for", "x-real-ip"},
+	}
+)
+
+// WithCompliancePreset adds the mask fields and excluded headers of presets
+// to the configuration and enables masking. Fields and headers already
+// configured are kept, so presets can be combined with each other and with
+// service-specific fields; apply them after WithMaskFields, which replaces
+// the list.
+func WithCompliancePreset(presets ...CompliancePreset) ConfigOption {
+	return func(cfg *Config) {
+		cfg.EnableMasking = true
+		for _, p := range presets {
+			for _, f := range p.MaskFields {
+				cfg.MaskFields = appendFold(cfg.MaskFields, f)
+				cfg.MaskFields = appendFold(cfg.MaskFields, strings.ReplaceAll(f, "_", ""))
+			}
+			for _, h := range p.ExcludeHeaders {
+				cfg.ExcludeHeaders = appendFold(cfg.ExcludeHeaders, h)
+			}
+		}
+	}
+}
+
+// appendFold appends s to list unless it is present, ignoring case
+func appendFold(list []string, s string) []string {
+	if slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) }) {
+		return list
+	}
+	return append(list, s)
+}
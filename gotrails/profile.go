@@ -0,0 +1,94 @@
+package gotrails
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// MaskProfile is a named set of masking rules used instead of the config's
+// for matching routes and outbound hosts, e.g. a strict profile for payment
+// routes and a relaxed one for the catalog
+type MaskProfile struct {
+	Name    string
+	Routes  []string       // request paths or gRPC methods, a trailing * matches any suffix
+	Hosts   []string       // outbound hosts, see MatchURLPattern
+	Options []ConfigOption // applied on top of the config, e.g. WithMaskFields
+
+	once   sync.Once
+	masker *masker.Masker
+}
+
+// WithMaskProfile adds a masking profile. The first profile matching a route
+// or host wins.
+func WithMaskProfile(p *MaskProfile) ConfigOption {
+	return func(c *Config) {
+		c.MaskProfiles = append(c.MaskProfiles, p)
+	}
+}
+
+// Masker returns the masker of the profile, built once from cfg with the
+// profile options applied
+func (p *MaskProfile) Masker(cfg *Config) *masker.Masker {
+	p.once.Do(func() {
+		profile := *cfg
+		profile.MaskProfiles = nil
+		profile.masker = nil
+		for _, opt := range p.Options {
+			opt(&profile)
+		}
+		p.masker = masker.New(profile.MaskerOptions()...)
+	})
+	return p.masker
+}
+
+// RouteMasker returns the masker of the first profile matching a request
+// path or gRPC method, nil when none does
+func (c *Config) RouteMasker(route string) *masker.Masker {
+	for _, p := range c.MaskProfiles {
+		for _, pattern := range p.Routes {
+			if matchPath(pattern, route) {
+				return p.Masker(c)
+			}
+		}
+	}
+	return nil
+}
+
+// HostMasker returns the masker of the first profile matching an outbound
+// URL, nil when none does
+func (c *Config) HostMasker(u *url.URL) *masker.Masker {
+	for _, p := range c.MaskProfiles {
+		for _, pattern := range p.Hosts {
+			if MatchURLPattern(pattern, u) {
+				return p.Masker(c)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchURLPattern reports whether u matches a host[/path] pattern. The
+// pattern is a host ("storage.example.com"), optionally with a leading
+// wildcard ("*.amazonaws.com") and a path ("api.example.com/upload/*", where
+// a trailing * matches any suffix).
+func MatchURLPattern(pattern string, u *url.URL) bool {
+	host, path, hasPath := strings.Cut(pattern, "/")
+	if suffix, ok := strings.CutPrefix(host, "*"); ok {
+		if !strings.HasSuffix(u.Hostname(), suffix) {
+			return false
+		}
+	} else if !strings.EqualFold(host, u.Host) && !strings.EqualFold(host, u.Hostname()) {
+		return false
+	}
+	if !hasPath {
+		return true
+	}
+	path = "/" + path
+	if prefix, ok := strings.CutSuffix(path, "*"); ok {
+		return strings.HasPrefix(u.Path, prefix)
+	}
+	return u.Path == path
+}
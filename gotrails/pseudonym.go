@@ -0,0 +1,126 @@
+package gotrails
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PseudonymPrefix marks a pseudonymized identifier
+const PseudonymPrefix = "psn:"
+
+// Pseudonym returns the keyed pseudonym of an identifier. The same value
+// always maps to the same pseudonym under a key, so trails of one user stay
+// linkable, but the value cannot be recovered without the key. Returns value
+// unchanged without a Config.PseudonymKey.
+func (c *Config) Pseudonym(value string) string {
+	if c == nil || len(c.PseudonymKey) == 0 || value == "" || strings.HasPrefix(value, PseudonymPrefix) {
+		return value
+	}
+	mac := hmac.New(sha256.New, c.PseudonymKey)
+	mac.Write([]byte(value))
+	return PseudonymPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// shouldPseudonymize reports whether values of the named field are
+// pseudonymized
+func (c *Config) shouldPseudonymize(field string) bool {
+	if len(c.PseudonymKey) == 0 {
+		return false
+	}
+	for _, f := range c.PseudonymFields {
+		if strings.EqualFold(f, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// pseudonymizeValue replaces the identifiers nested in v
+func (c *Config) pseudonymizeValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			switch {
+			case !c.shouldPseudonymize(k) || item == nil:
+				out[k] = c.pseudonymizeValue(item)
+			case isScalar(item):
+				out[k] = c.Pseudonym(fmt.Sprint(item))
+			default:
+				out[k] = c.pseudonymizeValue(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = c.pseudonymizeValue(item)
+		}
+		return out
+	case *RawBody:
+		return c.pseudonymizeValue(val.Value())
+	case nil, string, bool, float64, int, int64:
+		return v
+	default:
+		switch n := normalizeJSON(v).(type) {
+		case map[string]any, []any:
+			return c.pseudonymizeValue(n)
+		}
+		return v
+	}
+}
+
+func isScalar(v any) bool {
+	switch v.(type) {
+	case string, float64, int, int64, bool:
+		return true
+	}
+	return false
+}
+
+// pseudonymizeLocked replaces the identifiers in the captured bodies and the
+// metadata of the trail, assuming the lock is already held
+func (t *Trail) pseudonymizeLocked() {
+	if t.cfg == nil || len(t.cfg.PseudonymKey) == 0 || len(t.cfg.PseudonymFields) == 0 {
+		return
+	}
+	c := t.cfg
+	if t.Request != nil {
+		req := *t.Request
+		req.Body = c.pseudonymizeValue(req.Body)
+		t.Request = &req
+	}
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Body = c.pseudonymizeValue(resp.Body)
+		t.Response = &resp
+	}
+	if t.RPC != nil {
+		rpc := *t.RPC
+		rpc.Request, rpc.Response = c.pseudonymizeValue(rpc.Request), c.pseudonymizeValue(rpc.Response)
+		t.RPC = &rpc
+	}
+	if t.Message != nil {
+		msg := *t.Message
+		msg.Body = c.pseudonymizeValue(msg.Body)
+		t.Message = &msg
+	}
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		step.Request, step.Response = c.pseudonymizeValue(step.Request), c.pseudonymizeValue(step.Response)
+	}
+	for i := range t.Integrations {
+		in := &t.Integrations[i]
+		in.Request, in.Response = c.pseudonymizeValue(in.Request), c.pseudonymizeValue(in.Response)
+	}
+	for i := range t.Resources {
+		r := &t.Resources[i]
+		r.Before, r.After = c.pseudonymizeValue(r.Before), c.pseudonymizeValue(r.After)
+	}
+	if t.Metadata != nil {
+		t.Metadata = c.pseudonymizeValue(t.Metadata).(map[string]any)
+	}
+}
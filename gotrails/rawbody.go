@@ -0,0 +1,45 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// RawBody is a captured body whose parsing and masking are deferred until
+// the trail is serialized, see Config.LazyBodyParsing
+type RawBody struct {
+	data  []byte
+	parse func([]byte) any
+
+	once  sync.Once
+	value any
+}
+
+// NewRawBody wraps captured bytes with the function that parses and masks
+// them. data must not be modified afterwards.
+func NewRawBody(data []byte, parse func([]byte) any) *RawBody {
+	return &RawBody{data: data, parse: parse}
+}
+
+// Len returns the size of the captured bytes
+func (b *RawBody) Len() int {
+	return len(b.data)
+}
+
+// Value parses the body on first use and returns the parsed, masked value
+func (b *RawBody) Value() any {
+	b.once.Do(func() {
+		if b.parse != nil {
+			b.value = b.parse(b.data)
+		} else {
+			b.value = string(b.data)
+		}
+		b.data = nil
+	})
+	return b.value
+}
+
+// MarshalJSON encodes the parsed value
+func (b *RawBody) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.Value())
+}
@@ -0,0 +1,83 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// TrailRecord is the frozen snapshot of a trail produced by Finalize and
+// handed to sinks. It is taken under the trail lock and encoded up front, so
+// writes a handler makes to the trail after Finalize can never race with a
+// sink serializing the record. The embedded trail is a read-only copy: its
+// mutating methods are no-ops.
+//
+// With Config.LazyBodyParsing the encoding, the size budget and the hash are
+// computed when the record is first serialized instead; Hash is empty until
+// then.
+type TrailRecord struct {
+	*Trail
+
+	lazy bool
+	once sync.Once
+	data []byte // JSON encoding of the trail
+	err  error
+}
+
+// recordLocked snapshots the trail assuming the lock is already held
+func (t *Trail) recordLocked(lazy bool) *TrailRecord {
+	frozen := t.cloneLocked()
+	frozen.Hash = t.Hash
+	frozen.HashAlgorithm = t.HashAlgorithm
+	frozen.PrevHash = t.PrevHash
+	frozen.Signature = t.Signature
+	frozen.SignatureKeyID = t.SignatureKeyID
+	frozen.TrustedTimestamp = t.TrustedTimestamp
+	frozen.cfg = t.cfg
+	frozen.immutable = true
+
+	r := &TrailRecord{Trail: frozen, lazy: lazy}
+	if !lazy {
+		r.encode()
+	}
+	return r
+}
+
+// encode encodes the trail once, applying the deferred budget and hash first
+// for lazy records
+func (r *TrailRecord) encode() {
+	r.once.Do(func() {
+		t := r.Trail
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if r.lazy {
+			t.scanSecretsLocked()
+			t.pseudonymizeLocked()
+			t.shredLocked()
+			t.detectPIILocked()
+			t.enforceBudgetLocked()
+			t.HashAlgorithm = t.cfg.hashAlgorithm()
+			t.Hash = t.computeHashLocked()
+			t.signLocked()
+			t.timestampLocked()
+		}
+
+		// trailJSON drops the methods of Trail so encoding cannot recurse
+		type trailJSON Trail
+		r.data, r.err = json.Marshal((*trailJSON)(t))
+	})
+}
+
+// MarshalJSON returns the encoding of the trail record
+func (r *TrailRecord) MarshalJSON() ([]byte, error) {
+	if r == nil {
+		return []byte("null"), nil
+	}
+	r.encode()
+	return r.data, r.err
+}
+
+// Sum returns the hash of the record, computing it first for lazy records
+func (r *TrailRecord) Sum() string {
+	r.encode()
+	return r.Hash
+}
@@ -0,0 +1,257 @@
+package gotrails
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// Redaction records that a stored trail was re-masked after it was written
+type Redaction struct {
+	At           time.Time `json:"at"`
+	Reason       string    `json:"reason,omitempty"`
+	Fields       []string  `json:"fields"`                  // paths of the values masked
+	OriginalHash string    `json:"original_hash,omitempty"` // hash before the first redaction
+}
+
+// Redact re-applies the masking of cfg to a stored trail, for when a field
+// is discovered to be sensitive after trails were archived. It returns the
+// redacted trail, whether anything was masked, and an error if data is not a
+// trail. A Redaction entry listing the masked paths is appended, the hash is
+// recomputed and, with a signing key in cfg, the trail is signed again.
+func Redact(data []byte, cfg *Config, reason string) ([]byte, bool, error) {
+	t, err := UnmarshalTrail(data)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(t.Remask(cfg, reason)) == 0 {
+		return data, false, nil
+	}
+	out, err := MarshalTrail(t)
+	return out, err == nil, err
+}
+
+// Remask re-applies the masking of cfg to the trail and returns the paths of
+// the values it masked. See Redact.
+func (t *Trail) Remask(cfg *Config, reason string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	r := &remask{cfg: cfg, msk: cfg.Masker()}
+	requestHeaders := cfg.HeaderPolicy(HeaderDirectionRequest).Mask
+	responseHeaders := cfg.HeaderPolicy(HeaderDirectionResponse).Mask
+
+	if a := t.Actor; a != nil {
+		actor := *a
+		for _, f := range []struct {
+			name  string
+			value *string
+		}{
+			{"id", &actor.ID},
+			{"name", &actor.Name},
+			{"ip", &actor.IP},
+			{"session_id", &actor.SessionID},
+		} {
+			*f.value = r.value("actor."+f.name, f.name, *f.value).(string)
+		}
+		t.Actor = &actor
+	}
+	if t.Request != nil {
+		req := *t.Request
+		req.Query = r.query("request.query", req.Query)
+		req.Headers = r.headers("request.headers", req.Headers, requestHeaders)
+		req.Body = r.nested("request.body", req.Body)
+		t.Request = &req
+	}
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Headers = r.headers("response.headers", resp.Headers, responseHeaders)
+		resp.Body = r.nested("response.body", resp.Body)
+		t.Response = &resp
+	}
+	if t.RPC != nil {
+		rpc := *t.RPC
+		rpc.Request = r.nested("rpc.request", rpc.Request)
+		rpc.Response = r.nested("rpc.response", rpc.Response)
+		t.RPC = &rpc
+	}
+	if t.Message != nil {
+		msg := *t.Message
+		msg.Body = r.nested("message.body", msg.Body)
+		t.Message = &msg
+	}
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		step.Request = r.nested(fmt.Sprintf("internal_steps[%d].request", i), step.Request)
+		step.Response = r.nested(fmt.Sprintf("internal_steps[%d].response", i), step.Response)
+	}
+	for i := range t.Integrations {
+		in := &t.Integrations[i]
+		in.Request = r.nested(fmt.Sprintf("integrations[%d].request", i), in.Request)
+		in.Response = r.nested(fmt.Sprintf("integrations[%d].response", i), in.Response)
+	}
+	for i := range t.Resources {
+		res := &t.Resources[i]
+		res.Before = r.nested(fmt.Sprintf("resources[%d].before", i), res.Before)
+		res.After = r.nested(fmt.Sprintf("resources[%d].after", i), res.After)
+		for j := range res.Changes {
+			c := &res.Changes[j]
+			path := fmt.Sprintf("resources[%d].changes[%d]", i, j)
+			c.Before = r.change(path+".before", c.Path, c.Before)
+			c.After = r.change(path+".after", c.Path, c.After)
+		}
+	}
+	if t.Metadata != nil {
+		t.Metadata = r.nested("metadata", t.Metadata).(map[string]any)
+	}
+
+	if len(r.paths) == 0 {
+		return nil
+	}
+	redaction := Redaction{
+		At:           cfg.clock().Now(),
+		Reason:       reason,
+		Fields:       r.paths,
+		OriginalHash: t.chainHash(),
+	}
+	t.Redactions = append(t.Redactions, redaction)
+	if t.Hash != "" {
+		t.Hash = t.computeHashLocked()
+		prev := t.cfg
+		t.cfg = cfg
+		t.signLocked()
+		t.cfg = prev
+	}
+	return r.paths
+}
+
+// chainHash is the hash successors of the trail link to: the hash it had
+// before any redaction
+func (t *Trail) chainHash() string {
+	if len(t.Redactions) > 0 && t.Redactions[0].OriginalHash != "" {
+		return t.Redactions[0].OriginalHash
+	}
+	return t.Hash
+}
+
+// remask walks captured values, masking the fields the masker of cfg masks
+type remask struct {
+	cfg   *Config
+	msk   *masker.Masker
+	paths []string
+}
+
+// masked reports whether v already holds a masked value
+func (r *remask) masked(v any) bool {
+	s, ok := v.(string)
+	return ok && (s == r.cfg.MaskValue || masker.IsEncrypted(s) || masker.IsHashed(s))
+}
+
+// value masks v as the value of field
+func (r *remask) value(path, field string, v any) any {
+	return r.apply(path, v, r.msk.MaskField(field, v))
+}
+
+// change masks v as the value at change path
+func (r *remask) change(path, changePath string, v any) any {
+	if v == nil {
+		return v
+	}
+	return r.apply(path, v, maskChange(r.msk, changePath, v))
+}
+
+// nested masks the masked fields nested in v
+func (r *remask) nested(path string, v any) any {
+	return r.apply(path, v, r.msk.MaskAny(v))
+}
+
+// apply keeps the values the masker changed from before to after, except
+// those already masked, and records their paths. Keys and elements the
+// masker left out, e.g. beyond its limits, keep their stored value.
+func (r *remask) apply(path string, before, after any) any {
+	switch b := before.(type) {
+	case map[string]any:
+		a, ok := after.(map[string]any)
+		if !ok {
+			break
+		}
+		out := make(map[string]any, len(b))
+		for k, v := range b {
+			if masked, ok := a[k]; ok {
+				out[k] = r.apply(path+"."+k, v, masked)
+			} else {
+				out[k] = v
+			}
+		}
+		return out
+	case []any:
+		a, ok := after.([]any)
+		if !ok {
+			break
+		}
+		out := make([]any, len(b))
+		for i, v := range b {
+			if i < len(a) {
+				out[i] = r.apply(fmt.Sprintf("%s[%d]", path, i), v, a[i])
+			} else {
+				out[i] = v
+			}
+		}
+		return out
+	}
+	if before == nil || before == "" || r.masked(before) || reflect.DeepEqual(before, after) {
+		return before
+	}
+	r.paths = append(r.paths, path)
+	return after
+}
+
+// headers masks masked headers and the headers listed in mask
+func (r *remask) headers(path string, headers map[string][]string, mask []string) map[string][]string {
+	if headers == nil {
+		return nil
+	}
+	out := make(map[string][]string, len(headers))
+	for k, values := range headers {
+		out[k] = values
+		shouldMask := r.msk.ShouldMask(k) || slices.ContainsFunc(mask, func(m string) bool { return strings.EqualFold(m, k) })
+		if !shouldMask || len(values) == 0 || (len(values) == 1 && r.masked(values[0])) {
+			continue
+		}
+		r.paths = append(r.paths, path+"."+k)
+		out[k] = []string{r.msk.MaskedValue(strings.ToLower(k), values)}
+	}
+	return out
+}
+
+// query masks the values of masked query parameters
+func (r *remask) query(path, rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil || !r.msk.ShouldMask(name) {
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		if r.masked(value) {
+			continue
+		}
+		r.paths = append(r.paths, path+"."+name)
+		params[i] = key + "=" + url.QueryEscape(r.msk.MaskedValue(name, value))
+	}
+	return strings.Join(params, "&")
+}
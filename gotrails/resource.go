@@ -0,0 +1,45 @@
+package gotrails
+
+// Resource is a domain entity read or mutated during the request
+type Resource struct {
+	Type   string `json:"type"`             // e.g. "order", "user"
+	ID     string `json:"id"`               // e.g. "ord-123"
+	Action string `json:"action,omitempty"` // e.g. "read", "update"
+	Before any    `json:"before,omitempty"` // summary of the entity before the action
+	After  any    `json:"after,omitempty"`  // summary of the entity after the action
+
+	// Field-level diff recorded by RecordChange
+	Changes          []FieldChange `json:"changes,omitempty"`
+	ChangesTruncated bool          `json:"changes_truncated,omitempty"`
+}
+
+// Resource actions
+const (
+	ResourceActionRead   = "read"
+	ResourceActionCreate = "create"
+	ResourceActionUpdate = "update"
+	ResourceActionDelete = "delete"
+)
+
+// AddResource declares a domain entity touched by the request
+func (t *Trail) AddResource(resource Resource) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("AddResource") {
+		return
+	}
+	t.Resources = append(t.Resources, resource)
+}
+
+// HasResource reports whether the trail touched the entity, optionally
+// restricted to one action ("" matches any action)
+func (t *Trail) HasResource(resourceType, id, action string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, r := range t.Resources {
+		if r.Type == resourceType && r.ID == id && (action == "" || r.Action == action) {
+			return true
+		}
+	}
+	return false
+}
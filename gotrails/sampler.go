@@ -0,0 +1,210 @@
+package gotrails
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Sampler makes the head sampling decision of a new trail after the sampling
+// rate kept it, see Config.Sampler. now comes from the config's clock.
+type Sampler interface {
+	Sample(now time.Time) bool
+}
+
+// WithSampler sets the sampler applied after the sampling rate
+func WithSampler(s Sampler) ConfigOption {
+	return func(c *Config) {
+		c.Sampler = s
+	}
+}
+
+// WithRateLimit caps the trails created to perSecond, allowing bursts of up
+// to burst trails, see RateLimitSampler
+func WithRateLimit(perSecond float64, burst int) ConfigOption {
+	return WithSampler(NewRateLimitSampler(perSecond, burst))
+}
+
+// RateLimitSampler keeps at most a fixed number of trails per second, a hard
+// budget for the sinks whatever the traffic. It is a token bucket: each
+// trail takes a token, tokens refill at the rate and up to burst accumulate
+// while traffic is low.
+type RateLimitSampler struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	tokens    float64
+	last      time.Time
+}
+
+// NewRateLimitSampler creates a sampler keeping perSecond trails per second
+// with bursts of up to burst trails. A burst below 1 is the rate rounded up,
+// at least 1. The bucket starts full.
+func NewRateLimitSampler(perSecond float64, burst int) *RateLimitSampler {
+	perSecond = max(perSecond, 0)
+	if burst < 1 {
+		burst = max(int(math.Ceil(perSecond)), 1)
+	}
+	return &RateLimitSampler{perSecond: perSecond, burst: float64(burst), tokens: float64(burst)}
+}
+
+// Sample takes a token if one is left
+func (s *RateLimitSampler) Sample(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.last.IsZero() && now.After(s.last) {
+		s.tokens = min(s.tokens+now.Sub(s.last).Seconds()*s.perSecond, s.burst)
+	}
+	if s.last.IsZero() || now.After(s.last) {
+		s.last = now
+	}
+	if s.tokens < 1 {
+		return false
+	}
+	s.tokens--
+	return true
+}
+
+// sample makes the head sampling decision of a new trail: the sampling rate,
+// or the Controls override, then the Sampler
+func (c *Config) sample(rate float64) bool {
+	if override, ok := c.Controls.SamplingRate(); ok {
+		rate = override
+	}
+	keep := sampled(rate) && (c.Sampler == nil || c.Sampler.Sample(c.clock().Now()))
+	c.Controls.count(keep)
+	return keep
+}
+
+// ErrorBias keeps the trails dropped by head sampling that turn out to hold
+// failures, so sampling never hides them. With an ErrorBias every trail is
+// recorded and the dropped ones are only discarded at Finalize, see
+// Trail.Sampled. Kept failures are not limited by the Sampler.
+type ErrorBias struct {
+	Errors        bool            // keep trails with recorded errors, non-OK gRPC codes or terminated contexts
+	StatusClasses map[int]float64 // keep rate per HTTP status class, e.g. 5 for 5xx; 1 keeps them all
+}
+
+// DefaultErrorBias keeps every trail with errors or a non-2xx response
+func DefaultErrorBias() ErrorBias {
+	return ErrorBias{Errors: true, StatusClasses: map[int]float64{3: 1, 4: 1, 5: 1}}
+}
+
+// WithErrorBias keeps the failures dropped by head sampling, see ErrorBias
+func WithErrorBias(b ErrorBias) ConfigOption {
+	return func(c *Config) {
+		c.ErrorBias = &b
+	}
+}
+
+// keepsLocked reports whether the bias keeps t, dropped by head sampling
+func (b *ErrorBias) keepsLocked(t *Trail) bool {
+	if b == nil {
+		return false
+	}
+	if b.Errors && (len(t.Errors) > 0 || t.Cancelled || t.DeadlineExceeded || t.RPC != nil && t.RPC.Code != "" && t.RPC.Code != "OK") {
+		return true
+	}
+	if t.Response == nil {
+		return false
+	}
+	rate, ok := b.StatusClasses[t.Response.Status/100]
+	return ok && sampled(rate)
+}
+
+// newSampledTrail creates a trail if the head sampling keeps it at rate, or
+// else an unsampled one left to the ErrorBias
+func newSampledTrail(traceID, requestID string, cfg *Config, rate float64) *Trail {
+	if cfg.sample(rate) {
+		return newTrail(traceID, requestID, cfg)
+	}
+	if cfg.ErrorBias == nil {
+		return nil
+	}
+	t := newTrail(traceID, requestID, cfg)
+	t.unsampled = true
+	return t
+}
+
+// Sampled reports whether the trail is to be written to the sinks. It is
+// false for a trail dropped by head sampling, see Config.ErrorBias, unless
+// Finalize found a failure the bias keeps.
+func (t *Trail) Sampled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return !t.unsampled
+}
+
+// adaptiveBuckets is the number of buckets of an AdaptiveSampler window
+const adaptiveBuckets = 12
+
+// AdaptiveSampler adjusts its rate to keep about a target number of trails
+// per minute: it keeps target/seen of the trails, seen being the trails
+// offered over a sliding window. Services below the target keep every trail
+// and bursts back off within a bucket of the window.
+type AdaptiveSampler struct {
+	mu      sync.Mutex
+	target  float64       // trails per window
+	width   time.Duration // of a bucket
+	buckets [adaptiveBuckets]int
+	cur     int
+	start   time.Time // of the current bucket
+	rate    float64
+}
+
+// NewAdaptiveSampler creates a sampler keeping about perMinute trails per
+// minute, measured over window. A window of 0 is one minute.
+func NewAdaptiveSampler(perMinute float64, window time.Duration) *AdaptiveSampler {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &AdaptiveSampler{
+		target: max(perMinute, 0) * window.Minutes(),
+		width:  max(window/adaptiveBuckets, 1),
+		rate:   1,
+	}
+}
+
+// WithAdaptiveSampling keeps about perMinute trails per minute, measured over
+// the last minute, see AdaptiveSampler
+func WithAdaptiveSampling(perMinute float64) ConfigOption {
+	return WithSampler(NewAdaptiveSampler(perMinute, 0))
+}
+
+// Sample counts the trail and keeps it at the current rate
+func (s *AdaptiveSampler) Sample(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(now)
+	s.buckets[s.cur]++
+	seen := 0
+	for _, n := range s.buckets {
+		seen += n
+	}
+	s.rate = min(s.target/float64(seen), 1)
+	return sampled(s.rate)
+}
+
+// Rate returns the rate of the last decision
+func (s *AdaptiveSampler) Rate() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate
+}
+
+// advance moves the window to now, clearing the buckets left behind
+func (s *AdaptiveSampler) advance(now time.Time) {
+	if s.start.IsZero() {
+		s.start = now
+		return
+	}
+	n := int(now.Sub(s.start) / s.width)
+	if n <= 0 {
+		return
+	}
+	for range min(n, adaptiveBuckets) {
+		s.cur = (s.cur + 1) % adaptiveBuckets
+		s.buckets[s.cur] = 0
+	}
+	s.start = s.start.Add(time.Duration(n) * s.width)
+}
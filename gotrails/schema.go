@@ -0,0 +1,72 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the version of the serialized trail format written by this
+// package. Fields may be added within a version; renaming, removing or
+// changing the meaning of a field bumps the version and registers an upgrade
+// in schemaUpgrades so that archived trails remain readable.
+const SchemaVersion = 1
+
+// ErrUnsupportedSchemaVersion is returned when decoding a trail written by a
+// newer version of this package
+var ErrUnsupportedSchemaVersion = errors.New("gotrails: unsupported schema version")
+
+// schemaUpgrades migrates a decoded trail document from the version of its
+// index to the next one. Version 0 is the format written before
+// schema_version existed. Its fields read as version 1, but its hash was
+// computed over encoding/json output without schema_version or hash_alg, so
+// a version 0 trail no longer verifies against ComputeHash.
+var schemaUpgrades = []func(doc map[string]any) error{
+	0: func(doc map[string]any) error { return nil },
+}
+
+// MarshalTrail encodes the trail as JSON in the current schema version
+func MarshalTrail(t *Trail) ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// trailJSON drops the methods of Trail so encoding cannot recurse
+	type trailJSON Trail
+	return json.Marshal((*trailJSON)(t))
+}
+
+// UnmarshalTrail decodes a JSON trail of any supported schema version,
+// upgrading it to the current version
+func UnmarshalTrail(data []byte) (*Trail, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	version := 0
+	if v, ok := doc["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version < 0 || version > SchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedSchemaVersion, version)
+	}
+	for ; version < SchemaVersion; version++ {
+		if err := schemaUpgrades[version](doc); err != nil {
+			return nil, fmt.Errorf("gotrails: upgrade schema version %d: %w", version, err)
+		}
+	}
+	doc["schema_version"] = SchemaVersion
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	t := &Trail{}
+	if err := json.Unmarshal(upgraded, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
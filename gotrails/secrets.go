@@ -0,0 +1,307 @@
+package gotrails
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// SecretKind is a kind of credential recognized by SecretScanner
+type SecretKind string
+
+// Secret kinds
+const (
+	SecretAWSAccessKey SecretKind = "aws_access_key"
+	SecretJWT          SecretKind = "jwt"
+	SecretBearerToken  SecretKind = "bearer_token"
+	SecretPrivateKey   SecretKind = "private_key"
+	SecretHighEntropy  SecretKind = "high_entropy"
+)
+
+// SecretFinding locates a secret masked by SecretScanner. The secret itself
+// is never recorded.
+type SecretFinding struct {
+	Kind SecretKind `json:"kind"`
+	Path string     `json:"path"` // e.g. "request.headers.X-Debug" or "response.body.note"
+}
+
+// secretPattern matches a known secret format. Only the group named "secret",
+// if any, is masked.
+type secretPattern struct {
+	kind    SecretKind
+	pattern *regexp.Regexp
+}
+
+var defaultSecretPatterns = []secretPattern{
+	{SecretPrivateKey, regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{SecretAWSAccessKey, regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`)},
+	{SecretJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]*`)},
+	{SecretBearerToken, regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]{16,})`)},
+}
+
+// secretToken splits text into candidate tokens for the entropy check
+var secretToken = regexp.MustCompile(`[A-Za-z0-9+/_=.-]+`)
+
+// SecretScanner masks credentials anywhere in the captured bodies, headers,
+// queries, errors, events, actor, resources, attachments and metadata of
+// finalized trails, whatever the field is named: known formats such as AWS
+// access keys, JWTs and bearer tokens, and long high-entropy strings. Trail
+// identifiers, tags and links are not scanned.
+type SecretScanner struct {
+	patterns   []secretPattern
+	minEntropy float64 // bits per character, 0 disables the entropy check
+	minLength  int
+	report     func(t *Trail, findings []SecretFinding)
+}
+
+// SecretOption is an option for SecretScanner
+type SecretOption func(*SecretScanner)
+
+// WithSecretPattern adds a secret format. Matches are masked whole, or only
+// their group named "secret" if the pattern has one.
+func WithSecretPattern(kind SecretKind, pattern *regexp.Regexp) SecretOption {
+	return func(s *SecretScanner) {
+		s.patterns = append(s.patterns, secretPattern{kind, pattern})
+	}
+}
+
+// WithSecretEntropy sets the Shannon entropy in bits per character above
+// which tokens of at least minLength characters are masked. The default is
+// 4.3 bits over 24 characters; a zero threshold disables the check.
+func WithSecretEntropy(bits float64, minLength int) SecretOption {
+	return func(s *SecretScanner) {
+		s.minEntropy = bits
+		s.minLength = minLength
+	}
+}
+
+// WithSecretReport sets a handler called with the findings of every trail
+// containing secrets. It runs during Finalize with the trail locked, so it
+// must only read the trail fields.
+func WithSecretReport(fn func(t *Trail, findings []SecretFinding)) SecretOption {
+	return func(s *SecretScanner) {
+		s.report = fn
+	}
+}
+
+// NewSecretScanner creates a SecretScanner with the built-in secret formats
+func NewSecretScanner(opts ...SecretOption) *SecretScanner {
+	s := &SecretScanner{
+		patterns:   append([]secretPattern(nil), defaultSecretPatterns...),
+		minEntropy: 4.3,
+		minLength:  24,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// secretScan walks captured values, masking secrets and collecting findings
+type secretScan struct {
+	*SecretScanner
+	mask     string
+	findings []SecretFinding
+}
+
+// value scans v at path and returns it with its secrets masked
+func (s *secretScan) value(path string, v any) any {
+	switch val := v.(type) {
+	case nil, bool, float64, int, int64:
+		return v
+	case string:
+		return s.text(path, val)
+	case *RawBody:
+		return s.value(path, val.Value())
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = s.value(path+"."+k, item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = s.value(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return out
+	default:
+		switch n := normalizeJSON(v).(type) {
+		case map[string]any, []any, string:
+			before := len(s.findings)
+			if out := s.value(path, n); len(s.findings) > before {
+				return out
+			}
+		}
+		return v
+	}
+}
+
+// headers scans header values, returning a copy if any were masked
+func (s *secretScan) headers(path string, h map[string][]string) map[string][]string {
+	var out map[string][]string
+	for name, values := range h {
+		for i, v := range values {
+			masked := s.text(path+"."+name, v)
+			if masked == v {
+				continue
+			}
+			if out == nil {
+				out = make(map[string][]string, len(h))
+				for k, vs := range h {
+					out[k] = append([]string(nil), vs...)
+				}
+			}
+			out[name][i] = masked
+		}
+	}
+	if out == nil {
+		return h
+	}
+	return out
+}
+
+// text masks the secrets in a string value
+func (s *secretScan) text(path, v string) string {
+	if v == "" || v == s.mask || masker.IsEncrypted(v) {
+		return v
+	}
+	path = strings.TrimPrefix(path, ".")
+	for _, p := range s.patterns {
+		found := false
+		group := p.pattern.SubexpIndex("secret")
+		v = p.pattern.ReplaceAllStringFunc(v, func(match string) string {
+			found = true
+			if group < 0 {
+				return s.mask
+			}
+			sub := p.pattern.FindStringSubmatchIndex(match)
+			return match[:sub[2*group]] + s.mask + match[sub[2*group+1]:]
+		})
+		if found {
+			s.findings = append(s.findings, SecretFinding{Kind: p.kind, Path: path})
+		}
+	}
+	if s.minEntropy <= 0 {
+		return v
+	}
+	found := false
+	v = secretToken.ReplaceAllStringFunc(v, func(token string) string {
+		if len(token) < s.minLength || masker.IsEncrypted(token) || shannonEntropy(token) < s.minEntropy {
+			return token
+		}
+		found = true
+		return s.mask
+	})
+	if found {
+		s.findings = append(s.findings, SecretFinding{Kind: SecretHighEntropy, Path: path})
+	}
+	return v
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	n := 0
+	for _, r := range s {
+		counts[r]++
+		n++
+	}
+	var bits float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		bits -= p * math.Log2(p)
+	}
+	return bits
+}
+
+// scanSecretsLocked masks secrets in the captured values of the trail,
+// assuming the lock is already held
+func (t *Trail) scanSecretsLocked() {
+	if t.cfg == nil || t.cfg.SecretScanner == nil {
+		return
+	}
+	scan := &secretScan{SecretScanner: t.cfg.SecretScanner, mask: t.cfg.MaskValue}
+
+	if t.Request != nil {
+		req := *t.Request
+		req.Query = scan.text("request.query", req.Query)
+		req.Headers = scan.headers("request.headers", req.Headers)
+		req.Body = scan.value("request.body", req.Body)
+		t.Request = &req
+	}
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Headers = scan.headers("response.headers", resp.Headers)
+		resp.Body = scan.value("response.body", resp.Body)
+		t.Response = &resp
+	}
+	if t.RPC != nil {
+		rpc := *t.RPC
+		rpc.Metadata = scan.headers("rpc.metadata", rpc.Metadata)
+		rpc.Request = scan.value("rpc.request", rpc.Request)
+		rpc.Response = scan.value("rpc.response", rpc.Response)
+		t.RPC = &rpc
+	}
+	if t.Message != nil {
+		msg := *t.Message
+		msg.Headers = scan.headers("message.headers", msg.Headers)
+		msg.Body = scan.value("message.body", msg.Body)
+		t.Message = &msg
+	}
+	for i := range t.Integrations {
+		in := &t.Integrations[i]
+		in.Request = scan.value(fmt.Sprintf("integrations[%d].request", i), in.Request)
+		in.Response = scan.value(fmt.Sprintf("integrations[%d].response", i), in.Response)
+	}
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		step.Request = scan.value(fmt.Sprintf("internal_steps[%d].request", i), step.Request)
+		step.Response = scan.value(fmt.Sprintf("internal_steps[%d].response", i), step.Response)
+	}
+	for i := range t.Errors {
+		t.Errors[i].Message = scan.text(fmt.Sprintf("errors[%d].message", i), t.Errors[i].Message)
+	}
+	for i := range t.Events {
+		if attrs := t.Events[i].Attributes; attrs != nil {
+			t.Events[i].Attributes = scan.value(fmt.Sprintf("events[%d].attributes", i), attrs).(map[string]any)
+		}
+	}
+	if t.Actor != nil {
+		actor := *t.Actor
+		actor.ID = scan.text("actor.id", actor.ID)
+		actor.Name = scan.text("actor.name", actor.Name)
+		actor.SessionID = scan.text("actor.session_id", actor.SessionID)
+		t.Actor = &actor
+	}
+	for i := range t.Resources {
+		res := &t.Resources[i]
+		res.Before = scan.value(fmt.Sprintf("resources[%d].before", i), res.Before)
+		res.After = scan.value(fmt.Sprintf("resources[%d].after", i), res.After)
+		for j := range res.Changes {
+			c := &res.Changes[j]
+			path := fmt.Sprintf("resources[%d].changes[%d]", i, j)
+			c.Before = scan.value(path+".before", c.Before)
+			c.After = scan.value(path+".after", c.After)
+		}
+	}
+	for i := range t.Attachments {
+		a := &t.Attachments[i]
+		a.Name = scan.text(fmt.Sprintf("attachments[%d].name", i), a.Name)
+		a.URI = scan.text(fmt.Sprintf("attachments[%d].uri", i), a.URI)
+	}
+	if t.Metadata != nil {
+		t.Metadata = scan.value("metadata", t.Metadata).(map[string]any)
+	}
+
+	if len(scan.findings) == 0 {
+		return
+	}
+	t.SecretFindings = scan.findings
+	if scan.report != nil {
+		scan.report(t, scan.findings)
+	}
+}
@@ -0,0 +1,85 @@
+package gotrails
+
+import "encoding/json"
+
+// BodySerializer converts typed payloads attached by handlers (request,
+// response, step and integration bodies) into the value stored in the trail, e.g.
+// payload.StructSerializer
+type BodySerializer interface {
+	Serialize(v any) any
+}
+
+// BodySerializerFunc adapts a function to BodySerializer
+type BodySerializerFunc func(v any) any
+
+// Serialize calls f(v)
+func (f BodySerializerFunc) Serialize(v any) any {
+	return f(v)
+}
+
+// serializeBody converts a typed body with the configured serializer
+func (t *Trail) serializeBody(v any) any {
+	if v == nil || t.cfg == nil || t.cfg.BodySerializer == nil {
+		return v
+	}
+	return t.cfg.BodySerializer.Serialize(v)
+}
+
+// serializeTyped converts a typed payload with the configured serializer,
+// keeping JSON values such as the maps built and masked by transports as is
+func (t *Trail) serializeTyped(v any) any {
+	switch v.(type) {
+	case map[string]any, []any, string, float64, bool, json.Number:
+		return v
+	}
+	return t.serializeBody(v)
+}
+
+// metadataOnly reports whether the trail captures no bodies
+func (t *Trail) metadataOnly() bool {
+	return t.cfg != nil && t.cfg.IsMetadataOnly()
+}
+
+// SetRequestBody replaces the captured request body with a typed payload,
+// converted by the configured BodySerializer. It is a no-op in metadata-only mode.
+func (t *Trail) SetRequestBody(v any) {
+	if t.metadataOnly() {
+		return
+	}
+	body := t.serializeBody(v)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetRequestBody") {
+		return
+	}
+	var req HTTPRequest
+	if t.Request != nil {
+		req = *t.Request
+	}
+	req.Body = body
+	t.Request = &req
+}
+
+// SetResponseBody sets the captured response body to a typed payload,
+// converted by the configured BodySerializer. It takes precedence over the
+// body captured by the middleware. It is a no-op in metadata-only mode.
+func (t *Trail) SetResponseBody(v any) {
+	if t.metadataOnly() {
+		return
+	}
+	body := t.serializeBody(v)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rejectLocked("SetResponseBody") {
+		return
+	}
+	var resp HTTPResponse
+	if t.Response != nil {
+		resp = *t.Response
+	}
+	resp.Body = body
+	t.Response = &resp
+	t.typedResponseBody = true
+}
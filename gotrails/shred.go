@@ -0,0 +1,281 @@
+package gotrails
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// ErrSubjectForgotten is returned when the data key of a subject was destroyed
+var ErrSubjectForgotten = errors.New("gotrails: subject key destroyed")
+
+// SubjectKeyStore holds one data key per data subject. Key IDs are written to
+// the trails next to the ciphertext, so they must not reveal the subject.
+type SubjectKeyStore interface {
+	// SubjectKey returns the data key of a subject, creating it on first use
+	SubjectKey(ctx context.Context, subject string) (keyID string, key []byte, err error)
+	// LookupKey returns a data key by ID, ErrSubjectForgotten once destroyed
+	LookupKey(ctx context.Context, keyID string) ([]byte, error)
+	// DestroySubjectKey destroys the data key of a subject
+	DestroySubjectKey(ctx context.Context, subject string) error
+}
+
+// MemoryKeyStore is an in-process SubjectKeyStore, keys are lost with the
+// process
+type MemoryKeyStore struct {
+	mu        sync.Mutex
+	subjects  map[string]string // subject to key ID
+	keys      map[string][]byte
+	destroyed map[string]bool
+}
+
+// NewMemoryKeyStore creates a new MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		subjects:  make(map[string]string),
+		keys:      make(map[string][]byte),
+		destroyed: make(map[string]bool),
+	}
+}
+
+// SubjectKey returns the data key of subject, creating it on first use
+func (s *MemoryKeyStore) SubjectKey(ctx context.Context, subject string) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.subjects[subject]; ok {
+		return id, s.keys[id], nil
+	}
+
+	id := make([]byte, 16)
+	key := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, err
+	}
+	keyID := hex.EncodeToString(id)
+	s.subjects[subject] = keyID
+	s.keys[keyID] = key
+	return keyID, key, nil
+}
+
+// LookupKey returns a data key by ID
+func (s *MemoryKeyStore) LookupKey(ctx context.Context, keyID string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.destroyed[keyID] {
+		return nil, ErrSubjectForgotten
+	}
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, masker.ErrUnknownKey
+	}
+	return key, nil
+}
+
+// DestroySubjectKey destroys the data key of subject; a returning subject
+// gets a new key
+func (s *MemoryKeyStore) DestroySubjectKey(ctx context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.subjects[subject]; ok {
+		delete(s.keys, id)
+		delete(s.subjects, subject)
+		s.destroyed[id] = true
+	}
+	return nil
+}
+
+// Shredder encrypts the personal fields of every finalized trail with a data
+// key of the trail's subject. Destroying the key (crypto-shredding) makes the
+// subject's data in archived trails unreadable without rewriting them; the
+// hashes, computed over the ciphertext, stay valid.
+type Shredder struct {
+	store   SubjectKeyStore
+	fields  map[string]bool
+	subject func(*Trail) string
+}
+
+// ShredOption is an option for Shredder
+type ShredOption func(*Shredder)
+
+// WithShredSubject sets how the subject of a trail is found, by default the
+// actor ID. It runs during Finalize with the trail locked, so it must read
+// the trail fields directly instead of calling trail methods.
+func WithShredSubject(fn func(*Trail) string) ShredOption {
+	return func(s *Shredder) {
+		s.subject = fn
+	}
+}
+
+// NewShredder creates a Shredder encrypting the named personal fields
+func NewShredder(store SubjectKeyStore, fields []string, opts ...ShredOption) *Shredder {
+	s := &Shredder{
+		store:  store,
+		fields: make(map[string]bool, len(fields)),
+		subject: func(t *Trail) string {
+			if t.Actor != nil {
+				return t.Actor.ID
+			}
+			return ""
+		},
+	}
+	for _, f := range fields {
+		s.fields[strings.ToLower(f)] = true
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Forget destroys the data key of subject
+func (s *Shredder) Forget(ctx context.Context, subject string) error {
+	return s.store.DestroySubjectKey(ctx, subject)
+}
+
+// Reveal decrypts a personal field value, ErrSubjectForgotten once the
+// subject's key was destroyed
+func (s *Shredder) Reveal(ctx context.Context, field, envelope string) (any, error) {
+	keyID, ok := masker.EnvelopeKeyID(envelope)
+	if !ok {
+		return nil, masker.ErrNotEncrypted
+	}
+	key, err := s.store.LookupKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	return masker.Decrypt(field, envelope, func(string) ([]byte, bool) { return key, true })
+}
+
+// shredLocked encrypts the personal fields of the trail assuming the lock is
+// already held. Without a key the values are replaced by the mask value.
+func (t *Trail) shredLocked() {
+	if t.cfg == nil || t.cfg.Shredder == nil {
+		return
+	}
+	s := t.cfg.Shredder
+	subject := s.subject(t)
+	if subject == "" {
+		return
+	}
+
+	encrypt := func(field string, v any) string { return t.cfg.MaskValue }
+	if keyID, key, err := s.store.SubjectKey(context.Background(), subject); err == nil {
+		if enc, err := masker.NewAESEncrypter(keyID, key); err == nil {
+			encrypt = func(field string, v any) string {
+				if out, err := enc.EncryptField(field, v); err == nil {
+					return out
+				}
+				return t.cfg.MaskValue
+			}
+		}
+	}
+	shred := func(v any) any { return s.shredValue(v, encrypt) }
+
+	if a := t.Actor; a != nil {
+		actor := *a
+		for _, f := range []struct {
+			name  string
+			value *string
+		}{
+			{"id", &actor.ID},
+			{"name", &actor.Name},
+			{"ip", &actor.IP},
+			{"session_id", &actor.SessionID},
+		} {
+			if *f.value != "" && s.fields[f.name] {
+				*f.value = encrypt(f.name, *f.value)
+			}
+		}
+		t.Actor = &actor
+	}
+	if t.Request != nil {
+		req := *t.Request
+		req.Body = shred(req.Body)
+		t.Request = &req
+	}
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Body = shred(resp.Body)
+		t.Response = &resp
+	}
+	if t.RPC != nil {
+		rpc := *t.RPC
+		rpc.Request, rpc.Response = shred(rpc.Request), shred(rpc.Response)
+		t.RPC = &rpc
+	}
+	if t.Message != nil {
+		msg := *t.Message
+		msg.Body = shred(msg.Body)
+		t.Message = &msg
+	}
+	for i := range t.InternalSteps {
+		step := &t.InternalSteps[i]
+		step.Request, step.Response = shred(step.Request), shred(step.Response)
+	}
+	for i := range t.Integrations {
+		in := &t.Integrations[i]
+		in.Request, in.Response = shred(in.Request), shred(in.Response)
+	}
+	for i := range t.Resources {
+		r := &t.Resources[i]
+		r.Before, r.After = shred(r.Before), shred(r.After)
+		for j := range r.Changes {
+			c := &r.Changes[j]
+			if field := lastPathField(c.Path); s.fields[strings.ToLower(field)] {
+				if c.Before != nil {
+					c.Before = encrypt(field, c.Before)
+				}
+				if c.After != nil {
+					c.After = encrypt(field, c.After)
+				}
+				continue
+			}
+			c.Before, c.After = shred(c.Before), shred(c.After)
+		}
+	}
+	if t.Metadata != nil {
+		t.Metadata = shred(t.Metadata).(map[string]any)
+	}
+}
+
+// shredValue encrypts the personal fields nested in v
+func (s *Shredder) shredValue(v any, encrypt func(string, any) string) any {
+	switch val := v.(type) {
+	case nil, string, bool, float64, int, int64:
+		return v
+	case *RawBody:
+		return s.shredValue(val.Value(), encrypt)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			if s.fields[strings.ToLower(k)] && item != nil {
+				out[k] = encrypt(k, item)
+			} else {
+				out[k] = s.shredValue(item, encrypt)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = s.shredValue(item, encrypt)
+		}
+		return out
+	default:
+		// Typed values are shredded in their JSON form
+		switch n := normalizeJSON(v).(type) {
+		case map[string]any, []any:
+			return s.shredValue(n, encrypt)
+		default:
+			return v
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package gotrails
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// Signature verification errors
+var (
+	ErrUnsigned          = errors.New("gotrails: trail is not signed")
+	ErrUnknownSigningKey = errors.New("gotrails: unknown signing key")
+	ErrHashMismatch      = errors.New("gotrails: trail hash does not match its content")
+	ErrInvalidSignature  = errors.New("gotrails: invalid trail signature")
+)
+
+// signLocked signs the trail hash with the configured key, or the active
+// version of the configured keyring, assuming the lock is already held
+func (t *Trail) signLocked() {
+	if t.cfg == nil {
+		return
+	}
+	if t.cfg.Keyring != nil {
+		if key, ok := t.cfg.Keyring.Active(t.cfg.clock().Now()); ok {
+			t.Signature = signHash(key.Secret, t.Hash)
+			t.SignatureKeyID = key.ID
+		}
+		return
+	}
+	if len(t.cfg.SigningKey) == 0 {
+		return
+	}
+	t.Signature = signHash(t.cfg.SigningKey, t.Hash)
+	t.SignatureKeyID = t.cfg.SigningKeyID
+}
+
+func signHash(key []byte, hash string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks that the trail content matches its hash and that the
+// hash was signed with the key keys returns for the trail's key ID
+func (t *Trail) VerifySignature(keys func(keyID string) ([]byte, bool)) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.Signature == "" {
+		return ErrUnsigned
+	}
+	key, ok := keys(t.SignatureKeyID)
+	if !ok {
+		return ErrUnknownSigningKey
+	}
+	if t.computeHashLocked() != t.Hash {
+		return ErrHashMismatch
+	}
+	if !hmac.Equal([]byte(signHash(key, t.Hash)), []byte(t.Signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
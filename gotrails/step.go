@@ -0,0 +1,121 @@
+package gotrails
+
+import (
+	"context"
+	"sync"
+)
+
+// WithStep returns a context in which step is the current step. Steps added
+// with AddInternalStepToContext or TraceStep using the returned context
+// become children of step.
+func WithStep(ctx context.Context, step *InternalStep) context.Context {
+	if step == nil || step.ID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, stepContextKey, step.ID)
+}
+
+// StepIDFromContext returns the ID of the current step in context, if any
+func StepIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(stepContextKey).(string)
+	return id
+}
+
+// StepNode is an internal step together with its child steps
+type StepNode struct {
+	InternalStep
+	Children []*StepNode `json:"children,omitempty"`
+}
+
+// StepTree returns the internal steps arranged by their parent/child links.
+// Steps whose parent is not on the trail are returned as roots. Roots and
+// children keep the order in which they were added.
+func (t *Trail) StepTree() []*StepNode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	nodes := make([]*StepNode, len(t.InternalSteps))
+	byID := make(map[string]*StepNode, len(t.InternalSteps))
+	for i, step := range t.InternalSteps {
+		nodes[i] = &StepNode{InternalStep: step}
+		if step.ID != "" {
+			byID[step.ID] = nodes[i]
+		}
+	}
+
+	roots := make([]*StepNode, 0)
+	for _, node := range nodes {
+		if parent, ok := byID[node.ParentID]; ok && parent != node {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// StepHandle is an in-flight step started with StartStepCtx
+type StepHandle struct {
+	mu    sync.Mutex
+	step  InternalStep
+	trail *Trail
+	clock Clock
+	ended bool
+}
+
+// StartStepCtx starts a step and returns a handle to end it, together with a
+// context in which the step is the parent of nested steps. The step is linked
+// to the current step in ctx and added to the trail in ctx when ended.
+//
+//	step, ctx := gotrails.StartStepCtx(ctx, "PriceOrder")
+//	defer func() { step.End(resp, err) }()
+func StartStepCtx(ctx context.Context, name string) (*StepHandle, context.Context) {
+	clock := ClockFromContext(ctx)
+	h := &StepHandle{
+		step:  startStep(clock, name, nil, nil),
+		trail: GetTrail(ctx),
+		clock: clock,
+	}
+	h.step.ParentID = StepIDFromContext(ctx)
+	return h, WithStep(ctx, &h.step)
+}
+
+// ID returns the step ID
+func (h *StepHandle) ID() string {
+	return h.step.ID
+}
+
+// SetRequest sets the step request
+func (h *StepHandle) SetRequest(req any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.step.Request = req
+}
+
+// SetAttr sets a step attribute
+func (h *StepHandle) SetAttr(key string, value any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.step.Attributes == nil {
+		h.step.Attributes = make(map[string]any)
+	}
+	h.step.Attributes[key] = value
+}
+
+// End sets the latency, response and error of the step and adds it to the
+// trail. Only the first call has an effect.
+func (h *StepHandle) End(resp any, err error) {
+	h.mu.Lock()
+	if h.ended {
+		h.mu.Unlock()
+		return
+	}
+	h.ended = true
+	endStep(h.clock, &h.step, resp, err)
+	step := h.step
+	h.mu.Unlock()
+
+	if h.trail != nil {
+		h.trail.AddInternalStep(step)
+	}
+}
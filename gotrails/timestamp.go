@@ -0,0 +1,226 @@
+package gotrails
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// TimestampToken is a trusted timestamp over a trail hash or anchor root
+type TimestampToken struct {
+	Authority string    `json:"authority"`
+	Time      time.Time `json:"time"`  // time asserted by the authority
+	Token     []byte    `json:"token"` // DER encoded RFC 3161 TimeStampToken
+}
+
+// Timestamper obtains a trusted timestamp for a hex encoded hash
+type Timestamper interface {
+	Timestamp(ctx context.Context, hash string) (*TimestampToken, error)
+}
+
+// TimestamperFunc adapts a function to a Timestamper
+type TimestamperFunc func(ctx context.Context, hash string) (*TimestampToken, error)
+
+// Timestamp calls f
+func (f TimestamperFunc) Timestamp(ctx context.Context, hash string) (*TimestampToken, error) {
+	return f(ctx, hash)
+}
+
+// timestampLocked requests a timestamp for the trail hash from the configured
+// authority assuming the lock is already held. A failed request leaves the
+// trail without a token.
+func (t *Trail) timestampLocked() {
+	if t.cfg == nil || t.cfg.Timestamper == nil || t.Hash == "" {
+		return
+	}
+	token, err := t.cfg.Timestamper.Timestamp(context.Background(), t.Hash)
+	if err == nil {
+		t.TrustedTimestamp = token
+	}
+}
+
+// RFC 3161 errors
+var (
+	ErrTimestampRejected = errors.New("gotrails: timestamp request rejected")
+	ErrTimestampMismatch = errors.New("gotrails: timestamp token does not cover the hash")
+)
+
+var (
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	errMalformedTSTR = errors.New("gotrails: malformed timestamp response")
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type messageImprint struct {
+	HashAlgorithm algorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	Nonce          *big.Int `asn1:"optional"`
+	CertReq        bool     `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString asn1.RawValue  `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+// contentInfo and signedData decode just enough of the CMS token to reach
+// the TSTInfo; the authority's signature is not verified here
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue // [0] EXPLICIT wrapper around the SignedData
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	EncapContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     []byte `asn1:"explicit,tag:0"`
+	}
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+}
+
+// RFC3161Timestamper requests timestamps from an RFC 3161 time-stamp
+// authority over HTTP
+type RFC3161Timestamper struct {
+	url    string
+	client *http.Client
+}
+
+// NewRFC3161Timestamper creates a timestamper for the authority at url, nil
+// client uses a client with a 10 second timeout
+func NewRFC3161Timestamper(url string, client *http.Client) *RFC3161Timestamper {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RFC3161Timestamper{url: url, client: client}
+}
+
+// Timestamp requests a timestamp token for hash
+func (ts *RFC3161Timestamper) Timestamp(ctx context.Context, hash string) (*TimestampToken, error) {
+	digest, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("gotrails: invalid hash %q", hash)
+	}
+	alg, err := digestAlgorithm(digest)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: algorithmIdentifier{Algorithm: alg, Parameters: asn1.NullRawValue},
+			HashedMessage: digest,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.url, bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+	resp, err := ts.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: HTTP %d", ErrTimestampRejected, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	token, genTime, err := parseTimestampResponse(data, digest)
+	if err != nil {
+		return nil, err
+	}
+	return &TimestampToken{Authority: ts.url, Time: genTime, Token: token}, nil
+}
+
+// parseTimestampResponse returns the token and time of a granted response
+// whose message imprint is digest
+func parseTimestampResponse(data, digest []byte) ([]byte, time.Time, error) {
+	var resp timeStampResp
+	if _, err := asn1.Unmarshal(data, &resp); err != nil {
+		return nil, time.Time{}, errMalformedTSTR
+	}
+	// 0 granted, 1 granted with modifications
+	if resp.Status.Status > 1 {
+		return nil, time.Time{}, fmt.Errorf("%w: status %d", ErrTimestampRejected, resp.Status.Status)
+	}
+
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(resp.TimeStampToken.FullBytes, &ci); err != nil {
+		return nil, time.Time{}, errMalformedTSTR
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil || !sd.EncapContentInfo.ContentType.Equal(oidTSTInfo) {
+		return nil, time.Time{}, errMalformedTSTR
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.Content, &info); err != nil {
+		return nil, time.Time{}, errMalformedTSTR
+	}
+	alg, err := digestAlgorithm(digest)
+	if err != nil || !info.MessageImprint.HashAlgorithm.Algorithm.Equal(alg) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, time.Time{}, ErrTimestampMismatch
+	}
+	return resp.TimeStampToken.FullBytes, info.GenTime, nil
+}
+
+// digestAlgorithm returns the OID of the SHA-2 algorithm of a digest by its
+// length; RFC 3161 authorities do not accept other algorithms
+func digestAlgorithm(digest []byte) (asn1.ObjectIdentifier, error) {
+	switch len(digest) {
+	case sha256.Size:
+		return oidSHA256, nil
+	case sha512.Size:
+		return oidSHA512, nil
+	}
+	return nil, fmt.Errorf("gotrails: no RFC 3161 algorithm for a %d byte hash", len(digest))
+}
@@ -23,12 +23,17 @@ func GenerateRequestID() string {
 
 // ExtractTraceID extracts trace ID from HTTP headers or generates a new one
 func ExtractTraceID(r *http.Request, cfg *Config) string {
+	return ExtractTraceIDFromHeader(r.Header, cfg)
+}
+
+// ExtractTraceIDFromHeader extracts trace ID from a header set or generates a new one
+func ExtractTraceIDFromHeader(h http.Header, cfg *Config) string {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
 
 	// Try to get from configured header
-	traceID := r.Header.Get(cfg.TraceIDHeader)
+	traceID := h.Get(cfg.TraceIDHeader)
 	if traceID != "" {
 		return traceID
 	}
@@ -45,7 +50,7 @@ func ExtractTraceID(r *http.Request, cfg *Config) string {
 		if strings.EqualFold(header, cfg.TraceIDHeader) {
 			continue // Already checked
 		}
-		if val := r.Header.Get(header); val != "" {
+		if val := h.Get(header); val != "" {
 			// For traceparent header (W3C format), extract the trace-id portion
 			if strings.EqualFold(header, "Traceparent") {
 				parts := strings.Split(val, "-")
@@ -63,12 +68,17 @@ func ExtractTraceID(r *http.Request, cfg *Config) string {
 
 // ExtractRequestID extracts request ID from HTTP headers or generates a new one
 func ExtractRequestID(r *http.Request, cfg *Config) string {
+	return ExtractRequestIDFromHeader(r.Header, cfg)
+}
+
+// ExtractRequestIDFromHeader extracts request ID from a header set or generates a new one
+func ExtractRequestIDFromHeader(h http.Header, cfg *Config) string {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
 
 	// Try to get from configured header
-	requestID := r.Header.Get(cfg.RequestIDHeader)
+	requestID := h.Get(cfg.RequestIDHeader)
 	if requestID != "" {
 		return requestID
 	}
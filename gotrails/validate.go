@@ -0,0 +1,139 @@
+package gotrails
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+// ValidationPolicy lists the audit completeness rules checked by Validate
+type ValidationPolicy struct {
+	RequireActor    bool             // an Actor with an ID must be set
+	RequireResource bool             // at least one Resource must be declared
+	MaxBodySize     int              // max encoded size of any body or payload, 0 means unlimited
+	BannedPatterns  []*regexp.Regexp // must not match any captured value, e.g. card numbers
+}
+
+// Validation rules reported in violations
+const (
+	RuleRequireActor    = "require_actor"
+	RuleRequireResource = "require_resource"
+	RuleMaxBodySize     = "max_body_size"
+	RuleBannedPattern   = "banned_pattern"
+)
+
+// Violation is a trail that breaks a validation rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Path    string `json:"path,omitempty"` // e.g. "response.body", "integrations[0].request"
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Rule + ": " + v.Message
+	}
+	return v.Rule + " at " + v.Path + ": " + v.Message
+}
+
+// validationComponent is a captured value checked by Validate
+type validationComponent struct {
+	path  string
+	value any
+	body  bool
+}
+
+// Validate checks the trail against policy and returns the violations found,
+// nil if the trail complies. Use it in tests to enforce audit completeness
+// before trails reach production sinks.
+func (t *Trail) Validate(policy ValidationPolicy) []Violation {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var violations []Violation
+	if policy.RequireActor && (t.Actor == nil || t.Actor.ID == "") {
+		violations = append(violations, Violation{Rule: RuleRequireActor, Path: "actor", Message: "no actor set"})
+	}
+	if policy.RequireResource && len(t.Resources) == 0 {
+		violations = append(violations, Violation{Rule: RuleRequireResource, Path: "resources", Message: "no resource declared"})
+	}
+	if policy.MaxBodySize <= 0 && len(policy.BannedPatterns) == 0 {
+		return violations
+	}
+
+	for _, c := range t.validationComponents() {
+		data, err := json.Marshal(c.value)
+		if err != nil {
+			continue
+		}
+		if c.body && policy.MaxBodySize > 0 && len(data) > policy.MaxBodySize {
+			violations = append(violations, Violation{
+				Rule:    RuleMaxBodySize,
+				Path:    c.path,
+				Message: fmt.Sprintf("size %d exceeds %d", len(data), policy.MaxBodySize),
+			})
+		}
+		for _, pattern := range policy.BannedPatterns {
+			if pattern.Match(data) {
+				violations = append(violations, Violation{
+					Rule:    RuleBannedPattern,
+					Path:    c.path,
+					Message: "matches " + pattern.String(),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// validationComponents returns the captured values of the trail
+func (t *Trail) validationComponents() []validationComponent {
+	var components []validationComponent
+	add := func(path string, value any, body bool) {
+		if value != nil {
+			components = append(components, validationComponent{path, value, body})
+		}
+	}
+
+	if t.Request != nil {
+		add("request.query", nilIfEmpty(t.Request.Query), false)
+		add("request.headers", t.Request.Headers, false)
+		add("request.body", t.Request.Body, true)
+	}
+	if t.Response != nil {
+		add("response.headers", t.Response.Headers, false)
+		add("response.body", t.Response.Body, true)
+	}
+	if t.RPC != nil {
+		add("rpc.request", t.RPC.Request, true)
+		add("rpc.response", t.RPC.Response, true)
+	}
+	if t.Message != nil {
+		add("message.body", t.Message.Body, true)
+	}
+	for i, integration := range t.Integrations {
+		add(fmt.Sprintf("integrations[%d].request", i), integration.Request, true)
+		add(fmt.Sprintf("integrations[%d].response", i), integration.Response, true)
+	}
+	for i, step := range t.InternalSteps {
+		add(fmt.Sprintf("internal_steps[%d].request", i), step.Request, true)
+		add(fmt.Sprintf("internal_steps[%d].response", i), step.Response, true)
+	}
+	for i, e := range t.Errors {
+		add(fmt.Sprintf("errors[%d].message", i), e.Message, false)
+	}
+	for i, r := range t.Resources {
+		add(fmt.Sprintf("resources[%d]", i), r, false)
+	}
+	if len(t.Metadata) > 0 {
+		add("metadata", t.Metadata, false)
+	}
+	return components
+}
+
+func nilIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package gotrails
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrChainBroken is returned by VerifyChain when a trail fails verification
+var ErrChainBroken = errors.New("gotrails: hash chain broken")
+
+// Chain break reasons
+const (
+	ChainBreakHash     = "hash_mismatch"      // trail content does not match its hash
+	ChainBreakPrevHash = "prev_hash_mismatch" // trail does not link to the previous trail
+)
+
+// ChainBreak describes the first trail that failed verification
+type ChainBreak struct {
+	Index     int    `json:"index"` // position in the sequence, starting at 0
+	TraceID   string `json:"trace_id"`
+	RequestID string `json:"request_id"`
+	Reason    string `json:"reason"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+}
+
+// ChainReport is the result of VerifyChain
+type ChainReport struct {
+	Checked int         `json:"checked"` // trails verified before the walk stopped
+	Head    string      `json:"head"`    // hash of the last valid trail
+	Break   *ChainBreak `json:"break,omitempty"`
+}
+
+// Valid reports whether the chain verified without a break
+func (r ChainReport) Valid() bool {
+	return r.Break == nil
+}
+
+// VerifyChain walks an ordered sequence of trails, recomputes each hash and
+// checks that every trail links to the one before it. It stops at the first
+// break, which is described in the report and returned as ErrChainBroken.
+// The prev_hash of the first trail is not checked, so a chain can be verified
+// from any starting point.
+func VerifyChain(next func() (*Trail, bool)) (ChainReport, error) {
+	var report ChainReport
+	for i := 0; ; i++ {
+		trail, ok := next()
+		if !ok {
+			return report, nil
+		}
+		if trail == nil {
+			continue
+		}
+
+		if actual := trail.ComputeHash(); actual != trail.Hash {
+			return report, report.broken(i, trail, ChainBreakHash, trail.Hash, actual)
+		}
+		if report.Checked > 0 && trail.PrevHash != report.Head {
+			return report, report.broken(i, trail, ChainBreakPrevHash, report.Head, trail.PrevHash)
+		}
+		report.Checked++
+		report.Head = trail.chainHash()
+	}
+}
+
+func (r *ChainReport) broken(index int, trail *Trail, reason, expected, actual string) error {
+	r.Break = &ChainBreak{
+		Index:     index,
+		TraceID:   trail.TraceID,
+		RequestID: trail.RequestID,
+		Reason:    reason,
+		Expected:  expected,
+		Actual:    actual,
+	}
+	return fmt.Errorf("%w at index %d (trace %s): %s", ErrChainBroken, index, trail.TraceID, reason)
+}
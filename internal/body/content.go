@@ -0,0 +1,22 @@
+package body
+
+import (
+	"mime"
+	"strings"
+)
+
+// IsXML reports whether a Content-Type header denotes an XML body, e.g.
+// "text/xml" or "application/soap+xml"
+func IsXML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// IsForm reports whether a Content-Type header denotes a urlencoded form body
+func IsForm(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/x-www-form-urlencoded"
+}
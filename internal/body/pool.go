@@ -0,0 +1,28 @@
+package body
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize keeps unusually large buffers out of the pool
+const maxPooledBufferSize = 1 << 20 // 1MB
+
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// GetBuffer returns an empty capture buffer from the pool
+func GetBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// PutBuffer returns a capture buffer to the pool. The buffer must not be used
+// afterwards.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
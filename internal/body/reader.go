@@ -36,38 +36,43 @@ func NewReader(opts ...ReaderOption) *Reader {
 // ReadAndRestore reads the body up to maxSize and returns a new reader
 // that can be read again. Returns the read bytes and a new io.ReadCloser.
 func (r *Reader) ReadAndRestore(body io.ReadCloser) ([]byte, io.ReadCloser, error) {
+	data, newBody, _, err := r.ReadAndRestoreTruncated(body)
+	return data, newBody, err
+}
+
+// ReadAndRestoreTruncated is ReadAndRestore also reporting whether the body
+// was larger than maxSize, so the returned bytes are only its start
+func (r *Reader) ReadAndRestoreTruncated(body io.ReadCloser) ([]byte, io.ReadCloser, bool, error) {
 	if body == nil {
-		return nil, nil, nil
+		return nil, nil, false, nil
 	}
 
 	// Read up to maxSize + 1 to detect if body is larger
 	limitedReader := io.LimitReader(body, int64(r.maxSize+1))
 	data, err := io.ReadAll(limitedReader)
 	if err != nil {
-		return nil, body, err
+		return nil, body, false, err
 	}
 
 	// Check if body was truncated
 	truncated := len(data) > r.maxSize
-	if truncated {
-		data = data[:r.maxSize]
-	}
 
 	// Create a new reader that combines the read data with any remaining data
 	var newBody io.ReadCloser
 	if truncated {
-		// Body was larger than maxSize, combine read data with remaining
+		// Body was larger than maxSize, combine all read data with remaining
 		newBody = &multiReadCloser{
 			Reader: io.MultiReader(bytes.NewReader(data), body),
 			closer: body,
 		}
+		data = data[:r.maxSize]
 	} else {
 		// We read the entire body, close original and return buffered data
 		body.Close()
 		newBody = io.NopCloser(bytes.NewReader(data))
 	}
 
-	return data, newBody, nil
+	return data, newBody, truncated, nil
 }
 
 // ReadBytes reads the body up to maxSize and returns the bytes
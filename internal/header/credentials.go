@@ -0,0 +1,42 @@
+package header
+
+import (
+	"regexp"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+var (
+	// schemeCredential matches an auth scheme followed by its credential,
+	// e.g. "Bearer abc" or "Basic dXNlcjpwYXNz"
+	schemeCredential = regexp.MustCompile(`(?i)^(bearer|basic|token|digest|apikey|negotiate)\s+(\S.*)$`)
+
+	// jwtShape matches JSON web tokens anywhere in a value
+	jwtShape = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// credentialHeaders are name patterns of headers carrying credentials,
+// e.g. X-Internal-Token or X-Client-Secret
+var credentialHeaders = []string{"*token*", "*secret*", "*password*", "*api-key*", "*apikey*", "*credential*", "*session*"}
+
+// isCredentialHeader reports whether a lowercase header name looks like it
+// carries a credential
+func isCredentialHeader(name string) bool {
+	for _, p := range credentialHeaders {
+		if masker.MatchField(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskCredentials masks recognizable credentials in a header value, keeping
+// the auth scheme, e.g. "Bearer ***MASKED***"
+func (f *Filter) maskCredentials(header, value string) string {
+	if m := schemeCredential.FindStringSubmatch(value); m != nil {
+		return m[1] + " " + f.masked(header, []string{m[2]})
+	}
+	return jwtShape.ReplaceAllStringFunc(value, func(token string) string {
+		return f.masked(header, []string{token})
+	})
+}
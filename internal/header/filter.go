@@ -2,13 +2,19 @@ package header
 
 import (
 	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
 )
 
 // Filter provides header filtering functionality
 type Filter struct {
 	excludeHeaders map[string]bool
 	includeHeaders map[string]bool
+	dropHeaders    map[string]bool
 	maskValue      string
+	encrypter      masker.Encrypter
+	hashKey        []byte
+	credentials    bool
 }
 
 // FilterOption is an option for Filter
@@ -34,6 +40,16 @@ func WithIncludeHeaders(headers []string) FilterOption {
 	}
 }
 
+// WithDropHeaders sets headers to drop from the output entirely
+func WithDropHeaders(headers []string) FilterOption {
+	return func(f *Filter) {
+		f.dropHeaders = make(map[string]bool)
+		for _, h := range headers {
+			f.dropHeaders[strings.ToLower(h)] = true
+		}
+	}
+}
+
 // WithMaskValue sets the mask value for sensitive headers
 func WithMaskValue(value string) FilterOption {
 	return func(f *Filter) {
@@ -41,6 +57,29 @@ func WithMaskValue(value string) FilterOption {
 	}
 }
 
+// WithEncrypter encrypts masked header values instead of replacing them
+func WithEncrypter(e masker.Encrypter) FilterOption {
+	return func(f *Filter) {
+		f.encrypter = e
+	}
+}
+
+// WithHashKey replaces masked header values by their keyed hash instead of
+// the mask value
+func WithHashKey(key []byte) FilterOption {
+	return func(f *Filter) {
+		f.hashKey = key
+	}
+}
+
+// WithCredentialMasking masks headers whose names suggest credentials and
+// credential formats (Bearer/Basic values, JWTs) in any other header
+func WithCredentialMasking(enabled bool) FilterOption {
+	return func(f *Filter) {
+		f.credentials = enabled
+	}
+}
+
 // NewFilter creates a new header filter
 func NewFilter(opts ...FilterOption) *Filter {
 	f := &Filter{
@@ -61,6 +100,16 @@ func NewFilter(opts ...FilterOption) *Filter {
 	return f
 }
 
+// NewPolicyFilter creates a header filter capturing only include (all if
+// nil), leaving out drop and masking the values of mask
+func NewPolicyFilter(include, drop, mask []string, opts ...FilterOption) *Filter {
+	opts = append([]FilterOption{WithExcludeHeaders(mask), WithDropHeaders(drop)}, opts...)
+	if include != nil {
+		opts = append(opts, WithIncludeHeaders(include))
+	}
+	return NewFilter(opts...)
+}
+
 // Filter filters and masks headers based on configuration
 func (f *Filter) Filter(headers map[string][]string) map[string][]string {
 	if headers == nil {
@@ -79,16 +128,31 @@ func (f *Filter) Filter(headers map[string][]string) map[string][]string {
 			}
 		}
 
+		// Check if header should be dropped
+		if f.dropHeaders[lowerKey] {
+			continue
+		}
+
 		// Check if header should be excluded
 		if f.excludeHeaders[lowerKey] {
 			// Mask instead of excluding completely
-			result[key] = []string{f.maskValue}
+			result[key] = []string{f.masked(lowerKey, values)}
+			continue
+		}
+
+		if f.credentials && isCredentialHeader(lowerKey) {
+			result[key] = []string{f.masked(lowerKey, values)}
 			continue
 		}
 
 		// Copy the header values
 		result[key] = make([]string, len(values))
-		copy(result[key], values)
+		for i, v := range values {
+			if f.credentials {
+				v = f.maskCredentials(lowerKey, v)
+			}
+			result[key][i] = v
+		}
 	}
 
 	return result
@@ -116,3 +180,16 @@ func (f *Filter) AddExcludeHeader(header string) {
 func (f *Filter) RemoveExcludeHeader(header string) {
 	delete(f.excludeHeaders, strings.ToLower(header))
 }
+
+// masked returns the replacement of a masked header value
+func (f *Filter) masked(header string, values []string) string {
+	if f.encrypter != nil {
+		if enc, err := f.encrypter.EncryptField(header, values); err == nil {
+			return enc
+		}
+	}
+	if f.hashKey != nil {
+		return masker.HashValue(f.hashKey, strings.Join(values, ","))
+	}
+	return f.maskValue
+}
@@ -0,0 +1,31 @@
+package sqlquery
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Options are the settings of the SQL instrumentations
+type Options struct {
+	System        string // integration name prefix, e.g. "postgres"
+	ScrubLiterals bool
+}
+
+var (
+	stringLiteral  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteral = regexp.MustCompile(`(^|[^\w$:])\d+(?:\.\d+)?\b`)
+)
+
+// Scrub replaces string and numeric literals in a query with ?
+func Scrub(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "?")
+	return numericLiteral.ReplaceAllString(query, "${1}?")
+}
+
+// Operation returns the lowercased leading keyword of a query, or the action
+func Operation(query, action string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return action
+}
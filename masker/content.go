@@ -0,0 +1,78 @@
+package masker
+
+import "regexp"
+
+// Detector recognizes sensitive values by their content rather than their
+// field name
+type Detector struct {
+	Kind    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool // optional check of a match, e.g. a checksum
+}
+
+// Built-in detectors
+var (
+	DetectEmail      = Detector{Kind: "email", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)}
+	DetectCardNumber = Detector{Kind: "card_number", Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), Valid: LuhnValid}
+	DetectSSN        = Detector{Kind: "ssn", Pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`), Valid: ssnValid}
+	DetectPhone      = Detector{Kind: "phone", Pattern: regexp.MustCompile(`\+[1-9]\d{7,14}\b|\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`)}
+)
+
+// DefaultDetectors returns the built-in detectors, card numbers and SSNs
+// before phone numbers so their digits are not taken for one
+func DefaultDetectors() []Detector {
+	return []Detector{DetectEmail, DetectCardNumber, DetectSSN, DetectPhone}
+}
+
+// LuhnValid reports whether the digits of s pass the Luhn checksum
+func LuhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ssnValid rejects US social security numbers that are never issued
+func ssnValid(s string) bool {
+	area, group, serial := s[0:3], s[4:6], s[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// WithDetectors masks the matches of detectors inside string values whatever
+// their field is named, e.g. a card number in a "notes" field. Only the
+// matches are replaced.
+func WithDetectors(detectors []Detector) Option {
+	return func(m *Masker) {
+		m.detectors = detectors
+	}
+}
+
+// SetDetectors replaces the content detectors, nil disables content masking
+func (m *Masker) SetDetectors(detectors []Detector) {
+	m.detectors = detectors
+}
+
+// maskContent replaces the detected values in a string value of field
+func (m *Masker) maskContent(field, v string) string {
+	for _, d := range m.detectors {
+		v = d.Pattern.ReplaceAllStringFunc(v, func(match string) string {
+			if d.Valid != nil && !d.Valid(match) {
+				return match
+			}
+			return m.MaskedValue(field, match)
+		})
+	}
+	return v
+}
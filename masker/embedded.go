@@ -0,0 +1,67 @@
+package masker
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// urlInText finds absolute URLs inside free text
+var urlInText = regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^\s"'<>]+`)
+
+// WithEmbedded masks inside string values: embedded JSON documents, e.g.
+// double-encoded payloads, are parsed and masked like bodies, and URLs in the
+// text get their query secrets masked
+func WithEmbedded(enabled bool) Option {
+	return func(m *Masker) {
+		m.embedded = enabled
+	}
+}
+
+// SetEmbedded enables or disables masking inside string values
+func (m *Masker) SetEmbedded(enabled bool) {
+	m.embedded = enabled
+}
+
+// maskString masks a string value of field at path. Oversized values are
+// cut first, which also bounds the work of scanning them.
+func (m *Masker) maskString(field string, path []string, v string, w walk) string {
+	v = truncateString(v, m.maxString)
+	if m.embedded {
+		if out, ok := m.maskEmbeddedJSON(path, v, w); ok {
+			return out
+		}
+		v = urlInText.ReplaceAllStringFunc(v, func(match string) string {
+			masked, _ := m.maskNestedURL(match)
+			return masked
+		})
+	}
+	return m.maskContent(field, v)
+}
+
+// maskEmbeddedJSON masks v if it holds a JSON object or array. The original
+// string is kept when nothing in it was masked, so formatting survives.
+func (m *Masker) maskEmbeddedJSON(path []string, v string, w walk) (string, bool) {
+	trimmed := strings.TrimSpace(v)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return v, false
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(trimmed), &doc); err != nil {
+		return v, false
+	}
+
+	masked := m.maskInner("", doc, path, w)
+	if s, ok := masked.(string); ok {
+		return s, true // cut by the depth limit
+	}
+	if reflect.DeepEqual(doc, masked) {
+		return v, true
+	}
+	out, err := json.Marshal(masked)
+	if err != nil {
+		return v, false
+	}
+	return string(out), true
+}
@@ -0,0 +1,125 @@
+package masker
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// EncryptedPrefix marks a masked value that was encrypted instead of replaced
+const EncryptedPrefix = "enc:v1:"
+
+// Decryption errors
+var (
+	ErrNotEncrypted  = errors.New("masker: value is not encrypted")
+	ErrUnknownKey    = errors.New("masker: unknown encryption key")
+	ErrDecryptFailed = errors.New("masker: decryption failed")
+)
+
+// Encrypter turns the value of a masked field into ciphertext, so that
+// authorized investigators can recover it while other consumers of the trail
+// only see the ciphertext
+type Encrypter interface {
+	EncryptField(field string, value any) (string, error)
+}
+
+// AESEncrypter encrypts masked values with AES-256-GCM. Every value is
+// sealed with a fresh nonce into an envelope carrying the key ID:
+//
+//	enc:v1:<key id>:<base64url(nonce | ciphertext)>
+//
+// The JSON encoding of the value is encrypted, so numbers and objects come
+// back with their type. The field name is bound as additional data, so a
+// ciphertext cannot be moved to another field.
+type AESEncrypter struct {
+	keyID string
+	aead  cipher.AEAD
+}
+
+// NewAESEncrypter creates an AESEncrypter for a 16, 24 or 32 byte key
+func NewAESEncrypter(keyID string, key []byte) (*AESEncrypter, error) {
+	if strings.Contains(keyID, ":") {
+		return nil, errors.New("masker: key ID must not contain ':'")
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &AESEncrypter{keyID: keyID, aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptField encrypts value into an envelope
+func (e *AESEncrypter) EncryptField(field string, value any) (string, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := e.aead.Seal(nonce, nonce, plaintext, []byte(strings.ToLower(field)))
+	return EncryptedPrefix + e.keyID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// IsEncrypted reports whether s is an encrypted envelope
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, EncryptedPrefix)
+}
+
+// EnvelopeKeyID returns the key ID of an encrypted envelope
+func EnvelopeKeyID(s string) (string, bool) {
+	rest, ok := strings.CutPrefix(s, EncryptedPrefix)
+	if !ok {
+		return "", false
+	}
+	keyID, _, ok := strings.Cut(rest, ":")
+	return keyID, ok
+}
+
+// Decrypt recovers the value of field from an envelope, looking up the key
+// by the envelope's key ID
+func Decrypt(field, envelope string, keys func(keyID string) ([]byte, bool)) (any, error) {
+	rest, ok := strings.CutPrefix(envelope, EncryptedPrefix)
+	if !ok {
+		return nil, ErrNotEncrypted
+	}
+	keyID, data, ok := strings.Cut(rest, ":")
+	if !ok {
+		return nil, ErrNotEncrypted
+	}
+	key, ok := keys(keyID)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptFailed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(strings.ToLower(field)))
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	var v any
+	if err := json.Unmarshal(plaintext, &v); err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return v, nil
+}
@@ -0,0 +1,30 @@
+package masker
+
+// WithExemptions sets fields that are never masked by name, so broad patterns
+// like "*token*" or "pin*" keep useful fields such as "token_count" or
+// "pin_code_length". Exemptions may be patterns too. Path rules still apply.
+func WithExemptions(fields []string) Option {
+	return func(m *Masker) {
+		m.SetExemptions(fields)
+	}
+}
+
+// SetExemptions replaces the fields exempt from masking by name. It is safe
+// to call while the masker is in use.
+func (m *Masker) SetExemptions(fields []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exemptions, m.exemptGlobs = nil, nil
+	for _, f := range fields {
+		f = normalizeField(f)
+		if isGlob(f) {
+			m.exemptGlobs = append(m.exemptGlobs, compileGlob(f))
+			continue
+		}
+		if m.exemptions == nil {
+			m.exemptions = make(map[string]bool)
+		}
+		m.exemptions[f] = true
+	}
+	m.compileLocked()
+}
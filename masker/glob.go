@@ -0,0 +1,42 @@
+package masker
+
+import "strings"
+
+// glob is a compiled field pattern in which * matches any run of characters
+type glob struct {
+	parts []string // lowercased literals between the stars
+}
+
+func isGlob(pattern string) bool {
+	return strings.Contains(pattern, "*")
+}
+
+func compileGlob(pattern string) glob {
+	return glob{parts: strings.Split(normalizeField(pattern), "*")}
+}
+
+// match reports whether the lowercased field s matches the pattern
+func (g glob) match(s string) bool {
+	first, last := g.parts[0], g.parts[len(g.parts)-1]
+	if len(s) < len(first)+len(last) || !strings.HasPrefix(s, first) || !strings.HasSuffix(s, last) {
+		return false
+	}
+	s = s[len(first) : len(s)-len(last)]
+	for _, part := range g.parts[1 : len(g.parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return true
+}
+
+// MatchField reports whether field matches pattern, ignoring case. A * in
+// pattern matches any run of characters, e.g. "*password*" or "cc_*".
+func MatchField(pattern, field string) bool {
+	if !isGlob(pattern) {
+		return normalizeField(pattern) == normalizeField(field)
+	}
+	return compileGlob(pattern).match(normalizeField(field))
+}
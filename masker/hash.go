@@ -0,0 +1,57 @@
+package masker
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// HashedPrefix marks a value replaced by its keyed hash
+const HashedPrefix = "hmac:"
+
+// HashValue returns the keyed hash token of a value. The field is not part
+// of the hash, so a user ID maps to the same token wherever it appears.
+func HashValue(key []byte, value any) string {
+	var data []byte
+	switch v := value.(type) {
+	case map[string]any, []any:
+		data, _ = json.Marshal(v)
+	default:
+		data = []byte(StrategyValue(v))
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return HashedPrefix + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// IsHashed reports whether s is a hash token produced by HashValue
+func IsHashed(s string) bool {
+	return strings.HasPrefix(s, HashedPrefix)
+}
+
+// WithHashKey replaces masked values by their keyed hash instead of the mask
+// value, so equal values stay correlatable across trails without being
+// revealed
+func WithHashKey(key []byte) Option {
+	return func(m *Masker) {
+		m.hashKey = key
+	}
+}
+
+// SetHashKey sets the key of hash masking, nil replaces masked values with
+// the mask value
+func (m *Masker) SetHashKey(key []byte) {
+	m.hashKey = key
+}
+
+// HashedValue returns the keyed hash of a value of field, or its masked value
+// when the masker has no hash key, since an unkeyed hash of a guessable value
+// can be reversed
+func (m *Masker) HashedValue(field string, value any) string {
+	if m.hashKey == nil {
+		return m.MaskedValue(field, value)
+	}
+	return HashValue(m.hashKey, value)
+}
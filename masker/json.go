@@ -6,7 +6,7 @@ import (
 
 // MaskJSON masks sensitive fields in a JSON byte slice
 func (m *Masker) MaskJSON(data []byte) ([]byte, error) {
-	if !m.enabled || len(data) == 0 {
+	if !m.enabled.Load() || len(data) == 0 {
 		return data, nil
 	}
 
@@ -28,16 +28,26 @@ func (m *Masker) MaskJSONString(data string) (string, error) {
 	return string(result), nil
 }
 
-// maskAny recursively masks any value
-func (m *Masker) maskAny(v any) any {
-	switch val := v.(type) {
-	case map[string]any:
-		return m.MaskMap(val)
-	case []any:
-		return m.MaskSlice(val)
-	default:
+// MaskAny masks the masked fields nested in a decoded JSON value
+func (m *Masker) MaskAny(v any) any {
+	if !m.enabled.Load() {
+		return v
+	}
+	return m.maskAny(v)
+}
+
+// MaskField masks v as the value of field: entirely when the field is masked,
+// value by value when it is a parent, otherwise the masked fields nested in it
+func (m *Masker) MaskField(field string, v any) any {
+	if !m.enabled.Load() {
 		return v
 	}
+	return m.maskEntry(field, v, nil, m.newWalk())
+}
+
+// maskAny recursively masks any value
+func (m *Masker) maskAny(v any) any {
+	return m.maskInner("", v, nil, m.newWalk())
 }
 
 // ParseAndMaskJSON parses a JSON byte slice, masks it, and returns the result as any
@@ -51,7 +61,7 @@ func (m *Masker) ParseAndMaskJSON(data []byte) (any, error) {
 		return nil, err
 	}
 
-	if !m.enabled {
+	if !m.enabled.Load() {
 		return v, nil
 	}
 
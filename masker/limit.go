@@ -0,0 +1,112 @@
+package masker
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+)
+
+// Default masking limits
+const (
+	DefaultMaxDepth    = 32
+	DefaultMaxElements = 10000
+)
+
+// Truncation markers of values cut by the masking limits
+const (
+	// TruncatedKey holds the number of entries dropped from a map, or from
+	// a slice as the value of its final element
+	TruncatedKey = "_truncated"
+
+	// DepthTruncated replaces maps and slices nested below the depth limit
+	DepthTruncated = "***DEPTH LIMIT***"
+)
+
+// WithLimits bounds the work of masking deeply nested or very large values:
+// maps and slices below maxDepth are replaced by DepthTruncated, and entries
+// beyond the first maxElements keys and elements of a value are dropped. Cut
+// values are never kept unmasked. 0 disables a limit.
+func WithLimits(maxDepth, maxElements int) Option {
+	return func(m *Masker) {
+		m.SetLimits(maxDepth, maxElements)
+	}
+}
+
+// SetLimits sets the depth and element limits, 0 disables a limit
+func (m *Masker) SetLimits(maxDepth, maxElements int) {
+	m.maxDepth, m.maxElements = maxDepth, maxElements
+}
+
+// WithMaxStringLength cuts string values longer than n bytes, e.g. a base64
+// blob inside an otherwise small body, to their first n bytes followed by
+// "…[truncated N bytes]". 0 disables the limit.
+func WithMaxStringLength(n int) Option {
+	return func(m *Masker) {
+		m.maxString = n
+	}
+}
+
+// SetMaxStringLength sets the string length limit, 0 disables it
+func (m *Masker) SetMaxStringLength(n int) {
+	m.maxString = n
+}
+
+// truncateString cuts v to at most n bytes on a rune boundary and notes how
+// many bytes were dropped
+func truncateString(v string, n int) string {
+	if n <= 0 || len(v) <= n {
+		return v
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(v[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s…[truncated %d bytes]", v[:cut], len(v)-cut)
+}
+
+// walk tracks the limits during one masking call
+type walk struct {
+	rules  *matcher // one snapshot of the rules for the whole call
+	depth  int
+	budget *int // keys and elements left, nil when unlimited
+}
+
+// newWalk starts a masking call
+func (m *Masker) newWalk() walk {
+	w := walk{rules: m.rules()}
+	if m.maxElements > 0 {
+		budget := m.maxElements
+		w.budget = &budget
+	}
+	return w
+}
+
+// tooDeep reports whether a map or slice nested below w exceeds the depth limit
+func (m *Masker) tooDeep(w walk) bool {
+	return m.maxDepth > 0 && w.depth >= m.maxDepth
+}
+
+// take claims up to n entries from the budget and returns how many may be
+// processed
+func (w walk) take(n int) int {
+	if w.budget == nil {
+		return n
+	}
+	n = min(n, max(*w.budget, 0))
+	*w.budget -= n
+	return n
+}
+
+// limitKeys returns the keys of data to process, in sorted order when some
+// are dropped so the kept keys are deterministic
+func limitKeys(data map[string]any, n int) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	if n < len(keys) {
+		slices.Sort(keys)
+		keys = keys[:n]
+	}
+	return keys
+}
@@ -1,26 +1,48 @@
 package masker
 
 import (
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-// Masker provides field masking functionality
+// Masker provides field masking functionality. Fields, patterns, paths,
+// strategies, exemptions, parents and SetEnabled may change while the masker is in
+// use; other settings must be made before.
 type Masker struct {
 	fields    map[string]bool
+	globs     map[string]glob // patterns containing *, compiled once
 	maskValue string
-	enabled   bool
+	enabled   atomic.Bool
+	encrypter Encrypter
+	hashKey   []byte
+
+	paths         []maskPath
+	strategies    map[string]Strategy
+	strategyGlobs map[string]glob
+	detectors     []Detector
+	embedded      bool
+	exemptions    map[string]bool
+	exemptGlobs   []glob
+	parents       map[string]bool
+	parentGlobs   []glob
+	maxDepth      int
+	maxElements   int
+	maxString     int
+
+	mu      sync.Mutex              // serializes rule changes
+	matcher atomic.Pointer[matcher] // compiled fields, paths, strategies and exemptions, swapped on change
 }
 
 // Option is an option for Masker
 type Option func(*Masker)
 
-// WithFields sets the fields to mask
+// WithFields sets the fields to mask. A field containing * is a pattern
+// matching any run of characters in its place, e.g. "*password*" or "cc_*".
 func WithFields(fields []string) Option {
 	return func(m *Masker) {
-		m.fields = make(map[string]bool)
-		for _, f := range fields {
-			m.fields[strings.ToLower(f)] = true
-		}
+		m.ReplaceFields(fields)
 	}
 }
 
@@ -34,7 +56,15 @@ func WithMaskValue(value string) Option {
 // WithEnabled enables or disables masking
 func WithEnabled(enabled bool) Option {
 	return func(m *Masker) {
-		m.enabled = enabled
+		m.enabled.Store(enabled)
+	}
+}
+
+// WithEncrypter encrypts masked values instead of replacing them with the
+// mask value
+func WithEncrypter(e Encrypter) Option {
+	return func(m *Masker) {
+		m.encrypter = e
 	}
 }
 
@@ -53,29 +83,55 @@ func New(opts ...Option) *Masker {
 			"cvv":           true,
 			"pin":           true,
 		},
-		maskValue: "***MASKED***",
-		enabled:   true,
+		maskValue:   "***MASKED***",
+		maxDepth:    DefaultMaxDepth,
+		maxElements: DefaultMaxElements,
 	}
 
+	m.enabled.Store(true)
 	for _, opt := range opts {
 		opt(m)
 	}
+	m.compileLocked()
 
 	return m
 }
 
 // ShouldMask checks if a field should be masked
 func (m *Masker) ShouldMask(field string) bool {
-	if !m.enabled {
+	if !m.enabled.Load() {
 		return false
 	}
-	return m.fields[strings.ToLower(field)]
+	return m.rules().lookup(field).mask
+}
+
+// MaskedValue returns what replaces the value of a masked field: the result
+// of its Strategy, its ciphertext with an Encrypter, otherwise or when
+// encryption fails its keyed hash with a hash key, or else the mask value
+func (m *Masker) MaskedValue(field string, value any) string {
+	return m.maskedWith(field, m.strategy(field), value)
+}
+
+// maskedWith returns the masked value of a field with strategy s
+func (m *Masker) maskedWith(field string, s Strategy, value any) string {
+	if s != nil {
+		return s(StrategyValue(value))
+	}
+	if m.encrypter != nil {
+		if enc, err := m.encrypter.EncryptField(field, value); err == nil {
+			return enc
+		}
+	}
+	if m.hashKey != nil {
+		return HashValue(m.hashKey, value)
+	}
+	return m.maskValue
 }
 
 // Mask masks a value if the field should be masked
 func (m *Masker) Mask(field string, value any) any {
 	if m.ShouldMask(field) {
-		return m.maskValue
+		return m.MaskedValue(field, value)
 	}
 	return value
 }
@@ -83,61 +139,122 @@ func (m *Masker) Mask(field string, value any) any {
 // MaskString masks a string value if the field should be masked
 func (m *Masker) MaskString(field, value string) string {
 	if m.ShouldMask(field) {
-		return m.maskValue
+		return m.MaskedValue(field, value)
 	}
 	return value
 }
 
-// MaskMap masks values in a map based on field names
+// MaskMap masks values in a map based on field names and paths
 func (m *Masker) MaskMap(data map[string]any) map[string]any {
-	if !m.enabled || data == nil {
+	return m.maskMap(data, nil, m.newWalk())
+}
+
+// MaskSlice masks values in a slice
+func (m *Masker) MaskSlice(data []any) []any {
+	return m.maskSlice(data, nil, m.newWalk())
+}
+
+func (m *Masker) maskMap(data map[string]any, path []string, w walk) map[string]any {
+	if !m.enabled.Load() || data == nil {
 		return data
 	}
 
-	result := make(map[string]any, len(data))
-	for k, v := range data {
-		if m.ShouldMask(k) {
-			result[k] = m.maskValue
-		} else if nested, ok := v.(map[string]any); ok {
-			result[k] = m.MaskMap(nested)
-		} else if arr, ok := v.([]any); ok {
-			result[k] = m.MaskSlice(arr)
-		} else {
-			result[k] = v
+	w.depth++
+	n := w.take(len(data))
+	result := make(map[string]any, n+1)
+	if n == len(data) {
+		for k, v := range data {
+			result[k] = m.maskEntry(k, v, path, w)
 		}
+		return result
+	}
+	for _, k := range limitKeys(data, n) {
+		result[k] = m.maskEntry(k, data[k], path, w)
 	}
+	result[TruncatedKey] = len(data) - n
 	return result
 }
 
-// MaskSlice masks values in a slice
-func (m *Masker) MaskSlice(data []any) []any {
-	if !m.enabled || data == nil {
+// maskEntry masks the value of key k in a map at path
+func (m *Masker) maskEntry(k string, v any, path []string, w walk) any {
+	var child []string
+	if len(w.rules.paths) > 0 {
+		child = w.rules.childPath(path, normalizeField(k))
+	}
+	verdict := w.rules.lookup(k)
+	if verdict.parent {
+		return m.maskUnder(k, verdict.strategy, v, w)
+	}
+	if verdict.mask || (child != nil && w.rules.matchPath(child)) {
+		return m.maskedWith(k, verdict.strategy, v)
+	}
+	return m.maskInner(k, v, child, w)
+}
+
+func (m *Masker) maskSlice(data []any, path []string, w walk) []any {
+	if !m.enabled.Load() || data == nil {
 		return data
 	}
 
-	result := make([]any, len(data))
-	for i, v := range data {
-		if nested, ok := v.(map[string]any); ok {
-			result[i] = m.MaskMap(nested)
-		} else if arr, ok := v.([]any); ok {
-			result[i] = m.MaskSlice(arr)
+	w.depth++
+	child := w.rules.childPath(path, "[]")
+	n := w.take(len(data))
+	result := make([]any, n, n+1)
+	for i, v := range data[:n] {
+		if child != nil && w.rules.matchPath(child) {
+			result[i] = m.MaskedValue(lastKey(path), v)
 		} else {
-			result[i] = v
+			result[i] = m.maskInner(lastKey(path), v, child, w)
 		}
 	}
+	if dropped := len(data) - n; dropped > 0 {
+		result = append(result, map[string]any{TruncatedKey: dropped})
+	}
 	return result
 }
 
+// maskInner masks the contents of a value of field at path that is not
+// masked as a whole
+func (m *Masker) maskInner(field string, v any, path []string, w walk) any {
+	switch val := v.(type) {
+	case map[string]any:
+		if m.tooDeep(w) {
+			return DepthTruncated
+		}
+		return m.maskMap(val, path, w)
+	case []any:
+		if m.tooDeep(w) {
+			return DepthTruncated
+		}
+		return m.maskSlice(val, path, w)
+	case string:
+		return m.maskString(field, path, val, w)
+	default:
+		return v
+	}
+}
+
+// lastKey returns the last map key of path, the field name an element
+// belongs to
+func lastKey(path []string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] != "[]" {
+			return path[i]
+		}
+	}
+	return ""
+}
+
 // MaskHeaders masks sensitive headers
 func (m *Masker) MaskHeaders(headers map[string][]string) map[string][]string {
-	if !m.enabled || headers == nil {
+	if !m.enabled.Load() || headers == nil {
 		return headers
 	}
 
 	result := make(map[string][]string, len(headers))
 	for k, v := range headers {
 		if m.ShouldMask(k) {
-			result[k] = []string{m.maskValue}
+			result[k] = []string{m.MaskedValue(k, v)}
 		} else {
 			result[k] = v
 		}
@@ -145,19 +262,150 @@ func (m *Masker) MaskHeaders(headers map[string][]string) map[string][]string {
 	return result
 }
 
-// AddField adds a field to be masked
+// MaskQuery masks the values of sensitive parameters in a raw query string
+// (e.g. "api_key=abc&page=2"), keeping parameter order and encoding intact.
+// URLs passed as parameter values, such as callback URLs, are masked too.
+func (m *Masker) MaskQuery(rawQuery string) string {
+	if !m.enabled.Load() || rawQuery == "" {
+		return rawQuery
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			continue
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		if masked := m.maskParam(name, value); masked != value {
+			params[i] = key + "=" + url.QueryEscape(masked)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// MaskForm returns a copy of form values, e.g. a parsed
+// application/x-www-form-urlencoded body, masked by the same rules as query
+// parameters and body fields
+func (m *Masker) MaskForm(values url.Values) url.Values {
+	if !m.enabled.Load() || values == nil {
+		return values
+	}
+	result := make(url.Values, len(values))
+	for name, vs := range values {
+		masked := make([]string, len(vs))
+		for i, v := range vs {
+			masked[i] = m.maskParam(name, v)
+		}
+		result[name] = masked
+	}
+	return result
+}
+
+// maskParam masks the value of a query or form parameter. Parameters are
+// matched by name and by paths of a single key, and URLs in their values are
+// masked too.
+func (m *Masker) maskParam(name, value string) string {
+	rules := m.rules()
+	if m.ShouldMask(name) || rules.matchPath(rules.childPath(nil, normalizeField(name))) {
+		return m.MaskedValue(name, value)
+	}
+	if nested, ok := m.maskNestedURL(value); ok {
+		return nested
+	}
+	return m.maskString(name, nil, value, m.newWalk())
+}
+
+// MaskURL returns the URL as a string with sensitive query parameters, the
+// userinfo password and sensitive parameters of a query-style fragment
+// (e.g. "#access_token=...") masked
+func (m *Masker) MaskURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	if !m.enabled.Load() {
+		return u.String()
+	}
+	masked := *u
+	masked.RawQuery = m.MaskQuery(u.RawQuery)
+	if pw, ok := u.User.Password(); ok {
+		masked.User = url.UserPassword(u.User.Username(), m.MaskedValue("password", pw))
+	}
+	if strings.Contains(u.Fragment, "=") {
+		if f, err := url.PathUnescape(m.MaskQuery(u.EscapedFragment())); err == nil {
+			masked.Fragment, masked.RawFragment = f, ""
+		}
+	}
+	return masked.String()
+}
+
+// maskNestedURL masks v if it is an absolute URL, reporting whether anything
+// in it was masked
+func (m *Masker) maskNestedURL(v string) (string, bool) {
+	if !strings.Contains(v, "://") {
+		return v, false
+	}
+	u, err := url.Parse(v)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return v, false
+	}
+	masked := m.MaskURL(u)
+	return masked, masked != u.String()
+}
+
+// AddField adds a field or field pattern to be masked. It is safe to call
+// while the masker is in use.
 func (m *Masker) AddField(field string) {
-	m.fields[strings.ToLower(field)] = true
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addFieldLocked(field)
+	m.compileLocked()
 }
 
-// RemoveField removes a field from masking
+// RemoveField removes a field or field pattern from masking. It is safe to
+// call while the masker is in use.
 func (m *Masker) RemoveField(field string) {
-	delete(m.fields, strings.ToLower(field))
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	field = normalizeField(field)
+	delete(m.fields, field)
+	delete(m.globs, field)
+	m.compileLocked()
+}
+
+// addFieldLocked adds a field or field pattern, assuming the lock is held
+func (m *Masker) addFieldLocked(field string) {
+	field = normalizeField(field)
+	if !isGlob(field) {
+		m.fields[field] = true
+		return
+	}
+	if m.globs == nil {
+		m.globs = make(map[string]glob)
+	}
+	m.globs[field] = compileGlob(field)
 }
 
 // SetEnabled enables or disables masking
 func (m *Masker) SetEnabled(enabled bool) {
-	m.enabled = enabled
+	m.enabled.Store(enabled)
+}
+
+// Enabled reports whether masking is enabled
+func (m *Masker) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// SetEncrypter sets the encrypter for masked values, nil replaces them with
+// the mask value
+func (m *Masker) SetEncrypter(e Encrypter) {
+	m.encrypter = e
 }
 
 // GetMaskValue returns the mask value
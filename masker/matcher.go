@@ -0,0 +1,124 @@
+package masker
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedFields bounds the verdicts cached for field names, so adversarial
+// keys cannot grow the cache without limit
+const maxCachedFields = 4096
+
+// verdict is how a field name is masked
+type verdict struct {
+	mask     bool
+	parent   bool // mask element by element, see WithParents
+	strategy Strategy
+}
+
+// matcher decides how a field is masked in one lookup. It is compiled from
+// a snapshot of the fields, patterns, strategies, exemptions and parents
+// whenever they change: exact names resolve through one map, and names
+// needing patterns are evaluated once and cached by their original spelling.
+type matcher struct {
+	exact    map[string]verdict // normalized names with a precomputed verdict
+	patterns bool               // whether any rule is a pattern
+
+	fields      map[string]bool
+	fieldGlobs  []glob
+	exempt      map[string]bool
+	exemptGlobs []glob
+	parents     map[string]bool
+	parentGlobs []glob
+	strategies  map[string]Strategy
+	strategyPat []string // strategy patterns in sorted order
+	strategyGlb []glob   // compiled strategyPat
+	paths       []maskPath
+
+	cache  sync.Map // field name as seen → verdict
+	cached atomic.Int32
+}
+
+// rules returns the current matcher
+func (m *Masker) rules() *matcher {
+	return m.matcher.Load()
+}
+
+// compileLocked rebuilds the matcher after the masking rules changed and
+// publishes it to readers, assuming the lock is held
+func (m *Masker) compileLocked() {
+	mt := &matcher{
+		paths:       m.paths,
+		fields:      maps.Clone(m.fields),
+		fieldGlobs:  slices.Collect(maps.Values(m.globs)),
+		exempt:      maps.Clone(m.exemptions),
+		exemptGlobs: slices.Clone(m.exemptGlobs),
+		parents:     maps.Clone(m.parents),
+		parentGlobs: slices.Clone(m.parentGlobs),
+		strategies:  maps.Clone(m.strategies),
+		strategyPat: slices.Sorted(maps.Keys(m.strategyGlobs)), // deterministic among overlapping patterns
+	}
+	for _, p := range mt.strategyPat {
+		mt.strategyGlb = append(mt.strategyGlb, m.strategyGlobs[p])
+	}
+	mt.patterns = len(mt.fieldGlobs) > 0 || len(mt.exemptGlobs) > 0 || len(mt.parentGlobs) > 0 || len(mt.strategyGlb) > 0
+
+	mt.exact = make(map[string]verdict, len(mt.fields)+len(mt.exempt)+len(mt.parents)+len(mt.strategies))
+	for _, names := range []func(func(string) bool){maps.Keys(mt.fields), maps.Keys(mt.exempt), maps.Keys(mt.parents), maps.Keys(mt.strategies)} {
+		for name := range names {
+			if !isGlob(name) {
+				mt.exact[name] = mt.eval(name)
+			}
+		}
+	}
+	m.matcher.Store(mt)
+}
+
+// lookup returns the verdict of a field name
+func (mt *matcher) lookup(field string) verdict {
+	if v, ok := mt.cache.Load(field); ok {
+		return v.(verdict)
+	}
+	name := normalizeField(field)
+	v, ok := mt.exact[name]
+	if !ok && mt.patterns {
+		v = mt.eval(name)
+	}
+	if mt.cached.Load() < maxCachedFields {
+		mt.cached.Add(1)
+		mt.cache.Store(field, v)
+	}
+	return v
+}
+
+// eval computes the verdict of a normalized field name: exemptions win, then
+// the field's strategy, then the field names and patterns. A parent field is
+// masked too, element by element.
+func (mt *matcher) eval(field string) verdict {
+	if mt.exempt[field] {
+		return verdict{}
+	}
+	for _, g := range mt.exemptGlobs {
+		if g.match(field) {
+			return verdict{}
+		}
+	}
+
+	v := verdict{strategy: mt.strategies[field]}
+	for i := 0; v.strategy == nil && i < len(mt.strategyGlb); i++ {
+		if mt.strategyGlb[i].match(field) {
+			v.strategy = mt.strategies[mt.strategyPat[i]]
+		}
+	}
+	v.parent = mt.parents[field]
+	for i := 0; !v.parent && i < len(mt.parentGlobs); i++ {
+		v.parent = mt.parentGlobs[i].match(field)
+	}
+	v.mask = v.parent || v.strategy != nil || mt.fields[field]
+	for i := 0; !v.mask && i < len(mt.fieldGlobs); i++ {
+		v.mask = mt.fieldGlobs[i].match(field)
+	}
+	return v
+}
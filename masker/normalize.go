@@ -0,0 +1,36 @@
+package masker
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeField returns the form field names and rules are matched in:
+// NFKC-normalized, case-folded and without invisible format characters, so
+// fullwidth "ＰＡＳＳＷＯＲＤ" or "pass\u200bword" cannot slip past "password".
+// ASCII names are only lowercased.
+func normalizeField(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return strings.ToLower(s)
+	}
+
+	s = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1 // invisible format characters such as zero-width joiners
+		}
+		return r
+	}, norm.NFKC.String(s))
+	// casers keep state, so each call uses its own
+	return norm.NFKC.String(cases.Fold().String(s))
+}
@@ -0,0 +1,70 @@
+package masker
+
+// WithParents sets fields whose contents are masked element by element: every
+// primitive value anywhere under such a field is masked while arrays and
+// objects keep their shape, e.g. "card_numbers": ["***MASKED***", ...].
+// Parents may be patterns too, and exemptions still apply to them.
+func WithParents(fields []string) Option {
+	return func(m *Masker) {
+		m.SetParents(fields)
+	}
+}
+
+// SetParents replaces the fields whose contents are masked element by
+// element. It is safe to call while the masker is in use.
+func (m *Masker) SetParents(fields []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.parents, m.parentGlobs = nil, nil
+	for _, f := range fields {
+		f = normalizeField(f)
+		if isGlob(f) {
+			m.parentGlobs = append(m.parentGlobs, compileGlob(f))
+			continue
+		}
+		if m.parents == nil {
+			m.parents = make(map[string]bool)
+		}
+		m.parents[f] = true
+	}
+	m.compileLocked()
+}
+
+// maskUnder masks every primitive value in v, the value of parent field,
+// keeping nested maps and slices. Null stays null.
+func (m *Masker) maskUnder(field string, s Strategy, v any, w walk) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		if m.tooDeep(w) {
+			return DepthTruncated
+		}
+		w.depth++
+		n := w.take(len(val))
+		result := make(map[string]any, n+1)
+		for _, k := range limitKeys(val, n) {
+			result[k] = m.maskUnder(field, s, val[k], w)
+		}
+		if dropped := len(val) - n; dropped > 0 {
+			result[TruncatedKey] = dropped
+		}
+		return result
+	case []any:
+		if m.tooDeep(w) {
+			return DepthTruncated
+		}
+		w.depth++
+		n := w.take(len(val))
+		result := make([]any, n, n+1)
+		for i, e := range val[:n] {
+			result[i] = m.maskUnder(field, s, e, w)
+		}
+		if dropped := len(val) - n; dropped > 0 {
+			result = append(result, map[string]any{TruncatedKey: dropped})
+		}
+		return result
+	default:
+		return m.maskedWith(field, s, v)
+	}
+}
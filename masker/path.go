@@ -0,0 +1,80 @@
+package masker
+
+import "strings"
+
+// maskPath is a parsed path rule: lowercased keys, "[]" for any array
+// element and "*" for any key
+type maskPath []string
+
+// parsePath parses a dotted path such as "customer.contact.email",
+// "items[].sku_secret" or "$.data.*.token"
+func parsePath(p string) maskPath {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	var path maskPath
+	for _, seg := range strings.Split(p, ".") {
+		key, rest, _ := strings.Cut(seg, "[")
+		if key != "" {
+			path = append(path, normalizeField(key))
+		}
+		for rest != "" {
+			// any index, [] [*] or [2], matches every element
+			path = append(path, "[]")
+			_, rest, _ = strings.Cut(rest, "[")
+		}
+	}
+	return path
+}
+
+func (p maskPath) match(path []string) bool {
+	if len(p) != len(path) {
+		return false
+	}
+	for i, tok := range p {
+		if tok != path[i] && (tok != "*" || path[i] == "[]") {
+			return false
+		}
+	}
+	return true
+}
+
+// WithPaths masks the values at the given JSON paths, whatever the field is
+// named elsewhere. Keys are separated by dots, [] matches any array element
+// and * any key, e.g. "customer.contact.email" or "items[].sku_secret".
+func WithPaths(paths []string) Option {
+	return func(m *Masker) {
+		m.SetPaths(paths)
+	}
+}
+
+// SetPaths replaces the JSON paths masked regardless of field names. It is
+// safe to call while the masker is in use.
+func (m *Masker) SetPaths(paths []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.paths = nil
+	for _, p := range paths {
+		if parsed := parsePath(p); len(parsed) > 0 {
+			m.paths = append(m.paths, parsed)
+		}
+	}
+	m.compileLocked()
+}
+
+// childPath returns the path of a map key or array element below path, nil
+// when no path rules are configured
+func (mt *matcher) childPath(path []string, tok string) []string {
+	if len(mt.paths) == 0 {
+		return nil
+	}
+	return append(path[:len(path):len(path)], tok)
+}
+
+// matchPath reports whether a path rule targets path
+func (mt *matcher) matchPath(path []string) bool {
+	for _, p := range mt.paths {
+		if p.match(path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package masker
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Region-specific detectors, registered but not part of DefaultDetectors
+var (
+	// DetectNIK finds Indonesian national identity numbers (NIK): region,
+	// birth date (day plus 40 for women) and serial
+	DetectNIK = Detector{Kind: "nik", Pattern: regexp.MustCompile(`\b\d{16}\b`), Valid: nikValid}
+
+	// DetectNPWP finds Indonesian tax numbers in their formatted 15-digit
+	// form, e.g. 01.234.567.8-901.000
+	DetectNPWP = Detector{Kind: "npwp", Pattern: regexp.MustCompile(`\b\d{2}\.\d{3}\.\d{3}\.\d-\d{3}\.\d{3}\b`)}
+
+	// DetectIDBankAccount finds Indonesian bank account numbers in the
+	// formatted layouts of BRI (0123-01-012345-50-1) and Mandiri
+	// (123-00-1234567-8). Plain digit runs are too ambiguous to match.
+	DetectIDBankAccount = Detector{Kind: "id_bank_account", Pattern: regexp.MustCompile(`\b\d{4}-\d{2}-\d{6}-\d{2}-\d\b|\b\d{3}-\d{2}-\d{7}-\d\b`)}
+
+	// DetectIBAN finds international bank account numbers, compact or in
+	// groups of four, passing the mod-97 check
+	DetectIBAN = Detector{Kind: "iban", Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`), Valid: IBANValid}
+)
+
+var registry = struct {
+	sync.RWMutex
+	detectors []Detector
+}{detectors: []Detector{
+	DetectEmail, DetectCardNumber, DetectSSN, DetectIBAN, DetectNIK, DetectNPWP, DetectIDBankAccount, DetectPhone,
+}}
+
+// RegisterDetector adds d to the detector registry, replacing a registered
+// detector of the same kind. Detectors registered later are tried after the
+// built-ins.
+func RegisterDetector(d Detector) {
+	registry.Lock()
+	defer registry.Unlock()
+	for i, r := range registry.detectors {
+		if r.Kind == d.Kind {
+			registry.detectors[i] = d
+			return
+		}
+	}
+	registry.detectors = append(registry.detectors, d)
+}
+
+// Detectors returns the registered detectors of the given kinds in registry
+// order, or all registered detectors when no kind is given. Unknown kinds
+// are ignored.
+func Detectors(kinds ...string) []Detector {
+	registry.RLock()
+	defer registry.RUnlock()
+	var out []Detector
+	for _, d := range registry.detectors {
+		for _, k := range kinds {
+			if d.Kind == k {
+				out = append(out, d)
+				break
+			}
+		}
+	}
+	if len(kinds) == 0 {
+		out = append(out, registry.detectors...)
+	}
+	return out
+}
+
+// nikValid checks the province code, birth date and serial of a NIK
+func nikValid(s string) bool {
+	num := func(i int) int { return int(s[i]-'0')*10 + int(s[i+1]-'0') }
+	province, day, month := num(0), num(6), num(8)
+	if day > 40 {
+		day -= 40
+	}
+	return province >= 11 && province <= 94 && day >= 1 && day <= 31 && month >= 1 && month <= 12 && s[12:] != "0000"
+}
+
+// IBANValid reports whether s, ignoring spaces, passes the IBAN mod-97 check
+func IBANValid(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	rem := 0
+	for _, c := range s[4:] + s[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			rem = (rem*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			rem = (rem*100 + int(c-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
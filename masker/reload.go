@@ -0,0 +1,35 @@
+package masker
+
+import "context"
+
+// ReplaceFields replaces the masked fields and field patterns at once. It is
+// safe to call while the masker is in use: calls in flight finish with the
+// previous fields and later calls see the new ones.
+func (m *Masker) ReplaceFields(fields []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fields = make(map[string]bool, len(fields))
+	m.globs = nil
+	for _, f := range fields {
+		m.addFieldLocked(f)
+	}
+	m.compileLocked()
+}
+
+// Subscribe replaces the masked fields with every list received from
+// updates, e.g. pushed by a config service, so newly discovered sensitive
+// fields are masked without a redeploy. It blocks until ctx is done or
+// updates is closed, so run it in its own goroutine.
+func (m *Masker) Subscribe(ctx context.Context, updates <-chan []string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fields, ok := <-updates:
+			if !ok {
+				return
+			}
+			m.ReplaceFields(fields)
+		}
+	}
+}
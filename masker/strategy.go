@@ -0,0 +1,141 @@
+package masker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Strategy computes what replaces the value of a masked field, e.g. to keep
+// the last four digits of a card number visible for correlation
+type Strategy func(value string) string
+
+// KeepLast shows the last n characters and masks the rest. Values of n
+// characters or fewer are masked whole.
+func KeepLast(n int) Strategy {
+	return func(value string) string {
+		r := []rune(value)
+		if len(r) <= n {
+			return strings.Repeat("*", len(r))
+		}
+		return strings.Repeat("*", len(r)-n) + string(r[len(r)-n:])
+	}
+}
+
+// KeepFirst shows the first n characters and masks the rest. Values of n
+// characters or fewer are masked whole.
+func KeepFirst(n int) Strategy {
+	return func(value string) string {
+		r := []rune(value)
+		if len(r) <= n {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[:n]) + strings.Repeat("*", len(r)-n)
+	}
+}
+
+// KeepEmailDomain masks the local part of an email address, e.g.
+// "alice@example.com" becomes "*****@example.com". Other values are masked
+// whole.
+func KeepEmailDomain() Strategy {
+	return func(value string) string {
+		i := strings.LastIndexByte(value, '@')
+		if i <= 0 {
+			return strings.Repeat("*", len([]rune(value)))
+		}
+		return strings.Repeat("*", len([]rune(value[:i]))) + value[i:]
+	}
+}
+
+// MaskDigits masks the digits between the first keepFirst and the last
+// keepLast digits, keeping separators, e.g. MaskDigits(6, 4) turns
+// "4111 1111 1111 1111" into "4111 11** **** 1111". Values with no more than
+// keepFirst+keepLast digits are masked whole.
+func MaskDigits(keepFirst, keepLast int) Strategy {
+	return func(value string) string {
+		digits := 0
+		for _, c := range value {
+			if unicode.IsDigit(c) {
+				digits++
+			}
+		}
+		if digits <= keepFirst+keepLast {
+			keepFirst, keepLast = 0, 0
+		}
+		var b strings.Builder
+		i := 0
+		for _, c := range value {
+			if unicode.IsDigit(c) {
+				if i >= keepFirst && i < digits-keepLast {
+					c = '*'
+				}
+				i++
+			}
+			b.WriteRune(c)
+		}
+		return b.String()
+	}
+}
+
+// WithStrategy masks the named field, or fields matching a * pattern, with s
+// instead of the mask value. The field does not need to be in WithFields.
+func WithStrategy(field string, s Strategy) Option {
+	return func(m *Masker) {
+		m.SetStrategy(field, s)
+	}
+}
+
+// WithStrategies sets the strategies of several fields, see WithStrategy
+func WithStrategies(strategies map[string]Strategy) Option {
+	return func(m *Masker) {
+		for field, s := range strategies {
+			m.SetStrategy(field, s)
+		}
+	}
+}
+
+// SetStrategy sets the strategy of a field or field pattern, nil removes it.
+// It is safe to call while the masker is in use.
+func (m *Masker) SetStrategy(field string, s Strategy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	field = normalizeField(field)
+	switch {
+	case s == nil:
+		delete(m.strategies, field)
+		delete(m.strategyGlobs, field)
+	case isGlob(field):
+		if m.strategyGlobs == nil {
+			m.strategyGlobs = make(map[string]glob)
+		}
+		m.strategyGlobs[field] = compileGlob(field)
+		fallthrough
+	default:
+		if m.strategies == nil {
+			m.strategies = make(map[string]Strategy)
+		}
+		m.strategies[field] = s
+	}
+	m.compileLocked()
+}
+
+// strategy returns the strategy of a field, nil if it has none
+func (m *Masker) strategy(field string) Strategy {
+	return m.rules().lookup(field).strategy
+}
+
+// StrategyValue returns the text a strategy is applied to: strings as they
+// are, numbers without exponent and other values formatted with %v
+func StrategyValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,239 @@
+package masker
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+// MaskJSONStream masks the JSON document read from src and writes it to dst
+// token by token, without decoding the whole document into maps and slices.
+// Only masked values are decoded, to compute their replacement. Numbers are
+// copied verbatim. When the element limit cuts a map, its first keys in
+// document order are kept. On error dst holds a partial document.
+func (m *Masker) MaskJSONStream(dst io.Writer, src io.Reader) error {
+	if !m.enabled.Load() {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	s := &jsonStream{m: m, dec: dec, out: bufio.NewWriter(dst)}
+	if err := s.value("", nil, m.newWalk()); err != nil {
+		return err
+	}
+	return s.out.Flush()
+}
+
+// jsonStream is one MaskJSONStream call
+type jsonStream struct {
+	m   *Masker
+	dec *json.Decoder
+	out *bufio.Writer
+}
+
+// value masks the next value, of field at path
+func (s *jsonStream) value(field string, path []string, w walk) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if s.m.tooDeep(w) {
+			if err := s.skip(1); err != nil {
+				return err
+			}
+			return s.write(DepthTruncated)
+		}
+		w.depth++
+		if t == '{' {
+			return s.object(path, w)
+		}
+		return s.array(path, w)
+	case string:
+		return s.writeString(s.m.maskString(field, path, t, w))
+	case json.Number:
+		_, err := s.out.WriteString(t.String())
+		return err
+	default:
+		return s.write(t)
+	}
+}
+
+// object masks the entries of an object whose opening brace was read, like
+// maskMap
+func (s *jsonStream) object(path []string, w walk) error {
+	s.out.WriteByte('{')
+	dropped, n := 0, 0
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		k, _ := tok.(string)
+		if w.take(1) == 0 {
+			dropped++
+			if err := s.skip(0); err != nil {
+				return err
+			}
+			continue
+		}
+		if n++; n > 1 {
+			s.out.WriteByte(',')
+		}
+		if err := s.writeString(k); err != nil {
+			return err
+		}
+		s.out.WriteByte(':')
+		if err := s.entry(k, path, w); err != nil {
+			return err
+		}
+	}
+	if _, err := s.dec.Token(); err != nil {
+		return err
+	}
+	if dropped > 0 {
+		if n > 0 {
+			s.out.WriteByte(',')
+		}
+		s.writeString(TruncatedKey)
+		s.out.WriteByte(':')
+		s.write(dropped)
+	}
+	return s.out.WriteByte('}')
+}
+
+// entry masks the value of key k in an object at path, like maskEntry
+func (s *jsonStream) entry(k string, path []string, w walk) error {
+	var child []string
+	if len(w.rules.paths) > 0 {
+		child = w.rules.childPath(path, normalizeField(k))
+	}
+	verdict := w.rules.lookup(k)
+	if !verdict.parent && !verdict.mask && (child == nil || !w.rules.matchPath(child)) {
+		return s.value(k, child, w)
+	}
+
+	var v any
+	if err := s.dec.Decode(&v); err != nil {
+		return err
+	}
+	if verdict.parent {
+		return s.write(s.m.maskUnder(k, verdict.strategy, v, w))
+	}
+	return s.write(s.m.maskedWith(k, verdict.strategy, v))
+}
+
+// array masks the elements of an array whose opening bracket was read, like
+// maskSlice
+func (s *jsonStream) array(path []string, w walk) error {
+	s.out.WriteByte('[')
+	child := w.rules.childPath(path, "[]")
+	masked := child != nil && w.rules.matchPath(child)
+	dropped, n := 0, 0
+	for s.dec.More() {
+		if w.take(1) == 0 {
+			dropped++
+			if err := s.skip(0); err != nil {
+				return err
+			}
+			continue
+		}
+		if n++; n > 1 {
+			s.out.WriteByte(',')
+		}
+		if !masked {
+			if err := s.value(lastKey(path), child, w); err != nil {
+				return err
+			}
+			continue
+		}
+		var v any
+		if err := s.dec.Decode(&v); err != nil {
+			return err
+		}
+		if err := s.write(s.m.MaskedValue(lastKey(path), v)); err != nil {
+			return err
+		}
+	}
+	if _, err := s.dec.Token(); err != nil {
+		return err
+	}
+	if dropped > 0 {
+		if n > 0 {
+			s.out.WriteByte(',')
+		}
+		s.write(map[string]any{TruncatedKey: dropped})
+	}
+	return s.out.WriteByte(']')
+}
+
+// skip discards tokens until depth open objects and arrays are closed; with
+// depth 0 it discards the next value
+func (s *jsonStream) skip(depth int) error {
+	for {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth <= 0 {
+			return nil
+		}
+	}
+}
+
+// write encodes v to the output
+func (s *jsonStream) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(data)
+	return err
+}
+
+// writeString encodes v as a JSON string, escaping like encoding/json
+func (s *jsonStream) writeString(v string) error {
+	const hex = "0123456789abcdef"
+	s.out.WriteByte('"')
+	start := 0
+	for i := 0; i < len(v); {
+		r, size := utf8.DecodeRuneInString(v[i:])
+		invalid := r == utf8.RuneError && size == 1
+		if r >= utf8.RuneSelf && !invalid && r != '\u2028' && r != '\u2029' ||
+			r < utf8.RuneSelf && r >= ' ' && r != '"' && r != '\\' && r != '<' && r != '>' && r != '&' {
+			i += size
+			continue
+		}
+		s.out.WriteString(v[start:i])
+		switch {
+		case r == '"' || r == '\\':
+			s.out.WriteByte('\\')
+			s.out.WriteByte(byte(r))
+		case r == '\n':
+			s.out.WriteString(`\n`)
+		case r == '\r':
+			s.out.WriteString(`\r`)
+		case r == '\t':
+			s.out.WriteString(`\t`)
+		default:
+			s.out.WriteString(`\u`)
+			for shift := 12; shift >= 0; shift -= 4 {
+				s.out.WriteByte(hex[r>>shift&0xf])
+			}
+		}
+		i += size
+		start = i
+	}
+	s.out.WriteString(v[start:])
+	return s.out.WriteByte('"')
+}
@@ -0,0 +1,132 @@
+package masker
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+var errUnclosedXML = errors.New("masker: mismatched XML element")
+
+// MaskXML masks an XML document, e.g. a SOAP envelope. Elements whose local
+// name matches the mask fields or paths have their whole content replaced,
+// and matching attributes their value. Paths address elements by local name,
+// e.g. "Envelope.Body.Login.Password".
+func (m *Masker) MaskXML(data []byte) ([]byte, error) {
+	if !m.enabled.Load() || len(data) == 0 {
+		return data, nil
+	}
+
+	rules := m.rules()
+	var out bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		paths [][]string // element paths of the open elements, nil without path rules
+		names []string   // local names of the open elements
+	)
+	parent := func() []string {
+		if len(paths) == 0 {
+			return nil
+		}
+		return paths[len(paths)-1]
+	}
+	for {
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			if len(names) > 0 {
+				return nil, errUnclosedXML
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			path := rules.childPath(parent(), normalizeField(tok.Name.Local))
+			writeXMLStart(&out, tok, func(attr xml.Attr) string {
+				if m.ShouldMask(attr.Name.Local) {
+					return m.MaskedValue(attr.Name.Local, attr.Value)
+				}
+				return m.maskString(attr.Name.Local, nil, attr.Value, m.newWalk())
+			})
+			if m.ShouldMask(tok.Name.Local) || (path != nil && rules.matchPath(path)) {
+				text, err := xmlInnerText(dec)
+				if err != nil {
+					return nil, err
+				}
+				xml.EscapeText(&out, []byte(m.MaskedValue(tok.Name.Local, text)))
+				out.WriteString("</" + xmlName(tok.Name) + ">")
+				continue
+			}
+			paths = append(paths, path)
+			names = append(names, tok.Name.Local)
+		case xml.EndElement:
+			if len(names) == 0 || names[len(names)-1] != tok.Name.Local {
+				return nil, errUnclosedXML
+			}
+			paths, names = paths[:len(paths)-1], names[:len(names)-1]
+			out.WriteString("</" + xmlName(tok.Name) + ">")
+		case xml.CharData:
+			text := string(tok)
+			if strings.TrimSpace(text) != "" && len(names) > 0 {
+				text = m.maskString(names[len(names)-1], parent(), text, m.newWalk())
+			}
+			xml.EscapeText(&out, []byte(text))
+		case xml.Comment:
+			out.WriteString("<!--" + string(tok) + "-->")
+		case xml.ProcInst:
+			out.WriteString("<?" + tok.Target)
+			if len(tok.Inst) > 0 {
+				out.WriteString(" " + string(tok.Inst))
+			}
+			out.WriteString("?>")
+		case xml.Directive:
+			out.WriteString("<!" + string(tok) + ">")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// xmlName returns the name as written, with its namespace prefix
+func xmlName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// writeXMLStart writes a start tag, replacing attribute values with value
+func writeXMLStart(out *bytes.Buffer, tok xml.StartElement, value func(xml.Attr) string) {
+	out.WriteString("<" + xmlName(tok.Name))
+	for _, attr := range tok.Attr {
+		out.WriteString(" " + xmlName(attr.Name) + `="`)
+		xml.EscapeText(out, []byte(value(attr)))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+// xmlInnerText consumes the tokens up to the end of the current element and
+// returns the text they contain
+func xmlInnerText(dec *xml.Decoder) (string, error) {
+	var text strings.Builder
+	for depth := 1; depth > 0; {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text.Write(tok)
+		}
+	}
+	return strings.TrimSpace(text.String()), nil
+}
@@ -4,23 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/aizacoders/gotrails/gotrails"
 	"github.com/aizacoders/gotrails/internal/body"
 	"github.com/aizacoders/gotrails/internal/header"
 	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
 	"github.com/aizacoders/gotrails/sink"
 	"github.com/gin-gonic/gin"
 )
 
 // GinMiddleware is the gotrails middleware for Gin
 type GinMiddleware struct {
-	cfg          *gotrails.Config
-	sink         sink.Sink
-	masker       *masker.Masker
-	headerFilter *header.Filter
-	bodyReader   *body.Reader
+	cfg                  *gotrails.Config
+	sink                 sink.Sink
+	masker               *masker.Masker
+	requestHeaderFilter  *header.Filter
+	responseHeaderFilter *header.Filter
+	bodyReader           *body.Reader
 }
 
 // GinOption is an option for GinMiddleware
@@ -40,38 +43,37 @@ func WithGinSink(s sink.Sink) GinOption {
 	}
 }
 
-// WithGinMasker sets the masker
+// WithGinMasker sets the masker, used as given instead of one built from the
+// masking settings of the config
 func WithGinMasker(msk *masker.Masker) GinOption {
 	return func(m *GinMiddleware) {
 		m.masker = msk
 	}
 }
 
-// NewGinMiddleware creates a new Gin middleware
+// NewGinMiddleware creates a new Gin middleware. It panics if the config is
+// invalid, see gotrails.Config.Validate.
 func NewGinMiddleware(opts ...GinOption) *GinMiddleware {
 	m := &GinMiddleware{
-		cfg:    gotrails.DefaultConfig(),
-		sink:   sink.NewStdoutSink(),
-		masker: masker.New(),
+		cfg:  gotrails.DefaultConfig(),
+		sink: sink.NewStdoutSink(),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
+	if err := m.cfg.Validate(); err != nil {
+		panic(err)
+	}
 
-	// Initialize header filter with config
-	m.headerFilter = header.NewFilter(
-		header.WithExcludeHeaders(m.cfg.ExcludeHeaders),
-		header.WithMaskValue(m.cfg.MaskValue),
-	)
-	if m.cfg.IncludeHeaders != nil {
-		m.headerFilter = header.NewFilter(
-			header.WithIncludeHeaders(m.cfg.IncludeHeaders),
-			header.WithExcludeHeaders(m.cfg.ExcludeHeaders),
-			header.WithMaskValue(m.cfg.MaskValue),
-		)
+	if m.masker == nil {
+		m.masker = masker.New(m.cfg.MaskerOptions()...)
 	}
 
+	// Initialize header filters with config
+	m.requestHeaderFilter = newHeaderFilter(m.cfg, gotrails.HeaderDirectionRequest)
+	m.responseHeaderFilter = newHeaderFilter(m.cfg, gotrails.HeaderDirectionResponse)
+
 	// Initialize body reader with config
 	m.bodyReader = body.NewReader(
 		body.WithMaxSize(m.cfg.MaxRequestBodySize),
@@ -87,36 +89,53 @@ func (m *GinMiddleware) Handler() gin.HandlerFunc {
 		traceID := gotrails.ExtractTraceID(c.Request, m.cfg)
 		requestID := gotrails.ExtractRequestID(c.Request, m.cfg)
 
-		// Create a new trail
-		trail := gotrails.NewTrail(traceID, requestID, m.cfg)
+		// Create a new trail, skipping requests filtered out by path, method or sampling
+		trail := gotrails.NewRequestTrail(c.Request, traceID, requestID, m.cfg)
+		if trail == nil {
+			c.Next()
+			return
+		}
+
+		metadataOnly := m.cfg.IsMetadataOnly()
+		msk := m.masker
+		if pm := m.cfg.RouteMasker(c.Request.URL.Path); pm != nil {
+			msk = pm
+		}
 
 		// Read and restore the request body
 		var reqBody any
-		if c.Request.Body != nil && c.Request.ContentLength > 0 {
+		var reqDigest *gotrails.BodyDigest
+		var digested io.ReadCloser
+		if !metadataOnly && c.Request.Body != nil && c.Request.ContentLength > 0 {
 			bodyBytes, newBody, err := m.bodyReader.ReadAndRestore(c.Request.Body)
 			if err == nil {
-				c.Request.Body = newBody
+				reqDigest, digested = digestBody(m.cfg, newBody)
+				c.Request.Body = digested
 				// Parse and mask the body
-				if m.cfg.EnableMasking {
-					reqBody, _ = m.masker.ParseAndMaskJSON(bodyBytes)
-				} else {
-					reqBody, _ = parseJSON(bodyBytes)
-				}
+				reqBody = captureBody(m.cfg, msk, c.Request.Header.Get("Content-Type"), bodyBytes)
 			}
 		}
 
 		// Set request info
-		trail.SetRequest(&gotrails.HTTPRequest{
-			Method:  c.Request.Method,
-			Path:    c.Request.URL.Path,
-			Query:   c.Request.URL.RawQuery,
-			Headers: m.headerFilter.Filter(c.Request.Header),
-			Body:    reqBody,
-		})
+		req := &gotrails.HTTPRequest{
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			BodySize: max(c.Request.ContentLength, 0),
+		}
+		if !metadataOnly {
+			req.Query = c.Request.URL.RawQuery
+			if m.cfg.EnableMasking {
+				req.Query = msk.MaskQuery(req.Query)
+			}
+			req.Headers = m.requestHeaderFilter.Filter(c.Request.Header)
+			req.Body = reqBody
+		}
+		trail.SetRequest(req)
 
 		// Add trail to context
 		ctx := gotrails.WithTrail(c.Request.Context(), trail)
 		ctx = gotrails.WithConfig(ctx, m.cfg)
+		ctx = gotrails.WithFlush(ctx, m.sink.Write)
 		c.Request = c.Request.WithContext(ctx)
 
 		// Set trace headers in response
@@ -133,6 +152,8 @@ func (m *GinMiddleware) Handler() gin.HandlerFunc {
 
 		// Process request
 		c.Next()
+		trail.RecordContext(c.Request.Context())
+		recordRequestDigest(trail, m.cfg, reqDigest, digested)
 
 		// Capture response (tidak perlu custom response writer)
 		// var respBody any
@@ -143,13 +164,19 @@ func (m *GinMiddleware) Handler() gin.HandlerFunc {
 		// 		respBody, _ = parseJSON(rw.body.Bytes())
 		// 	}
 		// }
-		trail.SetResponse(&gotrails.HTTPResponse{
-			Status:  c.Writer.Status(),
-			Headers: m.headerFilter.Filter(c.Writer.Header()),
-		})
+		resp := &gotrails.HTTPResponse{
+			Status:   c.Writer.Status(),
+			BodySize: int64(max(c.Writer.Size(), 0)),
+		}
+		if !metadataOnly {
+			resp.Headers = m.responseHeaderFilter.Filter(c.Writer.Header())
+		}
+		trail.SetResponse(resp)
 
-		trail.Finalize()
-		_ = m.sink.Write(context.Background(), trail)
+		if record := trail.Finalize(); trail.Sampled() {
+			_ = m.sink.Write(context.Background(), record)
+		}
+		trail.Release()
 	}
 }
 
@@ -205,6 +232,73 @@ func parseJSON(data []byte) (any, error) {
 	return v, nil
 }
 
+// captureBody parses and masks a captured body, XML as a masked string, forms
+// as a map of fields and anything else as JSON, or defers that until the trail is serialized when
+// lazy body parsing is enabled. Lazy bodies keep a copy of data, so pooled
+// capture buffers can be reused.
+func captureBody(cfg *gotrails.Config, msk *masker.Masker, contentType string, data []byte) any {
+	parse := func(data []byte) any {
+		var v any
+		if body.IsXML(contentType) {
+			v = captureXML(cfg, msk, data)
+		} else if body.IsForm(contentType) {
+			formMasker := msk
+			if !cfg.EnableMasking {
+				formMasker = nil
+			}
+			v = payload.Form(data, formMasker)
+		} else if cfg.EnableMasking && cfg.StreamsMasking(len(data)) {
+			if raw, err := payload.StreamJSON(data, msk); err == nil {
+				v = raw
+			}
+		} else if cfg.EnableMasking {
+			v, _ = msk.ParseAndMaskJSON(data)
+		} else {
+			v, _ = parseJSON(data)
+		}
+		return v
+	}
+	if cfg.LazyBodyParsing {
+		return gotrails.NewRawBody(bytes.Clone(data), parse)
+	}
+	return parse(data)
+}
+
+// captureXML returns an XML body as a masked string. Bodies that cannot be
+// masked, e.g. truncated documents, are dropped rather than kept unmasked.
+func captureXML(cfg *gotrails.Config, msk *masker.Masker, data []byte) any {
+	if !cfg.EnableMasking {
+		return string(data)
+	}
+	out, err := msk.MaskXML(data)
+	if err != nil {
+		return nil
+	}
+	return string(out)
+}
+
+// digestBody tees the restored request body through a digest of the
+// complete body when body digests are enabled
+func digestBody(cfg *gotrails.Config, rc io.ReadCloser) (*gotrails.BodyDigest, io.ReadCloser) {
+	d := cfg.NewBodyDigest()
+	if d == nil {
+		return nil, rc
+	}
+	return d, d.Reader(rc)
+}
+
+// recordRequestDigest reads the rest of a digested request body and records
+// its digest when the body exceeded the size limit
+func recordRequestDigest(trail *gotrails.Trail, cfg *gotrails.Config, d *gotrails.BodyDigest, rc io.ReadCloser) {
+	if d == nil {
+		return
+	}
+	_ = rc.Close()
+	if d.Size() > int64(cfg.MaxRequestBodySize) {
+		trail.SetRequestBodyDigest(d.Sum(), d.Size())
+	}
+}
+
 // GinMiddlewareFunc returns a simple middleware function for quick setup
 func GinMiddlewareFunc(cfg *gotrails.Config, s sink.Sink) gin.HandlerFunc {
 	m := NewGinMiddleware(
@@ -214,18 +308,15 @@ func GinMiddlewareFunc(cfg *gotrails.Config, s sink.Sink) gin.HandlerFunc {
 	return m.Handler()
 }
 
-// StandardHTTPMiddleware wraps net/http handler with gotrails
+// StandardHTTPMiddleware wraps net/http handler with gotrails. It panics if
+// the config is invalid, see gotrails.Config.Validate.
 func StandardHTTPMiddleware(cfg *gotrails.Config, s sink.Sink) func(http.Handler) http.Handler {
-	msk := masker.New(
-		masker.WithFields(cfg.MaskFields),
-		masker.WithMaskValue(cfg.MaskValue),
-		masker.WithEnabled(cfg.EnableMasking),
-	)
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	msk := masker.New(cfg.MaskerOptions()...)
 
-	hf := header.NewFilter(
-		header.WithExcludeHeaders(cfg.ExcludeHeaders),
-		header.WithMaskValue(cfg.MaskValue),
-	)
+	hf := newHeaderFilter(cfg, gotrails.HeaderDirectionRequest)
 
 	br := body.NewReader(
 		body.WithMaxSize(cfg.MaxRequestBodySize),
@@ -237,35 +328,52 @@ func StandardHTTPMiddleware(cfg *gotrails.Config, s sink.Sink) func(http.Handler
 			traceID := gotrails.ExtractTraceID(r, cfg)
 			requestID := gotrails.ExtractRequestID(r, cfg)
 
-			// Create new trail
-			trail := gotrails.NewTrail(traceID, requestID, cfg)
+			// Create new trail, skipping requests filtered out by path, method or sampling
+			trail := gotrails.NewRequestTrail(r, traceID, requestID, cfg)
+			if trail == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			metadataOnly := cfg.IsMetadataOnly()
+			routeMsk := msk
+			if pm := cfg.RouteMasker(r.URL.Path); pm != nil {
+				routeMsk = pm
+			}
 
 			// Read and restore request body
 			var reqBody any
-			if r.Body != nil && r.ContentLength > 0 {
+			var reqDigest *gotrails.BodyDigest
+			var digested io.ReadCloser
+			if !metadataOnly && r.Body != nil && r.ContentLength > 0 {
 				bodyBytes, newBody, err := br.ReadAndRestore(r.Body)
 				if err == nil {
-					r.Body = newBody
-					if cfg.EnableMasking {
-						reqBody, _ = msk.ParseAndMaskJSON(bodyBytes)
-					} else {
-						reqBody, _ = parseJSON(bodyBytes)
-					}
+					reqDigest, digested = digestBody(cfg, newBody)
+					r.Body = digested
+					reqBody = captureBody(cfg, routeMsk, r.Header.Get("Content-Type"), bodyBytes)
 				}
 			}
 
 			// Set request info
-			trail.SetRequest(&gotrails.HTTPRequest{
-				Method:  r.Method,
-				Path:    r.URL.Path,
-				Query:   r.URL.RawQuery,
-				Headers: hf.Filter(r.Header),
-				Body:    reqBody,
-			})
+			req := &gotrails.HTTPRequest{
+				Method:   r.Method,
+				Path:     r.URL.Path,
+				BodySize: max(r.ContentLength, 0),
+			}
+			if !metadataOnly {
+				req.Query = r.URL.RawQuery
+				if cfg.EnableMasking {
+					req.Query = routeMsk.MaskQuery(req.Query)
+				}
+				req.Headers = hf.Filter(r.Header)
+				req.Body = reqBody
+			}
+			trail.SetRequest(req)
 
 			// Add trail to context
 			ctx := gotrails.WithTrail(r.Context(), trail)
 			ctx = gotrails.WithConfig(ctx, cfg)
+			ctx = gotrails.WithFlush(ctx, s.Write)
 			r = r.WithContext(ctx)
 
 			// Set trace headers in response
@@ -273,35 +381,44 @@ func StandardHTTPMiddleware(cfg *gotrails.Config, s sink.Sink) func(http.Handler
 			w.Header().Set(cfg.RequestIDHeader, requestID)
 
 			// Create response writer wrapper
+			maxSize := cfg.MaxResponseBodySize
+			if metadataOnly {
+				maxSize = 0
+			}
 			rw := &responseWriter{
 				ResponseWriter: w,
-				body:           &bytes.Buffer{},
-				maxSize:        cfg.MaxResponseBodySize,
+				body:           body.GetBuffer(),
+				maxSize:        maxSize,
 				status:         http.StatusOK,
 			}
+			if !metadataOnly {
+				rw.digest = cfg.NewBodyDigest()
+			}
 
 			// Process request
 			next.ServeHTTP(rw, r)
+			trail.RecordContext(r.Context())
+			recordRequestDigest(trail, cfg, reqDigest, digested)
 
 			// Capture response
-			var respBody any
-			if rw.body.Len() > 0 {
-				if cfg.EnableMasking {
-					respBody, _ = msk.ParseAndMaskJSON(rw.body.Bytes())
-				} else {
-					respBody, _ = parseJSON(rw.body.Bytes())
-				}
+			resp := &gotrails.HTTPResponse{
+				Status:   rw.status,
+				BodySize: rw.size,
 			}
-
-			trail.SetResponse(&gotrails.HTTPResponse{
-				Status: rw.status,
-				Body:   respBody,
-			})
-
-			trail.Finalize()
+			if !metadataOnly && rw.body.Len() > 0 {
+				resp.Body = captureBody(cfg, routeMsk, rw.Header().Get("Content-Type"), rw.body.Bytes())
+			}
+			if rw.digest != nil && rw.size > int64(maxSize) {
+				resp.BodyDigest = rw.digest.Sum()
+			}
+			trail.SetResponse(resp)
+			body.PutBuffer(rw.body)
 
 			// Finalize and flush trail
-			_ = s.Write(context.Background(), trail)
+			if record := trail.Finalize(); trail.Sampled() {
+				_ = s.Write(context.Background(), record)
+			}
+			trail.Release()
 		})
 	}
 }
@@ -312,6 +429,8 @@ type responseWriter struct {
 	body    *bytes.Buffer
 	status  int
 	maxSize int
+	size    int64
+	digest  *gotrails.BodyDigest // digest of the complete body, nil unless enabled
 }
 
 func (w *responseWriter) Write(data []byte) (int, error) {
@@ -323,7 +442,12 @@ func (w *responseWriter) Write(data []byte) (int, error) {
 			w.body.Write(data[:remaining])
 		}
 	}
-	return w.ResponseWriter.Write(data)
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	if w.digest != nil {
+		w.digest.Write(data[:n])
+	}
+	return n, err
 }
 
 func (w *responseWriter) WriteHeader(code int) {
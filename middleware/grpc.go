@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/header"
+	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
+	"github.com/aizacoders/gotrails/sink"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCMiddleware is the gotrails server interceptor for gRPC
+type GRPCMiddleware struct {
+	cfg          *gotrails.Config
+	sink         sink.Sink
+	masker       *masker.Masker
+	headerFilter *header.Filter
+}
+
+// GRPCOption is an option for GRPCMiddleware
+type GRPCOption func(*GRPCMiddleware)
+
+// WithGRPCConfig sets the config
+func WithGRPCConfig(cfg *gotrails.Config) GRPCOption {
+	return func(m *GRPCMiddleware) {
+		m.cfg = cfg
+	}
+}
+
+// WithGRPCSink sets the sink
+func WithGRPCSink(s sink.Sink) GRPCOption {
+	return func(m *GRPCMiddleware) {
+		m.sink = s
+	}
+}
+
+// WithGRPCMasker sets the masker, used as given instead of one built from the
+// masking settings of the config
+func WithGRPCMasker(msk *masker.Masker) GRPCOption {
+	return func(m *GRPCMiddleware) {
+		m.masker = msk
+	}
+}
+
+// NewGRPCMiddleware creates a new gRPC server middleware. It panics if the
+// config is invalid, see gotrails.Config.Validate.
+func NewGRPCMiddleware(opts ...GRPCOption) *GRPCMiddleware {
+	m := &GRPCMiddleware{
+		cfg:  gotrails.DefaultConfig(),
+		sink: sink.NewStdoutSink(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+	if err := m.cfg.Validate(); err != nil {
+		panic(err)
+	}
+
+	if m.masker == nil {
+		m.masker = masker.New(m.cfg.MaskerOptions()...)
+	}
+
+	// Initialize header filter with config
+	m.headerFilter = newHeaderFilter(m.cfg, gotrails.HeaderDirectionRequest)
+
+	return m
+}
+
+// UnaryServerInterceptor returns a gRPC UnaryServerInterceptor that creates one trail per call
+func (m *GRPCMiddleware) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		headers := make(http.Header, len(md))
+		for k, vals := range md {
+			for _, v := range vals {
+				headers.Add(k, v)
+			}
+		}
+
+		// Extract trace and request IDs from incoming metadata
+		traceID := gotrails.ExtractTraceIDFromHeader(headers, m.cfg)
+		requestID := gotrails.ExtractRequestIDFromHeader(headers, m.cfg)
+
+		// Create new trail
+		trail := gotrails.NewTrail(traceID, requestID, m.cfg)
+		if trail == nil {
+			return handler(ctx, req)
+		}
+
+		metadataOnly := m.cfg.IsMetadataOnly()
+		msk := m.masker
+		if pm := m.cfg.RouteMasker(info.FullMethod); pm != nil {
+			msk = pm
+		}
+		if !m.cfg.EnableMasking {
+			msk = nil
+		}
+
+		rpc := &gotrails.RPC{Method: info.FullMethod}
+		if !metadataOnly {
+			rpc.Metadata = m.headerFilter.Filter(headers)
+			rpc.Request = payload.Proto(req, msk, m.cfg.MaxRequestBodySize)
+		}
+
+		// Add trail to context
+		ctx = gotrails.WithTrail(ctx, trail)
+		ctx = gotrails.WithConfig(ctx, m.cfg)
+		ctx = gotrails.WithFlush(ctx, m.sink.Write)
+
+		// Process call
+		resp, err := handler(ctx, req)
+		trail.RecordContext(ctx)
+
+		rpc.Code = status.Code(err).String()
+		if !metadataOnly && err == nil {
+			rpc.Response = payload.Proto(resp, msk, m.cfg.MaxResponseBodySize)
+		}
+		if err != nil {
+			trail.AddErrorWithCode("grpc", status.Convert(err).Message(), rpc.Code)
+		}
+		trail.SetRPC(rpc)
+
+		// Finalize and flush trail
+		if record := trail.Finalize(); trail.Sampled() {
+			_ = m.sink.Write(context.Background(), record)
+		}
+		trail.Release()
+
+		return resp, err
+	}
+}
+
+// GRPCUnaryServerInterceptor returns a simple unary server interceptor for quick setup
+func GRPCUnaryServerInterceptor(cfg *gotrails.Config, s sink.Sink) grpc.UnaryServerInterceptor {
+	m := NewGRPCMiddleware(
+		WithGRPCConfig(cfg),
+		WithGRPCSink(s),
+	)
+	return m.UnaryServerInterceptor()
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGRPCUnaryServerInterceptorCapturesPayload(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	sink := &captureSink{}
+	interceptor := GRPCUnaryServerInterceptor(cfg, sink)
+
+	req, _ := structpb.NewStruct(map[string]any{"user_id": "u-123", "password": "secret"})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-trace-id", "trace-grpc", "authorization", "Bearer abc"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+
+	_, err := interceptor(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+		if !gotrails.HasTrail(ctx) {
+			t.Error("expected trail in handler context")
+		}
+		return structpb.NewStruct(map[string]any{"email": "user@email.com", "token": "abc"})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trail := sink.last()
+	if trail == nil || trail.RPC == nil {
+		t.Fatal("expected rpc trail in sink")
+	}
+	if trail.TraceID != "trace-grpc" {
+		t.Fatalf("expected trace id from metadata, got %s", trail.TraceID)
+	}
+	if trail.RPC.Code != codes.OK.String() {
+		t.Fatalf("expected OK code, got %s", trail.RPC.Code)
+	}
+	if got := trail.RPC.Metadata["Authorization"][0]; got != cfg.MaskValue {
+		t.Fatalf("expected masked authorization metadata, got %s", got)
+	}
+	reqBody := trail.RPC.Request.(map[string]any)
+	if reqBody["password"] != cfg.MaskValue || reqBody["user_id"] != "u-123" {
+		t.Fatalf("unexpected request payload: %v", reqBody)
+	}
+	respBody := trail.RPC.Response.(map[string]any)
+	if respBody["token"] != cfg.MaskValue {
+		t.Fatalf("expected masked token, got %v", respBody["token"])
+	}
+}
+
+func TestGRPCUnaryServerInterceptorRecordsError(t *testing.T) {
+	sink := &captureSink{}
+	interceptor := GRPCUnaryServerInterceptor(gotrails.NewConfig(), sink)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	trail := sink.last()
+	if trail.RPC.Code != codes.NotFound.String() {
+		t.Fatalf("expected NotFound code, got %s", trail.RPC.Code)
+	}
+	if len(trail.Errors) != 1 || trail.Errors[0].Message != "user not found" {
+		t.Fatalf("unexpected errors: %+v", trail.Errors)
+	}
+}
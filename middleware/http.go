@@ -1,8 +1,8 @@
 package middleware
 
 import (
-	"bytes"
 	"context"
+	"io"
 	"net/http"
 
 	"github.com/aizacoders/gotrails/gotrails"
@@ -14,12 +14,13 @@ import (
 
 // HTTPMiddleware is the gotrails middleware for net/http (native Go mux)
 type HTTPMiddleware struct {
-	cfg          *gotrails.Config
-	sink         sink.Sink
-	masker       *masker.Masker
-	headerFilter *header.Filter
-	bodyReader   *body.Reader
-	afterFlush   func(context.Context, *gotrails.Trail)
+	cfg                  *gotrails.Config
+	sink                 sink.Sink
+	masker               *masker.Masker
+	requestHeaderFilter  *header.Filter
+	responseHeaderFilter *header.Filter
+	bodyReader           *body.Reader
+	afterFlush           func(context.Context, *gotrails.Trail)
 }
 
 // HTTPOption is an option for HTTPMiddleware
@@ -39,7 +40,8 @@ func WithHTTPSink(s sink.Sink) HTTPOption {
 	}
 }
 
-// WithHTTPMasker sets the masker
+// WithHTTPMasker sets the masker, used as given instead of one built from the
+// masking settings of the config
 func WithHTTPMasker(msk *masker.Masker) HTTPOption {
 	return func(m *HTTPMiddleware) {
 		m.masker = msk
@@ -53,31 +55,29 @@ func WithHTTPAfterFlush(fn func(context.Context, *gotrails.Trail)) HTTPOption {
 	}
 }
 
-// NewHTTPMiddleware creates a new net/http middleware
+// NewHTTPMiddleware creates a new net/http middleware. It panics if the
+// config is invalid, see gotrails.Config.Validate.
 func NewHTTPMiddleware(opts ...HTTPOption) *HTTPMiddleware {
 	m := &HTTPMiddleware{
-		cfg:    gotrails.DefaultConfig(),
-		sink:   sink.NewStdoutSink(),
-		masker: masker.New(),
+		cfg:  gotrails.DefaultConfig(),
+		sink: sink.NewStdoutSink(),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
+	if err := m.cfg.Validate(); err != nil {
+		panic(err)
+	}
 
-	// Initialize header filter with config
-	m.headerFilter = header.NewFilter(
-		header.WithExcludeHeaders(m.cfg.ExcludeHeaders),
-		header.WithMaskValue(m.cfg.MaskValue),
-	)
-	if m.cfg.IncludeHeaders != nil {
-		m.headerFilter = header.NewFilter(
-			header.WithIncludeHeaders(m.cfg.IncludeHeaders),
-			header.WithExcludeHeaders(m.cfg.ExcludeHeaders),
-			header.WithMaskValue(m.cfg.MaskValue),
-		)
+	if m.masker == nil {
+		m.masker = masker.New(m.cfg.MaskerOptions()...)
 	}
 
+	// Initialize header filters with config
+	m.requestHeaderFilter = newHeaderFilter(m.cfg, gotrails.HeaderDirectionRequest)
+	m.responseHeaderFilter = newHeaderFilter(m.cfg, gotrails.HeaderDirectionResponse)
+
 	// Initialize body reader with config
 	m.bodyReader = body.NewReader(
 		body.WithMaxSize(m.cfg.MaxRequestBodySize),
@@ -93,35 +93,52 @@ func (m *HTTPMiddleware) Handler(next http.Handler) http.Handler {
 		traceID := gotrails.ExtractTraceID(r, m.cfg)
 		requestID := gotrails.ExtractRequestID(r, m.cfg)
 
-		// Create new trail
-		trail := gotrails.NewTrail(traceID, requestID, m.cfg)
+		// Create new trail, skipping requests filtered out by path, method or sampling
+		trail := gotrails.NewRequestTrail(r, traceID, requestID, m.cfg)
+		if trail == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		metadataOnly := m.cfg.IsMetadataOnly()
+		msk := m.masker
+		if pm := m.cfg.RouteMasker(r.URL.Path); pm != nil {
+			msk = pm
+		}
 
 		// Read and restore request body
 		var reqBody any
-		if r.Body != nil && r.ContentLength > 0 {
+		var reqDigest *gotrails.BodyDigest
+		var digested io.ReadCloser
+		if !metadataOnly && r.Body != nil && r.ContentLength > 0 {
 			bodyBytes, newBody, err := m.bodyReader.ReadAndRestore(r.Body)
 			if err == nil {
-				r.Body = newBody
-				if m.cfg.EnableMasking {
-					reqBody, _ = m.masker.ParseAndMaskJSON(bodyBytes)
-				} else {
-					reqBody, _ = parseJSON(bodyBytes)
-				}
+				reqDigest, digested = digestBody(m.cfg, newBody)
+				r.Body = digested
+				reqBody = captureBody(m.cfg, msk, r.Header.Get("Content-Type"), bodyBytes)
 			}
 		}
 
 		// Set request info
-		trail.SetRequest(&gotrails.HTTPRequest{
-			Method:  r.Method,
-			Path:    r.URL.Path,
-			Query:   r.URL.RawQuery,
-			Headers: m.headerFilter.Filter(r.Header),
-			Body:    reqBody,
-		})
+		req := &gotrails.HTTPRequest{
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			BodySize: max(r.ContentLength, 0),
+		}
+		if !metadataOnly {
+			req.Query = r.URL.RawQuery
+			if m.cfg.EnableMasking {
+				req.Query = msk.MaskQuery(req.Query)
+			}
+			req.Headers = m.requestHeaderFilter.Filter(r.Header)
+			req.Body = reqBody
+		}
+		trail.SetRequest(req)
 
 		// Add trail to context
 		ctx := gotrails.WithTrail(r.Context(), trail)
 		ctx = gotrails.WithConfig(ctx, m.cfg)
+		ctx = gotrails.WithFlush(ctx, m.sink.Write)
 		r = r.WithContext(ctx)
 
 		// Set trace headers in response
@@ -129,38 +146,50 @@ func (m *HTTPMiddleware) Handler(next http.Handler) http.Handler {
 		w.Header().Set(m.cfg.RequestIDHeader, requestID)
 
 		// Create response writer wrapper
+		maxSize := m.cfg.MaxResponseBodySize
+		if metadataOnly {
+			maxSize = 0
+		}
 		rw := &responseWriter{
 			ResponseWriter: w,
-			body:           &bytes.Buffer{},
-			maxSize:        m.cfg.MaxResponseBodySize,
+			body:           body.GetBuffer(),
+			maxSize:        maxSize,
 			status:         http.StatusOK,
 		}
+		if !metadataOnly {
+			rw.digest = m.cfg.NewBodyDigest()
+		}
 
 		// Process request
 		next.ServeHTTP(rw, r)
+		trail.RecordContext(r.Context())
+		recordRequestDigest(trail, m.cfg, reqDigest, digested)
 
 		// Capture response
-		var respBody any
-		if rw.body.Len() > 0 {
-			if m.cfg.EnableMasking {
-				respBody, _ = m.masker.ParseAndMaskJSON(rw.body.Bytes())
-			} else {
-				respBody, _ = parseJSON(rw.body.Bytes())
+		resp := &gotrails.HTTPResponse{
+			Status:   rw.status,
+			BodySize: rw.size,
+		}
+		if !metadataOnly {
+			if rw.body.Len() > 0 {
+				resp.Body = captureBody(m.cfg, msk, rw.Header().Get("Content-Type"), rw.body.Bytes())
+			}
+			if rw.digest != nil && rw.size > int64(maxSize) {
+				resp.BodyDigest = rw.digest.Sum()
 			}
+			resp.Headers = m.responseHeaderFilter.Filter(rw.Header())
 		}
-
-		trail.SetResponse(&gotrails.HTTPResponse{
-			Status:  rw.status,
-			Headers: m.headerFilter.Filter(rw.Header()),
-			Body:    respBody,
-		})
+		trail.SetResponse(resp)
+		body.PutBuffer(rw.body)
 
 		// Finalize and flush trail
-		trail.Finalize()
-		_ = m.sink.Write(context.Background(), trail)
-		if m.afterFlush != nil {
-			m.afterFlush(r.Context(), trail)
+		if record := trail.Finalize(); trail.Sampled() {
+			_ = m.sink.Write(context.Background(), record)
+			if m.afterFlush != nil {
+				m.afterFlush(r.Context(), trail)
+			}
 		}
+		trail.Release()
 	})
 }
 
@@ -184,3 +213,14 @@ func HTTPMiddlewareFunc(cfg *gotrails.Config, s sink.Sink) func(http.Handler) ht
 	)
 	return m.Middleware()
 }
+
+// newHeaderFilter creates the header filter of the config for a direction
+func newHeaderFilter(cfg *gotrails.Config, dir gotrails.HeaderDirection) *header.Filter {
+	p := cfg.HeaderPolicy(dir)
+	return header.NewPolicyFilter(p.Include, p.Drop, p.Mask,
+		header.WithMaskValue(cfg.MaskValue),
+		header.WithEncrypter(cfg.Encrypter),
+		header.WithHashKey(cfg.MaskHashKey),
+		header.WithCredentialMasking(cfg.MaskHeaderCredentials),
+	)
+}
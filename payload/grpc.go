@@ -0,0 +1,33 @@
+package payload
+
+import (
+	"encoding/json"
+
+	"github.com/aizacoders/gotrails/masker"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Proto converts a gRPC message into a masked, size-limited JSON value.
+// Protobuf messages are marshaled with protojson; other values fall back to
+// encoding/json. Payloads larger than maxSize are replaced like in JSON.
+func Proto(msg any, msk *masker.Masker, maxSize int) any {
+	if msg == nil {
+		return nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if pm, ok := msg.(proto.Message); ok {
+		data, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(pm)
+	} else {
+		data, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return nil
+	}
+
+	return JSON(data, msk, maxSize)
+}
@@ -1 +1,38 @@
 package payload
+
+import (
+	"net/url"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// Form converts an application/x-www-form-urlencoded body into a masked map
+// of its fields. Fields sent once become strings, repeated fields lists of
+// strings. Bodies that do not parse are dropped. A nil masker disables
+// masking.
+func Form(data []byte, msk *masker.Masker) any {
+	if len(data) == 0 {
+		return nil
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil
+	}
+	if msk != nil {
+		values = msk.MaskForm(values)
+	}
+
+	result := make(map[string]any, len(values))
+	for name, vs := range values {
+		if len(vs) == 1 {
+			result[name] = vs[0]
+			continue
+		}
+		list := make([]any, len(vs))
+		for i, v := range vs {
+			list[i] = v
+		}
+		result[name] = list
+	}
+	return result
+}
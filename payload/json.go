@@ -0,0 +1,54 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// JSON converts a raw payload into a masked, size-limited JSON value. A
+// payload larger than maxSize is replaced by {"truncated": true, "size": n}
+// and one that is not valid JSON is dropped, since neither can be masked. A
+// nil masker disables masking.
+func JSON(data []byte, msk *masker.Masker, maxSize int) any {
+	if len(data) == 0 {
+		return nil
+	}
+
+	if maxSize > 0 && len(data) > maxSize {
+		return map[string]any{"truncated": true, "size": len(data)}
+	}
+
+	if msk != nil {
+		if v, err := msk.ParseAndMaskJSON(data); err == nil {
+			return v
+		}
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	return v
+}
+
+// Key converts a message key for a trail. Keys often carry identifiers, so a
+// masker replaces the key by its keyed hash, or by the mask value without a
+// hash key. A nil or disabled masker records the key as is.
+func Key(key []byte, msk *masker.Masker) string {
+	if len(key) == 0 || msk == nil || !msk.Enabled() {
+		return string(key)
+	}
+	return msk.HashedValue("key", string(key))
+}
+
+// StreamJSON masks a JSON payload token by token into its encoded form,
+// avoiding the decoded tree that masking a large payload otherwise builds
+func StreamJSON(data []byte, msk *masker.Masker) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+	if err := msk.MaskJSONStream(&buf, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
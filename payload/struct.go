@@ -0,0 +1,169 @@
+package payload
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// StructSerializer converts typed payloads into masked JSON values without a
+// marshal/unmarshal round trip. It implements gotrails.BodySerializer.
+type StructSerializer struct {
+	Masker *masker.Masker // nil disables masking
+}
+
+// NewStructSerializer returns a StructSerializer masking with msk
+func NewStructSerializer(msk *masker.Masker) *StructSerializer {
+	return &StructSerializer{Masker: msk}
+}
+
+// Serialize converts v into a JSON value
+func (s *StructSerializer) Serialize(v any) any {
+	return Struct(v, s.Masker)
+}
+
+// Struct converts a typed value into a JSON value (maps, slices and scalars)
+// by walking it with reflection. Field names follow the json struct tags.
+// Fields tagged `gotrails:"mask"` or named in the masker are masked, fields
+// tagged `gotrails:"hash"` are replaced by their keyed hash (see
+// masker.Masker.HashedValue) and fields tagged `gotrails:"-"` are dropped. Types with their own JSON or text
+// encoding fall back to encoding/json. A nil masker disables masking.
+func Struct(v any, msk *masker.Masker) any {
+	if v == nil {
+		return nil
+	}
+	return structValue(reflect.ValueOf(v), msk)
+}
+
+func structValue(rv reflect.Value, msk *masker.Masker) any {
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Type().Implements(jsonMarshalerType) || rv.Type().Implements(textMarshalerType) {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		data, err := json.Marshal(rv.Interface())
+		if err != nil {
+			return nil
+		}
+		return JSON(data, msk, 0)
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return structValue(rv.Elem(), msk)
+	case reflect.Struct:
+		out := make(map[string]any, rv.NumField())
+		structFields(rv, msk, out)
+		return out
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if msk != nil && msk.ShouldMask(key) {
+				out[key] = msk.MaskedValue(key, iter.Value().Interface())
+				continue
+			}
+			out[key] = structValue(iter.Value(), msk)
+		}
+		return out
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes() // encoded as base64, like encoding/json
+		}
+		fallthrough
+	case reflect.Array:
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = structValue(rv.Index(i), msk)
+		}
+		return out
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return nil
+	default:
+		return rv.Interface()
+	}
+}
+
+// structFields adds the encoded fields of a struct to out, flattening
+// untagged embedded structs like encoding/json
+func structFields(rv reflect.Value, msk *masker.Masker, out map[string]any) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("gotrails")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				ft, fv = ft.Elem(), fv.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				structFields(fv, msk, out)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+
+		if msk != nil && tag == "hash" {
+			out[name] = msk.HashedValue(name, structValue(fv, nil))
+			continue
+		}
+		if msk != nil && (tag == "mask" || msk.ShouldMask(name)) {
+			out[name] = msk.MaskedValue(name, structValue(fv, nil))
+			continue
+		}
+		out[name] = structValue(fv, msk)
+	}
+}
+
+// isEmptyValue reports whether v is empty in the sense of json omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	default:
+		return v.IsZero()
+	}
+}
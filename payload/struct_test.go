@@ -0,0 +1,103 @@
+package payload
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/masker"
+)
+
+type base struct {
+	ID string `json:"id"`
+}
+
+type order struct {
+	base
+	Customer  string            `json:"customer"`
+	CardToken string            `json:"card_token" gotrails:"mask"`
+	Password  string            `json:"password"`
+	Notes     string            `json:"notes,omitempty"`
+	Internal  string            `gotrails:"-"`
+	Items     []item            `json:"items"`
+	Labels    map[string]string `json:"labels"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type item struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+func TestStructMasksAndFollowsJSONTags(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	v := Struct(&order{
+		base:      base{ID: "ord-1"},
+		Customer:  "c-1",
+		CardToken: "tok_123",
+		Password:  "hunter2",
+		Internal:  "hidden",
+		Items:     []item{{SKU: "A", Qty: 2}},
+		Labels:    map[string]string{"secret": "s", "channel": "web"},
+		CreatedAt: created,
+	}, masker.New())
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		t.Fatalf("expected map, got %T", v)
+	}
+	if m["id"] != "ord-1" || m["customer"] != "c-1" {
+		t.Fatalf("unexpected fields: %v", m)
+	}
+	if m["card_token"] != "***MASKED***" || m["password"] != "***MASKED***" {
+		t.Fatalf("expected masked fields, got %v", m)
+	}
+	if _, ok := m["notes"]; ok {
+		t.Fatal("expected omitempty field to be dropped")
+	}
+	if _, ok := m["Internal"]; ok {
+		t.Fatal("expected gotrails:\"-\" field to be dropped")
+	}
+	if items := m["items"].([]any); items[0].(map[string]any)["qty"] != 2 {
+		t.Fatalf("unexpected items: %v", m["items"])
+	}
+	if labels := m["labels"].(map[string]any); labels["secret"] != "***MASKED***" || labels["channel"] != "web" {
+		t.Fatalf("unexpected labels: %v", labels)
+	}
+	if m["created_at"] != "2026-01-02T03:04:05Z" {
+		t.Fatalf("expected time encoded as JSON, got %v", m["created_at"])
+	}
+}
+
+func TestStructHashTag(t *testing.T) {
+	type customer struct {
+		UserID string `json:"user_id" gotrails:"hash"`
+		Name   string `json:"name"`
+	}
+	key := []byte("k")
+	m := Struct(customer{UserID: "u-1", Name: "Ann"}, masker.New(masker.WithHashKey(key))).(map[string]any)
+	if m["user_id"] != masker.HashValue(key, "u-1") || m["name"] != "Ann" {
+		t.Fatalf("expected hashed user_id, got %v", m)
+	}
+
+	m = Struct(customer{UserID: "u-1"}, masker.New()).(map[string]any)
+	if m["user_id"] != "***MASKED***" {
+		t.Fatalf("expected masking without a hash key, got %v", m)
+	}
+}
+
+func TestJSONNeverReturnsUnmaskedBytes(t *testing.T) {
+	msk := masker.New()
+	large := []byte(`{"password":"hunter2","note":"` + strings.Repeat("x", 100) + `"}`)
+
+	got, ok := JSON(large, msk, 64).(map[string]any)
+	if !ok || got["truncated"] != true || got["size"] != len(large) {
+		t.Fatalf("expected an oversized payload replaced by a marker, got %v", got)
+	}
+	if v := JSON([]byte(`{"password":"hunter2"`), msk, 0); v != nil {
+		t.Fatalf("expected invalid JSON dropped, got %v", v)
+	}
+	if v := JSON([]byte(`{"password":"hunter2"}`), msk, 64).(map[string]any); v["password"] == "hunter2" {
+		t.Fatal("expected a payload within the limit masked")
+	}
+}
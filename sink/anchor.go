@@ -0,0 +1,196 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// Anchorer publishes the Merkle root of a batch of trails, for example to a
+// ledger or an object-lock bucket
+type Anchorer interface {
+	Anchor(ctx context.Context, anchor *gotrails.Anchor) error
+}
+
+// AnchorerFunc adapts a function to an Anchorer
+type AnchorerFunc func(ctx context.Context, anchor *gotrails.Anchor) error
+
+// Anchor calls f
+func (f AnchorerFunc) Anchor(ctx context.Context, anchor *gotrails.Anchor) error {
+	return f(ctx, anchor)
+}
+
+// AnchorSink writes records to the wrapped sink and anchors their hashes in
+// batches: every interval, or sooner once the batch is full, it builds the
+// Merkle root over the collected hashes and hands it to the Anchorer.
+type AnchorSink struct {
+	sink      Sink
+	anchorer  Anchorer
+	interval  time.Duration
+	batchSize int
+	clock     gotrails.Clock
+	stamper   gotrails.Timestamper
+	onError   func(error)
+
+	mu      sync.Mutex
+	leaves  []string
+	started time.Time
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// AnchorOption is an option for AnchorSink
+type AnchorOption func(*AnchorSink)
+
+// WithAnchorInterval sets how often a batch is anchored, 1 minute by default
+func WithAnchorInterval(d time.Duration) AnchorOption {
+	return func(a *AnchorSink) {
+		if d > 0 {
+			a.interval = d
+		}
+	}
+}
+
+// WithAnchorBatchSize sets the number of hashes that triggers an early
+// anchor, 1000 by default
+func WithAnchorBatchSize(n int) AnchorOption {
+	return func(a *AnchorSink) {
+		if n > 0 {
+			a.batchSize = n
+		}
+	}
+}
+
+// WithAnchorClock sets the clock used for anchor timestamps
+func WithAnchorClock(c gotrails.Clock) AnchorOption {
+	return func(a *AnchorSink) {
+		a.clock = c
+	}
+}
+
+// WithAnchorTimestamper requests a trusted timestamp for every anchor root
+// before it is anchored
+func WithAnchorTimestamper(ts gotrails.Timestamper) AnchorOption {
+	return func(a *AnchorSink) {
+		a.stamper = ts
+	}
+}
+
+// WithAnchorErrorHandler sets the error handler for failed anchors and
+// timestamp requests
+func WithAnchorErrorHandler(fn func(error)) AnchorOption {
+	return func(a *AnchorSink) {
+		a.onError = fn
+	}
+}
+
+// NewAnchorSink creates a new AnchorSink
+func NewAnchorSink(s Sink, anchorer Anchorer, opts ...AnchorOption) *AnchorSink {
+	a := &AnchorSink{
+		sink:      s,
+		anchorer:  anchorer,
+		interval:  time.Minute,
+		batchSize: 1000,
+		clock:     gotrails.SystemClock,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	go a.run()
+	return a
+}
+
+// run anchors the pending batch every interval
+func (a *AnchorSink) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			a.Flush(context.Background())
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+// Write writes the record to the wrapped sink and adds its hash to the batch
+func (a *AnchorSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	hash := record.Sum()
+	err := a.sink.Write(ctx, record)
+	if hash == "" {
+		return err
+	}
+
+	a.mu.Lock()
+	if len(a.leaves) == 0 {
+		a.started = a.clock.Now()
+	}
+	a.leaves = append(a.leaves, hash)
+	full := len(a.leaves) >= a.batchSize
+	a.mu.Unlock()
+
+	if full {
+		a.Flush(ctx)
+	}
+	return err
+}
+
+// Flush anchors the pending batch, if any
+func (a *AnchorSink) Flush(ctx context.Context) {
+	a.mu.Lock()
+	if len(a.leaves) == 0 {
+		a.mu.Unlock()
+		return
+	}
+	anchor := &gotrails.Anchor{
+		Root:       gotrails.MerkleRoot(a.leaves),
+		Leaves:     a.leaves,
+		StartedAt:  a.started,
+		AnchoredAt: a.clock.Now(),
+	}
+	a.leaves = nil
+	a.mu.Unlock()
+
+	if a.stamper != nil {
+		token, err := a.stamper.Timestamp(ctx, anchor.Root)
+		if err != nil {
+			a.handleError(err)
+		}
+		anchor.TrustedTimestamp = token
+	}
+	if err := a.anchorer.Anchor(ctx, anchor); err != nil {
+		a.handleError(err)
+	}
+}
+
+func (a *AnchorSink) handleError(err error) {
+	if a.onError != nil {
+		a.onError(err)
+	}
+}
+
+// Close anchors the pending batch and closes the wrapped sink
+func (a *AnchorSink) Close() error {
+	a.once.Do(func() {
+		close(a.stop)
+		<-a.done
+		a.Flush(context.Background())
+	})
+	return a.sink.Close()
+}
+
+// Name returns the name of the anchor sink
+func (a *AnchorSink) Name() string {
+	return "anchor:" + a.sink.Name()
+}
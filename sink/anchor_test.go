@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestAnchorSinkAnchorsBatches(t *testing.T) {
+	var mu sync.Mutex
+	var anchors []*gotrails.Anchor
+	anchorer := AnchorerFunc(func(ctx context.Context, anchor *gotrails.Anchor) error {
+		mu.Lock()
+		defer mu.Unlock()
+		anchors = append(anchors, anchor)
+		return nil
+	})
+	s := NewAnchorSink(NewNoopSink(), anchorer, WithAnchorBatchSize(2), WithAnchorInterval(time.Hour))
+
+	cfg := gotrails.NewConfig(gotrails.WithLazyBodyParsing(true))
+	var hashes []string
+	for _, id := range []string{"trace-1", "trace-2", "trace-3"} {
+		record := gotrails.NewTrail(id, "req", cfg).Finalize()
+		if err := s.Write(context.Background(), record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hashes = append(hashes, record.Hash)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(anchors) != 2 || len(anchors[0].Leaves) != 2 || len(anchors[1].Leaves) != 1 {
+		t.Fatalf("expected a full batch and a batch flushed on close, got %+v", anchors)
+	}
+	proof, ok := anchors[0].Proof(hashes[1])
+	if !ok || !gotrails.VerifyMerkleProof(hashes[1], proof, anchors[0].Root) {
+		t.Fatalf("expected %s to be provable against %s", hashes[1], anchors[0].Root)
+	}
+}
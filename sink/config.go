@@ -0,0 +1,54 @@
+package sink
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// FromConfig builds the sink selected by cfg.Sink, e.g. from GOTRAILS_SINK
+// with gotrails.ConfigFromEnv or from gotrails.LoadConfig. Several
+// comma-separated sinks are combined into a MultiSink.
+//
+//	stdout          JSON lines on stdout, the default
+//	stdout:pretty   indented JSON on stdout
+//	noop            discards trails
+//	segment:<dir>   hash-chained segment files in dir, see NewSegmentSink
+func FromConfig(cfg *gotrails.Config) (Sink, error) {
+	specs := strings.Split(cfg.Sink, ",")
+	if len(specs) == 1 {
+		return fromSpec(specs[0])
+	}
+	multi := NewMultiSink()
+	for _, spec := range specs {
+		s, err := fromSpec(strings.TrimSpace(spec))
+		if err != nil {
+			multi.Close()
+			return nil, err
+		}
+		multi.AddSink(s)
+	}
+	return multi, nil
+}
+
+// fromSpec builds one sink of FromConfig
+func fromSpec(spec string) (Sink, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "stdout":
+		if arg != "" && arg != "pretty" {
+			return nil, fmt.Errorf("sink: unknown stdout option %q", arg)
+		}
+		return NewStdoutSink(WithPrettyPrint(arg == "pretty")), nil
+	case "noop":
+		return NewNoopSink(), nil
+	case "segment":
+		if arg == "" {
+			return nil, fmt.Errorf("sink: segment sink needs a directory, e.g. segment:/var/log/trails")
+		}
+		return NewSegmentSink(arg)
+	default:
+		return nil, fmt.Errorf("sink: unknown sink %q, want stdout, stdout:pretty, noop or segment:<dir>", spec)
+	}
+}
@@ -0,0 +1,28 @@
+package sink
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestFromConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "trails")
+	for spec, name := range map[string]string{"": "stdout", "stdout:pretty": "stdout", "noop": "noop", "segment:" + dir: "segment", "stdout, noop": "multi"} {
+		s, err := FromConfig(gotrails.NewConfig(gotrails.WithSink(spec)))
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", spec, err)
+		}
+		if s.Name() != name {
+			t.Fatalf("%q: expected %s sink, got %s", spec, name, s.Name())
+		}
+		s.Close()
+	}
+
+	for _, spec := range []string{"kafka", "segment", "stdout:loud", "stdout,kafka"} {
+		if _, err := FromConfig(gotrails.NewConfig(gotrails.WithSink(spec))); err == nil {
+			t.Fatalf("%q: expected an error", spec)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package sink
 import (
 	"context"
 	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
 )
 
 // KafkaProducer is an interface for producing messages to Kafka
@@ -19,22 +23,43 @@ type IntegrationKafkaProducer struct {
 func (p *IntegrationKafkaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
 	start := time.Now()
 	err := p.Base.Produce(ctx, topic, key, value)
-	latency := time.Since(start)
+	latencyMs := time.Since(start).Milliseconds()
 
-	integration := map[string]any{
-		"type":    "kafka",
-		"topic":   topic,
-		"latency": latency,
-		"error":   err,
+	// Attach integration to trail in context if present
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return err
 	}
 
-	// Attach integration to trail in context if present
-	trail := ctx.Value("gotrails_trail")
-	if trail != nil {
-		if t, ok := trail.(interface{ AddIntegration(any) }); ok {
-			t.AddIntegration(integration)
+	cfg := gotrails.GetConfig(ctx)
+	if cfg == nil {
+		cfg = gotrails.DefaultConfig()
+	}
+
+	request := map[string]any{
+		"action": "produce",
+		"topic":  topic,
+		"size":   len(value),
+	}
+	if !cfg.IsMetadataOnly() {
+		var msk *masker.Masker
+		if cfg.EnableMasking {
+			msk = cfg.Masker()
 		}
+		request["key"] = payload.Key(key, msk)
+		request["value"] = payload.JSON(value, msk, cfg.MaxRequestBodySize)
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeKafka,
+		Name:      topic,
+		LatencyMs: latencyMs,
+		Request:   request,
+	}
+	if err != nil {
+		integration.Error = err.Error()
 	}
+	trail.AddIntegration(integration)
 
 	return err
 }
@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/masker"
+)
+
+type kafkaProducerFunc func(ctx context.Context, topic string, key, value []byte) error
+
+func (f kafkaProducerFunc) Produce(ctx context.Context, topic string, key, value []byte) error {
+	return f(ctx, topic, key, value)
+}
+
+func TestIntegrationKafkaProducerRecordsIntegration(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+
+	producer := NewIntegrationKafkaProducer(kafkaProducerFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		return nil
+	}))
+
+	value := []byte(`{"payment_id":"pay-123","token":"abc"}`)
+	if err := producer.Produce(ctx, "payment.created", []byte("pay-123"), value); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeKafka || integration.Name != "payment.created" {
+		t.Fatalf("unexpected integration: %+v", integration)
+	}
+	req := integration.Request.(map[string]any)
+	if req["key"] != cfg.MaskValue || req["size"] != len(value) {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	if req["value"].(map[string]any)["token"] != cfg.MaskValue {
+		t.Fatalf("expected masked token, got %v", req["value"])
+	}
+}
+
+func TestIntegrationKafkaProducerHashesKey(t *testing.T) {
+	key := []byte("k")
+	cfg := gotrails.NewConfig(gotrails.WithHashMasking(key))
+	trail := gotrails.NewTrail("trace-2", "req-2", cfg)
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+
+	producer := NewIntegrationKafkaProducer(kafkaProducerFunc(func(ctx context.Context, topic string, key, value []byte) error {
+		return nil
+	}))
+	if err := producer.Produce(ctx, "payment.created", []byte("user-42"), []byte(`{}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := trail.Integrations[0].Request.(map[string]any)
+	if req["key"] != masker.HashValue(key, "user-42") {
+		t.Fatalf("expected hashed key, got %v", req["key"])
+	}
+}
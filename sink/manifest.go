@@ -0,0 +1,277 @@
+package sink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// ManifestFormat identifies the segment manifest format
+const ManifestFormat = "gotrails-manifest/v1"
+
+// ErrManifestInvalid is returned when a segment and its manifest disagree, or
+// a sealed segment has no valid manifest
+var ErrManifestInvalid = errors.New("sink: segment manifest invalid")
+
+// SegmentManifest is the signed summary SegmentSink writes next to every
+// sealed segment. As the segment digest is signed, a replaced or rewritten
+// segment file is detected even when its internal chain is consistent.
+type SegmentManifest struct {
+	Format       string    `json:"format"`
+	Segment      string    `json:"segment"` // file name of the segment
+	Index        int       `json:"index"`
+	Records      int       `json:"records"`
+	FirstTraceID string    `json:"first_trace_id,omitempty"`
+	LastTraceID  string    `json:"last_trace_id,omitempty"`
+	Digest       string    `json:"digest"` // SHA-256 of the sealed segment file
+	PrevChain    string    `json:"prev_chain"`
+	Chain        string    `json:"chain"`
+	SealedAt     time.Time `json:"sealed_at"`
+	KeyID        string    `json:"key_id"`
+	Signature    string    `json:"signature"` // HMAC-SHA256 of the manifest without signature
+}
+
+// WithSegmentManifest writes a manifest signed with key next to every sealed
+// segment, see SegmentManifest
+func WithSegmentManifest(keyID string, key []byte) SegmentOption {
+	return func(s *SegmentSink) {
+		s.manifestKeyID = keyID
+		s.manifestKey = key
+	}
+}
+
+func manifestPath(segment string) string {
+	return strings.TrimSuffix(segment, ".seg") + ".manifest.json"
+}
+
+// signature returns the signature of the manifest with key
+func (m SegmentManifest) signature(key []byte) (string, error) {
+	m.Signature = ""
+	data, err := gotrails.CanonicalJSON(m)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeManifestLocked writes the signed manifest of the segment just sealed
+func (s *SegmentSink) writeManifestLocked(path string) error {
+	digest, err := fileDigest(path)
+	if err != nil {
+		return err
+	}
+	m := SegmentManifest{
+		Format:       ManifestFormat,
+		Segment:      filepath.Base(path),
+		Index:        s.index,
+		Records:      s.records,
+		FirstTraceID: s.firstTraceID,
+		LastTraceID:  s.lastTraceID,
+		Digest:       digest,
+		PrevChain:    s.prevChain,
+		Chain:        s.chain,
+		SealedAt:     s.clock.Now().UTC(),
+		KeyID:        s.manifestKeyID,
+	}
+	if m.Signature, err = m.signature(s.manifestKey); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestPath(path), append(data, '\n'), 0o444); err != nil {
+		return err
+	}
+	return s.writeHeadLocked(m)
+}
+
+// HeadFormat identifies the manifest head format
+const HeadFormat = "gotrails-head/v1"
+
+// manifestHead is the signed pointer to the newest sealed segment, rewritten
+// on every seal. Without it, deleting the newest segments together with their
+// manifests would leave a valid but shorter chain.
+type manifestHead struct {
+	Format    string    `json:"format"`
+	Segment   string    `json:"segment"`
+	Index     int       `json:"index"`
+	Chain     string    `json:"chain"`
+	Manifest  string    `json:"manifest"` // SHA-256 of the manifest of the segment
+	UpdatedAt time.Time `json:"updated_at"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"` // HMAC-SHA256 of the head without signature
+}
+
+func headPath(dir string) string {
+	return filepath.Join(dir, "head.json")
+}
+
+// signature returns the signature of the head with key
+func (h manifestHead) signature(key []byte) (string, error) {
+	h.Signature = ""
+	data, err := gotrails.CanonicalJSON(h)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// writeHeadLocked points the head at the manifest just written, replacing
+// the previous head atomically
+func (s *SegmentSink) writeHeadLocked(m SegmentManifest) error {
+	digest, err := fileDigest(filepath.Join(s.dir, manifestPath(m.Segment)))
+	if err != nil {
+		return err
+	}
+	h := manifestHead{
+		Format:    HeadFormat,
+		Segment:   m.Segment,
+		Index:     m.Index,
+		Chain:     m.Chain,
+		Manifest:  digest,
+		UpdatedAt: m.SealedAt,
+		KeyID:     s.manifestKeyID,
+	}
+	if h.Signature, err = h.signature(s.manifestKey); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := headPath(s.dir) + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, headPath(s.dir))
+}
+
+// VerifyManifests verifies the segments of dir and checks every sealed
+// segment against its signed manifest, using keys to look up the signing
+// keys. A missing, unsigned or mismatching manifest, a manifest whose
+// segment is gone, or a head that does not point at the newest manifest, as
+// when the newest segments were deleted, is reported as ErrManifestInvalid.
+func VerifyManifests(dir string, keys func(keyID string) ([]byte, bool)) ([]SegmentManifest, error) {
+	infos, err := VerifySegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(name, format string, args ...any) error {
+		return fmt.Errorf("%w: %s: %s", ErrManifestInvalid, name, fmt.Sprintf(format, args...))
+	}
+
+	segments := make(map[string]bool, len(infos))
+	var manifests []SegmentManifest
+	for _, info := range infos {
+		segments[manifestPath(info.Path)] = true
+		if !info.Sealed {
+			continue
+		}
+		name := filepath.Base(info.Path)
+		data, err := os.ReadFile(manifestPath(info.Path))
+		if errors.Is(err, os.ErrNotExist) {
+			return manifests, invalid(name, "no manifest")
+		}
+		if err != nil {
+			return manifests, err
+		}
+		var m SegmentManifest
+		if err := json.Unmarshal(data, &m); err != nil || m.Format != ManifestFormat {
+			return manifests, invalid(name, "unreadable manifest")
+		}
+		key, ok := keys(m.KeyID)
+		if !ok {
+			return manifests, invalid(name, "unknown key %q", m.KeyID)
+		}
+		if sig, err := m.signature(key); err != nil || !hmac.Equal([]byte(sig), []byte(m.Signature)) {
+			return manifests, invalid(name, "invalid signature")
+		}
+		digest, err := fileDigest(info.Path)
+		if err != nil {
+			return manifests, err
+		}
+		if m.Segment != name || m.Digest != digest || m.Index != info.Index || m.Records != info.Records || m.PrevChain != info.PrevChain || m.Chain != info.Chain {
+			return manifests, invalid(name, "segment does not match its manifest")
+		}
+		manifests = append(manifests, m)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.manifest.json"))
+	if err != nil {
+		return manifests, err
+	}
+	for _, path := range paths {
+		if !segments[path] {
+			return manifests, invalid(filepath.Base(path), "segment missing")
+		}
+	}
+	return manifests, verifyHead(dir, manifests, keys)
+}
+
+// verifyHead checks that the head of dir points at the last of manifests
+func verifyHead(dir string, manifests []SegmentManifest, keys func(keyID string) ([]byte, bool)) error {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: head.json: %s", ErrManifestInvalid, fmt.Sprintf(format, args...))
+	}
+	data, err := os.ReadFile(headPath(dir))
+	if errors.Is(err, os.ErrNotExist) {
+		if len(manifests) == 0 {
+			return nil
+		}
+		return invalid("no head")
+	}
+	if err != nil {
+		return err
+	}
+	var h manifestHead
+	if err := json.Unmarshal(data, &h); err != nil || h.Format != HeadFormat {
+		return invalid("unreadable head")
+	}
+	key, ok := keys(h.KeyID)
+	if !ok {
+		return invalid("unknown key %q", h.KeyID)
+	}
+	if sig, err := h.signature(key); err != nil || !hmac.Equal([]byte(sig), []byte(h.Signature)) {
+		return invalid("invalid signature")
+	}
+	if len(manifests) == 0 || h.Index > manifests[len(manifests)-1].Index {
+		return invalid("segments up to %s missing", h.Segment)
+	}
+	m := manifests[len(manifests)-1]
+	digest, err := fileDigest(filepath.Join(dir, manifestPath(m.Segment)))
+	if err != nil {
+		return err
+	}
+	if h.Index != m.Index || h.Segment != m.Segment || h.Chain != m.Chain || h.Manifest != digest {
+		return invalid("head does not point at the newest manifest %s", m.Segment)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestSegmentManifests(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("manifest-key")
+	keys := func(id string) ([]byte, bool) { return key, id == "m1" }
+
+	s, err := NewSegmentSink(dir, WithSegmentMaxBytes(1), WithSegmentManifest("m1", key))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		_ = s.Write(context.Background(), gotrails.NewTrail(id, "req", gotrails.NewConfig()).Finalize())
+	}
+	_ = s.Close()
+
+	manifests, err := VerifyManifests(dir, keys)
+	if err != nil || len(manifests) != 3 {
+		t.Fatalf("expected 3 valid manifests, got %d (%v)", len(manifests), err)
+	}
+	if m := manifests[1]; m.Records != 1 || m.FirstTraceID != "t2" || m.LastTraceID != "t2" || m.PrevChain != manifests[0].Chain {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+	if _, err := VerifyManifests(dir, func(string) ([]byte, bool) { return []byte("other"), true }); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected invalid signature, got %v", err)
+	}
+
+	// Replacing the last segment by a rebuilt, self-consistent one passes
+	// the chain check but not the manifest
+	last := segmentPath(dir, 3)
+	trail, _ := gotrails.NewTrail("tX", "req", gotrails.NewConfig()).Finalize().MarshalJSON()
+	hash := segmentHash(trail)
+	chain := nextChain(manifests[1].Chain, hash)
+	header, _ := json.Marshal(segmentLine{Format: SegmentFormat, Index: 3, PrevChain: manifests[1].Chain})
+	record := fmt.Appendf(nil, `{"seq":1,"hash":"%s","chain":"%s","trail":%s}`, hash, chain, trail)
+	footer, _ := json.Marshal(segmentLine{Sealed: true, Records: 1, Chain: chain})
+	_ = os.Chmod(last, 0o644)
+	if err := os.WriteFile(last, []byte(string(header)+"\n"+string(record)+"\n"+string(footer)+"\n"), 0o644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := VerifySegments(dir); err != nil {
+		t.Fatalf("expected rebuilt segment to pass the chain check, got %v", err)
+	}
+	if _, err := VerifyManifests(dir, keys); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected replaced segment detected, got %v", err)
+	}
+
+	// A manifest without its segment is reported
+	_ = os.Chmod(last, 0o644)
+	_ = os.Remove(last)
+	if _, err := VerifyManifests(dir, keys); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected missing segment reported, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "00000003.manifest.json")); err != nil {
+		t.Fatalf("expected manifest kept, got %v", err)
+	}
+}
+
+func TestSegmentManifestsDetectDeletedNewestSegments(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("manifest-key")
+	keys := func(id string) ([]byte, bool) { return key, id == "m1" }
+	clock := gotrails.NewManualClock(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+
+	s, err := NewSegmentSink(dir, WithSegmentMaxBytes(1), WithSegmentManifest("m1", key), WithSegmentClock(clock))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []string{"t1", "t2", "t3"} {
+		_ = s.Write(context.Background(), gotrails.NewTrail(id, "req", gotrails.NewConfig()).Finalize())
+	}
+	_ = s.Close()
+
+	manifests, err := VerifyManifests(dir, keys)
+	if err != nil || len(manifests) != 3 {
+		t.Fatalf("expected 3 valid manifests, got %d (%v)", len(manifests), err)
+	}
+	if !manifests[0].SealedAt.Equal(clock.Now()) {
+		t.Fatalf("expected sealed_at from the clock, got %v", manifests[0].SealedAt)
+	}
+
+	// Dropping the newest segment with its manifest leaves a valid chain,
+	// but the head still points at it
+	last := segmentPath(dir, 3)
+	_ = os.Chmod(last, 0o644)
+	_ = os.Remove(last)
+	_ = os.Remove(manifestPath(last))
+	if _, err := VerifySegments(dir); err != nil {
+		t.Fatalf("expected the shorter chain to pass the chain check, got %v", err)
+	}
+	if _, err := VerifyManifests(dir, keys); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected deleted newest segment detected, got %v", err)
+	}
+
+	_ = os.Remove(headPath(dir))
+	if _, err := VerifyManifests(dir, keys); !errors.Is(err, ErrManifestInvalid) {
+		t.Fatalf("expected missing head reported, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package sink
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// MemoryStore is a sink keeping written trails in memory, for tests and
+// small tools. It implements RetentionStore.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	seq    int
+	ids    []string
+	trails map[string]*gotrails.Trail
+}
+
+// NewMemoryStore creates a new MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{trails: make(map[string]*gotrails.Trail)}
+}
+
+// Write stores the trail record
+func (s *MemoryStore) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	id := strconv.Itoa(s.seq)
+	s.ids = append(s.ids, id)
+	s.trails[id] = record.Trail
+	return nil
+}
+
+// Trails returns the stored trails in write order
+func (s *MemoryStore) Trails() []*gotrails.Trail {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*gotrails.Trail, len(s.ids))
+	for i, id := range s.ids {
+		out[i] = s.trails[id]
+	}
+	return out
+}
+
+// ScanTrails calls fn for every stored trail in write order
+func (s *MemoryStore) ScanTrails(ctx context.Context, fn func(StoredTrail) error) error {
+	s.mu.RLock()
+	stored := make([]StoredTrail, len(s.ids))
+	for i, id := range s.ids {
+		t := s.trails[id]
+		stored[i] = StoredTrail{ID: id, Timestamp: t.Timestamp, Tags: t.Tags}
+	}
+	s.mu.RUnlock()
+
+	for _, st := range stored {
+		if err := fn(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTrails deletes stored trails by ID
+func (s *MemoryStore) DeleteTrails(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.trails, id)
+	}
+	kept := s.ids[:0]
+	for _, id := range s.ids {
+		if _, ok := s.trails[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	s.ids = kept
+	return nil
+}
+
+// Close does nothing
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// Name returns the name of the memory store
+func (s *MemoryStore) Name() string {
+	return "memory"
+}
@@ -0,0 +1,141 @@
+package sink
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// TrailQuery selects stored trails; zero fields match everything
+type TrailQuery struct {
+	TraceID   string    `json:"trace_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Service   string    `json:"service,omitempty"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	Tag       string    `json:"tag,omitempty"`
+	Since     time.Time `json:"since,omitzero"`
+	Until     time.Time `json:"until,omitzero"`
+	Limit     int       `json:"limit,omitempty"`
+}
+
+// Match reports whether a trail is selected by the query
+func (q TrailQuery) Match(t *gotrails.Trail) bool {
+	switch {
+	case q.TraceID != "" && t.TraceID != q.TraceID,
+		q.RequestID != "" && t.RequestID != q.RequestID,
+		q.Service != "" && t.Service != q.Service,
+		q.ActorID != "" && (t.Actor == nil || t.Actor.ID != q.ActorID),
+		q.Tag != "" && !slices.Contains(t.Tags, q.Tag),
+		!q.Since.IsZero() && t.Timestamp.Before(q.Since),
+		!q.Until.IsZero() && !t.Timestamp.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// Querier is implemented by sinks whose trails can be read back
+type Querier interface {
+	QueryTrails(ctx context.Context, q TrailQuery) ([]*gotrails.Trail, error)
+}
+
+// QueryTrails returns the stored trails matching q in write order
+func (s *MemoryStore) QueryTrails(ctx context.Context, q TrailQuery) ([]*gotrails.Trail, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*gotrails.Trail
+	for _, id := range s.ids {
+		if t := s.trails[id]; q.Match(t) {
+			out = append(out, t)
+			if q.Limit > 0 && len(out) == q.Limit {
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+// MetaAuditTag tags the meta-trails recording access to stored trails
+const MetaAuditTag = "meta-audit"
+
+// AuditedQuerier records every query on a Querier as a meta-trail: who
+// queried, the query, and which trails were returned, each as a "trail"
+// resource read. Access to audit data is itself audited this way.
+type AuditedQuerier struct {
+	querier Querier
+	sink    Sink
+	cfg     *gotrails.Config
+	actor   func(context.Context) *gotrails.Actor
+}
+
+// AuditedQuerierOption is an option for AuditedQuerier
+type AuditedQuerierOption func(*AuditedQuerier)
+
+// WithQueryActor sets how the querying actor is found. By default it is the
+// actor of the trail in the context, e.g. the request serving an admin UI.
+func WithQueryActor(fn func(context.Context) *gotrails.Actor) AuditedQuerierOption {
+	return func(a *AuditedQuerier) {
+		a.actor = fn
+	}
+}
+
+// WithQueryConfig sets the config of the meta-trails
+func WithQueryConfig(cfg *gotrails.Config) AuditedQuerierOption {
+	return func(a *AuditedQuerier) {
+		a.cfg = cfg
+	}
+}
+
+// NewAuditedQuerier wraps q, writing meta-trails to s
+func NewAuditedQuerier(q Querier, s Sink, opts ...AuditedQuerierOption) *AuditedQuerier {
+	a := &AuditedQuerier{
+		querier: q,
+		sink:    s,
+		cfg:     gotrails.DefaultConfig(),
+		actor: func(ctx context.Context) *gotrails.Actor {
+			if parent := gotrails.GetTrail(ctx); parent != nil {
+				return parent.Clone().Actor
+			}
+			return nil
+		},
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// QueryTrails runs the query and records it as a meta-trail
+func (a *AuditedQuerier) QueryTrails(ctx context.Context, q TrailQuery) ([]*gotrails.Trail, error) {
+	var meta *gotrails.Trail
+	if parent := gotrails.GetTrail(ctx); parent != nil {
+		meta = gotrails.NewChildTrail(parent, a.cfg)
+	} else {
+		meta = gotrails.NewTrail(gotrails.GenerateTraceID(), gotrails.GenerateRequestID(), a.cfg)
+	}
+	if actor := a.actor(ctx); actor != nil {
+		meta.SetActor(*actor)
+	}
+	meta.AddTag(MetaAuditTag)
+	meta.SetMetadata("query", q)
+
+	trails, err := a.querier.QueryTrails(ctx, q)
+	if err != nil {
+		meta.AddError("query", err.Error())
+	}
+	meta.SetMetadata("result_count", len(trails))
+	for _, t := range trails {
+		meta.AddResource(gotrails.Resource{Type: "trail", ID: t.TraceID + "/" + t.RequestID, Action: gotrails.ResourceActionRead})
+	}
+
+	werr := a.sink.Write(context.WithoutCancel(ctx), meta.Finalize())
+	meta.Release()
+	if werr != nil && err == nil {
+		// Access that cannot be audited is refused
+		return nil, werr
+	}
+	return trails, err
+}
@@ -0,0 +1,42 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestAuditedQuerierRecordsAccess(t *testing.T) {
+	store := NewMemoryStore()
+	for _, id := range []string{"t1", "t2", "t3"} {
+		trail := gotrails.NewTrail(id, "req-"+id, gotrails.NewConfig(gotrails.WithServiceName("payments")))
+		_ = store.Write(context.Background(), trail.Finalize())
+	}
+
+	audit := NewMemoryStore()
+	querier := NewAuditedQuerier(store, audit)
+	request := gotrails.NewTrail("admin-1", "admin-req-1", gotrails.NewConfig())
+	request.SetActor(gotrails.Actor{ID: "auditor-7", Type: gotrails.ActorTypeUser})
+	ctx := gotrails.WithTrail(context.Background(), request)
+
+	trails, err := querier.QueryTrails(ctx, TrailQuery{Service: "payments", Limit: 2})
+	if err != nil || len(trails) != 2 {
+		t.Fatalf("expected 2 trails, got %d (%v)", len(trails), err)
+	}
+
+	metas := audit.Trails()
+	if len(metas) != 1 {
+		t.Fatalf("expected 1 meta-trail, got %d", len(metas))
+	}
+	meta := metas[0]
+	if meta.Actor == nil || meta.Actor.ID != "auditor-7" || meta.ParentTraceID != "admin-1" || meta.Tags[0] != MetaAuditTag {
+		t.Fatalf("unexpected meta-trail: %+v", meta)
+	}
+	if len(meta.Resources) != 2 || meta.Resources[0].ID != "t1/req-t1" || meta.Resources[0].Action != gotrails.ResourceActionRead {
+		t.Fatalf("expected returned trails as read resources, got %+v", meta.Resources)
+	}
+	if q, ok := meta.Metadata["query"].(TrailQuery); !ok || q.Service != "payments" || meta.Metadata["result_count"] != 2 {
+		t.Fatalf("unexpected metadata: %v", meta.Metadata)
+	}
+}
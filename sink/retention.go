@@ -0,0 +1,209 @@
+package sink
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// StoredTrail is what retention needs to know about a stored trail
+type StoredTrail struct {
+	ID        string // store specific, e.g. a row ID or file offset
+	Timestamp time.Time
+	Tags      []string
+}
+
+// RetentionStore is implemented by sinks that keep trails and can delete them.
+// SegmentSink deliberately is not one: its segments are append-only and
+// chained, so expire segment archives as a whole, e.g. with the storage
+// lifecycle of the directory they are copied to.
+type RetentionStore interface {
+	ScanTrails(ctx context.Context, fn func(StoredTrail) error) error
+	DeleteTrails(ctx context.Context, ids []string) error
+}
+
+// Archiver copies expired trails elsewhere, e.g. to cold storage, before
+// they are deleted
+type Archiver interface {
+	Archive(ctx context.Context, trails []StoredTrail) error
+}
+
+// ArchiverFunc adapts a function to an Archiver
+type ArchiverFunc func(ctx context.Context, trails []StoredTrail) error
+
+// Archive calls f
+func (f ArchiverFunc) Archive(ctx context.Context, trails []StoredTrail) error {
+	return f(ctx, trails)
+}
+
+// RetentionPolicy decides which stored trails expire. Trails carrying a
+// legal hold tag never expire and do not count towards MaxCount.
+type RetentionPolicy struct {
+	MaxAge   time.Duration // trails older than this expire, 0 keeps them forever
+	MaxCount int           // only the newest MaxCount trails are kept, 0 means unlimited
+	HoldTags []string      // legal hold tags
+}
+
+// RetentionResult summarizes one retention run
+type RetentionResult struct {
+	Scanned  int `json:"scanned"`
+	Held     int `json:"held"`
+	Archived int `json:"archived"`
+	Deleted  int `json:"deleted"`
+}
+
+// Retention applies a RetentionPolicy to a RetentionStore
+type Retention struct {
+	store    RetentionStore
+	policy   RetentionPolicy
+	archiver Archiver
+	clock    gotrails.Clock
+	onError  func(error)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// RetentionOption is an option for Retention
+type RetentionOption func(*Retention)
+
+// WithArchiver archives expired trails before deleting them; trails whose
+// archiving fails are kept
+func WithArchiver(a Archiver) RetentionOption {
+	return func(r *Retention) {
+		r.archiver = a
+	}
+}
+
+// WithRetentionClock sets the clock trail ages are measured with
+func WithRetentionClock(c gotrails.Clock) RetentionOption {
+	return func(r *Retention) {
+		r.clock = c
+	}
+}
+
+// WithRetentionErrorHandler sets the error handler for scheduled runs
+func WithRetentionErrorHandler(fn func(error)) RetentionOption {
+	return func(r *Retention) {
+		r.onError = fn
+	}
+}
+
+// NewRetention creates a Retention for store
+func NewRetention(store RetentionStore, policy RetentionPolicy, opts ...RetentionOption) *Retention {
+	r := &Retention{
+		store:  store,
+		policy: policy,
+		clock:  gotrails.SystemClock,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run applies the policy once
+func (r *Retention) Run(ctx context.Context) (RetentionResult, error) {
+	var result RetentionResult
+	var trails []StoredTrail
+	err := r.store.ScanTrails(ctx, func(t StoredTrail) error {
+		result.Scanned++
+		if r.held(t) {
+			result.Held++
+			return nil
+		}
+		trails = append(trails, t)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Newest first, so the trails past MaxCount are the oldest
+	slices.SortStableFunc(trails, func(a, b StoredTrail) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	cutoff := r.clock.Now().Add(-r.policy.MaxAge)
+	var expired []StoredTrail
+	for i, t := range trails {
+		if (r.policy.MaxCount > 0 && i >= r.policy.MaxCount) || (r.policy.MaxAge > 0 && t.Timestamp.Before(cutoff)) {
+			expired = append(expired, t)
+		}
+	}
+	if len(expired) == 0 {
+		return result, nil
+	}
+
+	if r.archiver != nil {
+		if err := r.archiver.Archive(ctx, expired); err != nil {
+			return result, err
+		}
+		result.Archived = len(expired)
+	}
+	ids := make([]string, len(expired))
+	for i, t := range expired {
+		ids[i] = t.ID
+	}
+	if err := r.store.DeleteTrails(ctx, ids); err != nil {
+		return result, err
+	}
+	result.Deleted = len(ids)
+	return result, nil
+}
+
+// held reports whether a trail is under legal hold
+func (r *Retention) held(t StoredTrail) bool {
+	for _, tag := range t.Tags {
+		if slices.Contains(r.policy.HoldTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Start runs the policy every interval until Stop. Starting a running
+// Retention has no effect.
+func (r *Retention) Start(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	r.stop, r.done = stop, done
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := r.Run(context.Background()); err != nil && r.onError != nil {
+					r.onError(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled runs started with Start and waits for a run in
+// progress. The Retention can be started again afterwards.
+func (r *Retention) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop, r.done = nil, nil
+}
@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestRetentionExpiresByAgeAndCount(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := gotrails.NewManualClock(start)
+	cfg := gotrails.NewConfig(gotrails.WithClock(clock))
+	store := NewMemoryStore()
+	for _, id := range []string{"t1", "t2", "t3", "t4", "t5"} {
+		trail := gotrails.NewTrail(id, "req", cfg)
+		if id == "t1" {
+			trail.AddTag("legal-hold")
+		}
+		_ = store.Write(context.Background(), trail.Finalize())
+		clock.Advance(24 * time.Hour)
+	}
+
+	var archived []StoredTrail
+	retention := NewRetention(store, RetentionPolicy{MaxAge: 72 * time.Hour, MaxCount: 2, HoldTags: []string{"legal-hold"}},
+		WithRetentionClock(clock),
+		WithArchiver(ArchiverFunc(func(ctx context.Context, trails []StoredTrail) error {
+			archived = append(archived, trails...)
+			return nil
+		})),
+	)
+	result, err := retention.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// t2 is too old, t3 is past the newest two, t1 is on hold
+	if result != (RetentionResult{Scanned: 5, Held: 1, Archived: 2, Deleted: 2}) || len(archived) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	var kept []string
+	for _, trail := range store.Trails() {
+		kept = append(kept, trail.TraceID)
+	}
+	if len(kept) != 3 || kept[0] != "t1" || kept[1] != "t4" || kept[2] != "t5" {
+		t.Fatalf("expected t1, t4 and t5 to be kept, got %v", kept)
+	}
+}
+
+func TestRetentionStartTwiceRunsOnce(t *testing.T) {
+	retention := NewRetention(NewMemoryStore(), RetentionPolicy{MaxAge: time.Hour})
+	retention.Start(time.Millisecond)
+	first := retention.done
+	retention.Start(time.Millisecond)
+	if retention.done != first {
+		t.Fatal("expected the second Start to keep the running loop")
+	}
+	retention.Stop()
+	select {
+	case <-first:
+	default:
+		t.Fatal("expected Stop to stop the loop")
+	}
+
+	retention.Start(time.Millisecond)
+	retention.Stop()
+	retention.Stop()
+}
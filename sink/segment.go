@@ -0,0 +1,323 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// SegmentFormat identifies the append-only segment format
+const SegmentFormat = "gotrails-segment/v1"
+
+// ErrSegmentCorrupt is returned when a segment fails verification
+var ErrSegmentCorrupt = errors.New("sink: segment corrupt")
+
+// SegmentSink writes trails to append-only segment files, a tamper-evident
+// alternative to plain NDJSON. Every segment is a JSON lines file:
+//
+//	{"format":"gotrails-segment/v1","index":1,"prev_chain":""}
+//	{"seq":1,"hash":"<sha256 of trail>","chain":"<sha256 of prev chain + hash>","trail":{...}}
+//	{"sealed":true,"records":1,"chain":"<last chain>"}
+//
+// The running chain continues across segments through prev_chain. Files are
+// only ever opened for appending, existing segments are never reopened, and
+// sealed segments are made read-only. Use VerifySegments to check them, or
+// VerifyManifests with WithSegmentManifest.
+type SegmentSink struct {
+	dir      string
+	maxBytes int64
+	sync     bool
+
+	manifestKeyID string
+	manifestKey   []byte
+	clock         gotrails.Clock
+
+	mu           sync.Mutex
+	file         *os.File
+	index        int
+	records      int
+	size         int64
+	chain        string
+	prevChain    string // chain at the start of the current segment
+	firstTraceID string
+	lastTraceID  string
+}
+
+// SegmentOption is an option for SegmentSink
+type SegmentOption func(*SegmentSink)
+
+// WithSegmentMaxBytes sets the size after which a segment is sealed and a
+// new one started, 64MB by default
+func WithSegmentMaxBytes(n int64) SegmentOption {
+	return func(s *SegmentSink) {
+		if n > 0 {
+			s.maxBytes = n
+		}
+	}
+}
+
+// WithSegmentSync fsyncs the segment after every record
+func WithSegmentSync(sync bool) SegmentOption {
+	return func(s *SegmentSink) {
+		s.sync = sync
+	}
+}
+
+// WithSegmentClock sets the clock used for manifest timestamps
+func WithSegmentClock(c gotrails.Clock) SegmentOption {
+	return func(s *SegmentSink) {
+		s.clock = c
+	}
+}
+
+// NewSegmentSink creates a SegmentSink writing to dir. The existing segments
+// are verified and writing continues in a new segment chained to the last.
+func NewSegmentSink(dir string, opts ...SegmentOption) (*SegmentSink, error) {
+	s := &SegmentSink{dir: dir, maxBytes: 64 << 20, clock: gotrails.SystemClock}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	infos, err := VerifySegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if n := len(infos); n > 0 {
+		s.index = infos[n-1].Index
+		s.chain = infos[n-1].Chain
+	}
+	return s, nil
+}
+
+// SegmentInfo describes a verified segment
+type SegmentInfo struct {
+	Path      string `json:"path"`
+	Index     int    `json:"index"`
+	Records   int    `json:"records"`
+	PrevChain string `json:"prev_chain"`
+	Chain     string `json:"chain"` // chain after the last record
+	Sealed    bool   `json:"sealed"`
+}
+
+// segmentLine is any line of a segment
+type segmentLine struct {
+	Format    string          `json:"format,omitempty"`
+	Index     int             `json:"index,omitempty"`
+	PrevChain string          `json:"prev_chain"`
+	Seq       int             `json:"seq,omitempty"`
+	Hash      string          `json:"hash,omitempty"`
+	Chain     string          `json:"chain,omitempty"`
+	Trail     json.RawMessage `json:"trail,omitempty"`
+	Sealed    bool            `json:"sealed,omitempty"`
+	Records   int             `json:"records,omitempty"`
+}
+
+func segmentPath(dir string, index int) string {
+	return filepath.Join(dir, fmt.Sprintf("%08d.seg", index))
+}
+
+func segmentHash(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func nextChain(prev, hash string) string {
+	return segmentHash([]byte(prev + hash))
+}
+
+// Write appends the trail record to the current segment
+func (s *SegmentSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	data, err := record.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		if err := s.openLocked(); err != nil {
+			return err
+		}
+	}
+
+	hash := segmentHash(data)
+	chain := nextChain(s.chain, hash)
+	line := fmt.Appendf(nil, `{"seq":%d,"hash":"%s","chain":"%s","trail":`, s.records+1, hash, chain)
+	line = append(append(line, data...), '}', '\n')
+	if err := s.appendLocked(line); err != nil {
+		return err
+	}
+	s.records++
+	s.chain = chain
+	if s.records == 1 {
+		s.firstTraceID = record.TraceID
+	}
+	s.lastTraceID = record.TraceID
+
+	if s.size >= s.maxBytes {
+		return s.sealLocked()
+	}
+	return nil
+}
+
+// openLocked starts the next segment
+func (s *SegmentSink) openLocked() error {
+	s.index++
+	f, err := os.OpenFile(segmentPath(s.dir, s.index), os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file, s.records, s.size = f, 0, 0
+	s.prevChain, s.firstTraceID, s.lastTraceID = s.chain, "", ""
+	header, _ := json.Marshal(segmentLine{Format: SegmentFormat, Index: s.index, PrevChain: s.chain})
+	return s.appendLocked(append(header, '\n'))
+}
+
+func (s *SegmentSink) appendLocked(line []byte) error {
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if s.sync {
+		return s.file.Sync()
+	}
+	return nil
+}
+
+// sealLocked writes the footer of the current segment, makes it read-only
+// and writes its manifest
+func (s *SegmentSink) sealLocked() error {
+	footer, _ := json.Marshal(segmentLine{Sealed: true, Records: s.records, Chain: s.chain})
+	err := s.appendLocked(append(footer, '\n'))
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := os.Chmod(s.file.Name(), 0o444); err == nil {
+		err = cerr
+	}
+	if err == nil && s.manifestKey != nil {
+		err = s.writeManifestLocked(s.file.Name())
+	}
+	s.file = nil
+	return err
+}
+
+// Close seals the current segment
+func (s *SegmentSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	return s.sealLocked()
+}
+
+// Name returns the name of the segment sink
+func (s *SegmentSink) Name() string {
+	return "segment"
+}
+
+// VerifySegment recomputes the record hashes and the running chain of one
+// segment
+func VerifySegment(path string) (SegmentInfo, error) {
+	info := SegmentInfo{Path: path}
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	corrupt := func(line int, format string, args ...any) error {
+		return fmt.Errorf("%w: %s line %d: %s", ErrSegmentCorrupt, filepath.Base(path), line, fmt.Sprintf(format, args...))
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<30)
+	n := 0
+	for scanner.Scan() {
+		n++
+		var l segmentLine
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return info, corrupt(n, "invalid JSON")
+		}
+		switch {
+		case n == 1:
+			if l.Format != SegmentFormat {
+				return info, corrupt(n, "unknown format %q", l.Format)
+			}
+			info.Index, info.PrevChain, info.Chain = l.Index, l.PrevChain, l.PrevChain
+		case info.Sealed:
+			return info, corrupt(n, "data after seal")
+		case l.Sealed:
+			if l.Records != info.Records || l.Chain != info.Chain {
+				return info, corrupt(n, "seal does not match the records")
+			}
+			info.Sealed = true
+		default:
+			if l.Seq != info.Records+1 {
+				return info, corrupt(n, "expected seq %d, got %d", info.Records+1, l.Seq)
+			}
+			if hash := segmentHash(l.Trail); hash != l.Hash {
+				return info, corrupt(n, "record hash mismatch")
+			}
+			if chain := nextChain(info.Chain, l.Hash); chain != l.Chain {
+				return info, corrupt(n, "chain mismatch")
+			}
+			info.Records++
+			info.Chain = l.Chain
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return info, err
+	}
+	if n == 0 {
+		return info, corrupt(0, "empty segment")
+	}
+	return info, nil
+}
+
+// VerifySegments verifies every segment of dir in order, including the
+// links between segments. The first segment must start the chain, so deleted
+// oldest segments are reported; deleted newest segments are only detected by
+// VerifyManifests.
+func VerifySegments(dir string) ([]SegmentInfo, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var infos []SegmentInfo
+	for i, path := range paths {
+		info, err := VerifySegment(path)
+		if err != nil {
+			return infos, err
+		}
+		if i == 0 && (info.Index != 1 || info.PrevChain != "") {
+			return infos, fmt.Errorf("%w: %s does not start the chain", ErrSegmentCorrupt, filepath.Base(path))
+		}
+		if i > 0 {
+			prev := infos[i-1]
+			if info.Index != prev.Index+1 || info.PrevChain != prev.Chain {
+				return infos, fmt.Errorf("%w: %s does not follow %s", ErrSegmentCorrupt, filepath.Base(path), filepath.Base(prev.Path))
+			}
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
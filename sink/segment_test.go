@@ -0,0 +1,77 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestSegmentSinkChainsAndVerifies(t *testing.T) {
+	dir := t.TempDir()
+	write := func(s *SegmentSink, ids ...string) {
+		for _, id := range ids {
+			if err := s.Write(context.Background(), gotrails.NewTrail(id, "req", gotrails.NewConfig()).Finalize()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+
+	s, err := NewSegmentSink(dir, WithSegmentMaxBytes(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	write(s, "t1", "t2")
+	_ = s.Close()
+
+	// A restarted sink continues the chain in a new segment
+	s, err = NewSegmentSink(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	write(s, "t3")
+	_ = s.Close()
+
+	infos, err := VerifySegments(dir)
+	if err != nil || len(infos) != 3 {
+		t.Fatalf("expected 3 valid segments, got %d (%v)", len(infos), err)
+	}
+	if !infos[2].Sealed || infos[2].Records != 1 || infos[2].PrevChain != infos[1].Chain {
+		t.Fatalf("unexpected last segment: %+v", infos[2])
+	}
+	if fi, _ := os.Stat(infos[0].Path); fi.Mode().Perm()&0o222 != 0 {
+		t.Fatalf("expected sealed segment to be read-only, got %v", fi.Mode())
+	}
+
+	data, _ := os.ReadFile(infos[1].Path)
+	_ = os.Chmod(infos[1].Path, 0o644)
+	_ = os.WriteFile(infos[1].Path, bytes.Replace(data, []byte(`"trace_id":"t2"`), []byte(`"trace_id":"t9"`), 1), 0o644)
+	if _, err := VerifySegments(dir); !errors.Is(err, ErrSegmentCorrupt) {
+		t.Fatalf("expected ErrSegmentCorrupt after tampering, got %v", err)
+	}
+	if _, err := NewSegmentSink(dir); !errors.Is(err, ErrSegmentCorrupt) {
+		t.Fatalf("expected sink to refuse a tampered directory, got %v", err)
+	}
+}
+
+func TestVerifySegmentsRequiresChainStart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSegmentSink(dir, WithSegmentMaxBytes(1))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, id := range []string{"t1", "t2"} {
+		_ = s.Write(context.Background(), gotrails.NewTrail(id, "req", gotrails.NewConfig()).Finalize())
+	}
+	_ = s.Close()
+
+	first := segmentPath(dir, 1)
+	_ = os.Chmod(first, 0o644)
+	_ = os.Remove(first)
+	if _, err := VerifySegments(dir); !errors.Is(err, ErrSegmentCorrupt) {
+		t.Fatalf("expected deleted oldest segment reported, got %v", err)
+	}
+}
@@ -8,8 +8,8 @@ import (
 
 // Sink is the interface for trail output destinations
 type Sink interface {
-	// Write writes a trail to the sink
-	Write(ctx context.Context, trail *gotrails.Trail) error
+	// Write writes a finalized trail record to the sink
+	Write(ctx context.Context, record *gotrails.TrailRecord) error
 
 	// Close closes the sink and releases resources
 	Close() error
@@ -31,10 +31,10 @@ func NewMultiSink(sinks ...Sink) *MultiSink {
 }
 
 // Write writes to all sinks
-func (m *MultiSink) Write(ctx context.Context, trail *gotrails.Trail) error {
+func (m *MultiSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
 	var lastErr error
 	for _, s := range m.sinks {
-		if err := s.Write(ctx, trail); err != nil {
+		if err := s.Write(ctx, record); err != nil {
 			lastErr = err
 		}
 	}
@@ -71,7 +71,7 @@ func NewNoopSink() *NoopSink {
 }
 
 // Write does nothing
-func (n *NoopSink) Write(ctx context.Context, trail *gotrails.Trail) error {
+func (n *NoopSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
 	return nil
 }
 
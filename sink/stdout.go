@@ -1,6 +1,7 @@
 package sink
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -66,8 +67,8 @@ func NewStdoutSink(opts ...StdoutOption) *StdoutSink {
 	return s
 }
 
-// Write writes a trail to stdout as JSON
-func (s *StdoutSink) Write(ctx context.Context, trail *gotrails.Trail) error {
+// Write writes a trail record to stdout as JSON
+func (s *StdoutSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
 	if s.disabled {
 		return nil
 	}
@@ -78,24 +79,26 @@ func (s *StdoutSink) Write(ctx context.Context, trail *gotrails.Trail) error {
 	var data []byte
 	var err error
 
-	if s.pretty {
-		data, err = json.MarshalIndent(trail, "", "  ")
-	} else {
-		data, err = json.Marshal(trail)
-	}
-
+	data, err = record.MarshalJSON()
 	if err != nil {
 		return err
 	}
+	if s.pretty {
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
 
 	if s.identify {
 		method := ""
 		path := ""
-		if trail != nil && trail.Request != nil {
-			method = trail.Request.Method
-			path = trail.Request.Path
+		if record != nil && record.Request != nil {
+			method = record.Request.Method
+			path = record.Request.Path
 		}
-		_, err = fmt.Fprintf(s.writer, "[GOTRAILS-debug] [trace_id=%s,request_id=%s,method=%s,path=%s,loggers=%s]\n", trail.TraceID, trail.RequestID, method, path, data)
+		_, err = fmt.Fprintf(s.writer, "[GOTRAILS-debug] [trace_id=%s,request_id=%s,method=%s,path=%s,loggers=%s]\n", record.TraceID, record.RequestID, method, path, data)
 		if err != nil {
 			return err
 		}
@@ -105,8 +108,8 @@ func (s *StdoutSink) Write(ctx context.Context, trail *gotrails.Trail) error {
 		return err
 	}
 
-	// Add newline
-	data = append(data, '\n')
+	// Add newline, without writing into the record's encoding
+	data = append(data[:len(data):len(data)], '\n')
 	_, err = s.writer.Write(data)
 	return err
 }
@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"context"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/header"
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// configFromContext returns the config in context or the default config
+func configFromContext(ctx context.Context) *gotrails.Config {
+	if cfg := gotrails.GetConfig(ctx); cfg != nil {
+		return cfg
+	}
+	return gotrails.DefaultConfig()
+}
+
+// maskerFromConfig returns the shared masker of the config, nil if masking
+// is disabled
+func maskerFromConfig(cfg *gotrails.Config) *masker.Masker {
+	if !cfg.EnableMasking {
+		return nil
+	}
+	return cfg.Masker()
+}
+
+// newHeaderFilter creates the header filter of the config for a direction
+func newHeaderFilter(cfg *gotrails.Config, dir gotrails.HeaderDirection) *header.Filter {
+	p := cfg.HeaderPolicy(dir)
+	return header.NewPolicyFilter(p.Include, p.Drop, p.Mask,
+		header.WithMaskValue(cfg.MaskValue),
+		header.WithEncrypter(cfg.Encrypter),
+		header.WithHashKey(cfg.MaskHashKey),
+		header.WithCredentialMasking(cfg.MaskHeaderCredentials),
+	)
+}
@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/body"
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// ElasticsearchPerformer is implemented by elastictransport.Interface
+type ElasticsearchPerformer interface {
+	Perform(req *http.Request) (*http.Response, error)
+}
+
+// ElasticsearchTransport wraps an Elasticsearch transport to capture requests
+// as search integrations. It implements both elastictransport.Interface and
+// http.RoundTripper, so it can wrap a client transport or be set as
+// elasticsearch.Config.Transport.
+type ElasticsearchTransport struct {
+	Base ElasticsearchPerformer
+}
+
+// NewElasticsearchTransport wraps an elastictransport.Interface, nil wraps http.DefaultTransport
+func NewElasticsearchTransport(base ElasticsearchPerformer) *ElasticsearchTransport {
+	if base == nil {
+		base = roundTripPerformer{http.DefaultTransport}
+	}
+	return &ElasticsearchTransport{Base: base}
+}
+
+// roundTripPerformer adapts an http.RoundTripper to ElasticsearchPerformer
+type roundTripPerformer struct {
+	http.RoundTripper
+}
+
+func (p roundTripPerformer) Perform(req *http.Request) (*http.Response, error) {
+	return p.RoundTrip(req)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ElasticsearchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.Perform(req)
+}
+
+// Perform executes the request and records it as an integration
+func (t *ElasticsearchTransport) Perform(req *http.Request) (*http.Response, error) {
+	trail := gotrails.GetTrail(req.Context())
+	if trail == nil {
+		return t.Base.Perform(req)
+	}
+
+	cfg := configFromContext(req.Context())
+	metadataOnly := cfg.IsMetadataOnly()
+
+	request := map[string]any{
+		"method": req.Method,
+		"path":   req.URL.Path,
+	}
+	if metadataOnly {
+		request["body_size"] = max(req.ContentLength, 0)
+	} else if req.Body != nil && req.ContentLength != 0 {
+		reader := body.NewReader(body.WithMaxSize(cfg.MaxRequestBodySize))
+		if data, newBody, truncated, err := reader.ReadAndRestoreTruncated(req.Body); err == nil {
+			req.Body = newBody
+			request["body"] = elasticsearchBodySummary(maskerFromConfig(cfg), req.Header.Get("Content-Type"), data, truncated)
+		}
+	}
+
+	clock := gotrails.ClockFromContext(req.Context())
+	start := clock.Now()
+	resp, err := t.Base.Perform(req)
+	latencyMs := clock.Since(start).Milliseconds()
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeSearch,
+		Name:      "elasticsearch." + elasticsearchOperation(req.Method, req.URL.Path),
+		LatencyMs: latencyMs,
+		Request:   request,
+		Metadata:  map[string]any{"host": req.URL.Host},
+	}
+	if index := elasticsearchIndex(req.URL.Path); index != "" {
+		integration.Metadata["index"] = index
+	}
+	if resp != nil {
+		response := map[string]any{"status": resp.StatusCode}
+		if resp.Body != nil {
+			reader := body.NewReader(body.WithMaxSize(cfg.MaxResponseBodySize))
+			if data, newBody, rerr := reader.ReadAndRestore(resp.Body); rerr == nil {
+				resp.Body = newBody
+				elasticsearchResponseSummary(response, data)
+			}
+		}
+		integration.Response = response
+		if reason, ok := response["error"].(string); ok {
+			integration.Error = reason
+		}
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+
+	return resp, err
+}
+
+// elasticsearchBodySummary returns the masked JSON request body. NDJSON
+// bodies (bulk, msearch) are summarized by their line count.
+func elasticsearchBodySummary(msk *masker.Masker, contentType string, data []byte, truncated bool) any {
+	if strings.Contains(contentType, "ndjson") {
+		return map[string]any{
+			"lines":     bytes.Count(bytes.TrimSpace(data), []byte("\n")) + 1,
+			"truncated": truncated,
+		}
+	}
+	if truncated {
+		return truncatedBody(len(data))
+	}
+	return parseAndMaskJSON(msk, data)
+}
+
+// elasticsearchResponseSummary adds took, bulk item errors and the error
+// reason of an Elasticsearch response to the captured response
+func elasticsearchResponseSummary(response map[string]any, data []byte) {
+	var reply struct {
+		Took   *int64 `json:"took"`
+		Errors bool   `json:"errors"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return
+	}
+	if reply.Took != nil {
+		response["took_ms"] = *reply.Took
+	}
+	if reply.Errors {
+		response["item_errors"] = true
+	}
+	if reply.Error != nil {
+		response["error"] = reply.Error.Type + ": " + reply.Error.Reason
+	}
+}
+
+// elasticsearchOperation derives the operation from the last API segment of
+// the path (e.g. "/orders/_search" becomes "search"), falling back to the method
+func elasticsearchOperation(method, path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.HasPrefix(segments[i], "_") {
+			return strings.TrimPrefix(segments[i], "_")
+		}
+	}
+	return strings.ToLower(method)
+}
+
+// elasticsearchIndex returns the index targeted by the path, if any
+func elasticsearchIndex(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if strings.HasPrefix(first, "_") {
+		return ""
+	}
+	return first
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+func TestElasticsearchTransportCapturesSearch(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	cfg.EnableMasking = true
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+
+	base := roundTripPerformer{roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"took":12,"hits":{"total":{"value":1}}}`)),
+		}, nil
+	})}
+
+	req := httptest.NewRequest(http.MethodPost, "http://es:9200/orders/_search", bytes.NewBufferString(`{"query":{"match":{"password":"secret"}}}`))
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+
+	if _, err := NewElasticsearchTransport(base).Perform(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeSearch || integration.Name != "elasticsearch.search" {
+		t.Fatalf("unexpected integration: %s %s", integration.Type, integration.Name)
+	}
+	if integration.Metadata["index"] != "orders" {
+		t.Fatalf("expected index metadata, got %v", integration.Metadata)
+	}
+	match := integration.Request.(map[string]any)["body"].(map[string]any)["query"].(map[string]any)["match"].(map[string]any)
+	if match["password"] != cfg.MaskValue {
+		t.Fatalf("expected masked password, got %v", match["password"])
+	}
+	if integration.Response.(map[string]any)["took_ms"] != int64(12) {
+		t.Fatalf("expected took_ms, got %v", integration.Response)
+	}
+}
+
+func TestElasticsearchTransportRecordsErrorReason(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+
+	base := roundTripPerformer{roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"error":{"type":"index_not_found_exception","reason":"no such index [orders]"},"status":404}`)),
+		}, nil
+	})}
+
+	req := httptest.NewRequest(http.MethodGet, "http://es:9200/orders/_doc/1", nil)
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	if _, err := NewElasticsearchTransport(base).RoundTrip(req.WithContext(ctx)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	integration := trail.Integrations[0]
+	if integration.Name != "elasticsearch.doc" || integration.Error != "index_not_found_exception: no such index [orders]" {
+		t.Fatalf("unexpected integration: %s %q", integration.Name, integration.Error)
+	}
+}
+
+func TestElasticsearchTransportTruncationMatchesBodyLimit(t *testing.T) {
+	const query = `{"query":{"match_all":{}}}`
+	base := roundTripPerformer{roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{}`))}, nil
+	})}
+
+	for _, tc := range []struct {
+		limit     int
+		truncated bool
+	}{{len(query), false}, {len(query) - 1, true}} {
+		cfg := gotrails.NewConfig(gotrails.WithMaxRequestBodySize(tc.limit))
+		trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+		ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+		req := httptest.NewRequest(http.MethodPost, "http://es:9200/orders/_search", bytes.NewBufferString(query))
+		if _, err := NewElasticsearchTransport(base).Perform(req.WithContext(ctx)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		body := trail.Integrations[0].Request.(map[string]any)["body"].(map[string]any)
+		if _, got := body["truncated"]; got != tc.truncated {
+			t.Fatalf("limit %d: expected truncated=%v, got %v", tc.limit, tc.truncated, body)
+		}
+	}
+}
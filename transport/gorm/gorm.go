@@ -0,0 +1,116 @@
+package gorm
+
+import (
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"gorm.io/gorm"
+)
+
+const gormStartKey = "gotrails:start"
+
+// GormPlugin is a gorm.Plugin that records create, query, update, delete,
+// row and raw operations as database integrations on the trail in the
+// statement context. Use db.WithContext(ctx) so the trail can be found.
+type GormPlugin struct {
+	system string
+}
+
+// NewGormPlugin creates a new GormPlugin. system is used as integration name prefix (e.g. "postgres").
+func NewGormPlugin(system string) *GormPlugin {
+	if system == "" {
+		system = "gorm"
+	}
+	return &GormPlugin{system: system}
+}
+
+// Name returns the plugin name
+func (p *GormPlugin) Name() string {
+	return "gotrails"
+}
+
+// Initialize registers the plugin callbacks
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("gotrails:before_create", p.before); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("gotrails:after_create", p.after("create")); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("gotrails:before_query", p.before); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("gotrails:after_query", p.after("query")); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("gotrails:before_update", p.before); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("gotrails:after_update", p.after("update")); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("gotrails:before_delete", p.before); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("gotrails:after_delete", p.after("delete")); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("gotrails:before_row", p.before); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("gotrails:after_row", p.after("row")); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("gotrails:before_raw", p.before); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("gotrails:after_raw", p.after("raw"))
+}
+
+func (p *GormPlugin) before(db *gorm.DB) {
+	db.InstanceSet(gormStartKey, time.Now())
+}
+
+func (p *GormPlugin) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement == nil || db.Statement.Context == nil {
+			return
+		}
+		trail := gotrails.GetTrail(db.Statement.Context)
+		if trail == nil {
+			return
+		}
+
+		var latencyMs int64
+		if v, ok := db.InstanceGet(gormStartKey); ok {
+			if start, ok := v.(time.Time); ok {
+				latencyMs = time.Since(start).Milliseconds()
+			}
+		}
+
+		name := p.system + "." + operation
+		if db.Statement.Table != "" {
+			name = p.system + "." + db.Statement.Table + "." + operation
+		}
+
+		integration := gotrails.Integration{
+			Type:      gotrails.IntegrationTypeDatabase,
+			Name:      name,
+			LatencyMs: latencyMs,
+			Request: map[string]any{
+				"operation":  operation,
+				"table":      db.Statement.Table,
+				"query":      db.Statement.SQL.String(),
+				"args_count": len(db.Statement.Vars),
+			},
+			Response: map[string]any{
+				"rows_affected": db.Statement.RowsAffected,
+			},
+		}
+		if db.Error != nil {
+			integration.Error = db.Error.Error()
+		}
+		trail.AddIntegration(integration)
+	}
+}
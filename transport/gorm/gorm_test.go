@@ -0,0 +1,123 @@
+package gorm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "FAIL" {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(2), nil
+}
+
+type fakeStmt struct{}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeDialector struct {
+	conn gorm.ConnPool
+}
+
+func (d fakeDialector) Name() string { return "fake" }
+
+func (d fakeDialector) Initialize(db *gorm.DB) error {
+	db.ConnPool = d.conn
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+
+func (d fakeDialector) Migrator(db *gorm.DB) gorm.Migrator             { return nil }
+func (d fakeDialector) DataTypeOf(*schema.Field) string                { return "" }
+func (d fakeDialector) DefaultValueOf(*schema.Field) clause.Expression { return nil }
+func (d fakeDialector) QuoteTo(w clause.Writer, s string)              { _, _ = w.WriteString(s) }
+func (d fakeDialector) Explain(sql string, vars ...interface{}) string { return sql }
+func (d fakeDialector) BindVarTo(w clause.Writer, stmt *gorm.Statement, v any) {
+	_, _ = w.WriteString("$" + strconv.Itoa(len(stmt.Vars)))
+}
+
+type payment struct {
+	ID     string
+	Amount int
+}
+
+func TestGormPluginRecordsIntegrations(t *testing.T) {
+	sql.Register("gotrails-gorm-fake", fakeDriver{})
+	sqlDB, err := sql.Open("gotrails-gorm-fake", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer sqlDB.Close()
+
+	db, err := gorm.Open(fakeDialector{conn: sqlDB}, &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.Use(NewGormPlugin("postgres")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	if err := db.WithContext(ctx).Model(&payment{}).Where("id = ?", "pay-123").Update("amount", 150000).Error; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeDatabase || integration.Name != "postgres.payments.update" {
+		t.Fatalf("unexpected integration: %+v", integration)
+	}
+	req := integration.Request.(map[string]any)
+	if req["query"] != "UPDATE payments SET amount=$1 WHERE id = $2" || req["args_count"] != 2 {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	if integration.Response.(map[string]any)["rows_affected"] != int64(2) {
+		t.Fatalf("unexpected response: %v", integration.Response)
+	}
+}
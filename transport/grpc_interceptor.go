@@ -2,32 +2,61 @@ package transport
 
 import (
 	"context"
-	"time"
+	"strings"
 
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/payload"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
-// IntegrationUnaryClientInterceptor returns a gRPC UnaryClientInterceptor that captures integration events
+// IntegrationUnaryClientInterceptor returns a gRPC UnaryClientInterceptor that
+// records outgoing calls as integrations on the trail in context and
+// propagates the trace and request IDs in the outgoing metadata
 func IntegrationUnaryClientInterceptor() grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		start := time.Now()
+		trail := gotrails.GetTrail(ctx)
+		if trail == nil {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		cfg := configFromContext(ctx)
+		ctx = metadata.AppendToOutgoingContext(ctx,
+			strings.ToLower(cfg.TraceIDHeader), trail.TraceID,
+			strings.ToLower(cfg.RequestIDHeader), trail.RequestID,
+		)
+
+		clock := gotrails.ClockFromContext(ctx)
+		start := clock.Now()
 		err := invoker(ctx, method, req, reply, cc, opts...)
-		latency := time.Since(start)
+		latencyMs := clock.Since(start).Milliseconds()
 
-		integration := map[string]any{
-			"type":    "grpc",
-			"method":  method,
-			"latency": latency,
-			"error":   err,
+		code := status.Code(err).String()
+		request := map[string]any{"method": method}
+		response := map[string]any{"code": code}
+		if !cfg.IsMetadataOnly() {
+			msk := maskerFromConfig(cfg)
+			request["body"] = payload.Proto(req, msk, cfg.MaxRequestBodySize)
+			if err == nil {
+				response["body"] = payload.Proto(reply, msk, cfg.MaxResponseBodySize)
+			}
 		}
 
-		// Attach integration to trail in context if present
-		trail := ctx.Value("gotrails_trail")
-		if trail != nil {
-			if t, ok := trail.(interface{ AddIntegration(any) }); ok {
-				t.AddIntegration(integration)
-			}
+		integration := gotrails.Integration{
+			Type:      gotrails.IntegrationTypeGRPC,
+			Name:      method,
+			LatencyMs: latencyMs,
+			Request:   request,
+			Response:  response,
+		}
+		if cc != nil {
+			integration.Metadata = map[string]any{"target": cc.Target()}
+		}
+		if err != nil {
+			integration.Error = status.Convert(err).Message()
 		}
+		trail.AddIntegration(integration)
 
 		return err
 	}
@@ -0,0 +1,68 @@
+package transport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestIntegrationUnaryClientInterceptorRecordsCall(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	cfg.EnableMasking = true
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+
+	req, _ := structpb.NewStruct(map[string]any{"user_id": "u-1", "password": "secret"})
+	reply := &structpb.Struct{}
+
+	err := IntegrationUnaryClientInterceptor()(ctx, "/user.UserService/GetUser", req, reply, nil,
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			md, _ := metadata.FromOutgoingContext(ctx)
+			if got := md.Get("x-trace-id"); len(got) != 1 || got[0] != "trace-1" {
+				t.Errorf("expected propagated trace id, got %v", got)
+			}
+			return nil
+		})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeGRPC || integration.Name != "/user.UserService/GetUser" {
+		t.Fatalf("unexpected integration: %s %s", integration.Type, integration.Name)
+	}
+	body := integration.Request.(map[string]any)["body"].(map[string]any)
+	if body["password"] != cfg.MaskValue || body["user_id"] != "u-1" {
+		t.Fatalf("unexpected request payload: %v", body)
+	}
+	if integration.Response.(map[string]any)["code"] != codes.OK.String() {
+		t.Fatalf("expected OK code, got %v", integration.Response)
+	}
+}
+
+func TestIntegrationUnaryClientInterceptorRecordsError(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	err := IntegrationUnaryClientInterceptor()(ctx, "/user.UserService/GetUser", nil, nil, nil,
+		func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return status.Error(codes.Unavailable, "connection refused")
+		})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable, got %v", err)
+	}
+
+	integration := trail.Integrations[0]
+	if integration.Error != "connection refused" || integration.Response.(map[string]any)["code"] != codes.Unavailable.String() {
+		t.Fatalf("unexpected integration: %+v", integration)
+	}
+}
@@ -2,19 +2,70 @@ package transport
 
 import (
 	"bytes"
+	"cmp"
 	"encoding/json"
 	"net/http"
-	"time"
+	"net/url"
 
 	"github.com/aizacoders/gotrails/gotrails"
 	"github.com/aizacoders/gotrails/internal/body"
-	"github.com/aizacoders/gotrails/internal/header"
 	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
 )
 
 // HTTPRoundTripper wraps an http.RoundTripper to capture HTTP calls as integrations
 type HTTPRoundTripper struct {
 	Base http.RoundTripper
+
+	// StreamResponses captures response bodies lazily as the caller reads
+	// them instead of buffering them up front. The integration is recorded
+	// when the call returns; the body, its size and digest are added once it
+	// is fully read or closed, unless the trail was finalized by then.
+	StreamResponses bool
+
+	policies []hostPolicy
+}
+
+// CapturePolicy overrides what HTTPRoundTripper captures for matching requests
+type CapturePolicy struct {
+	Skip                bool // record no integration at all
+	DisableBodies       bool // record method, URL, headers and status only
+	MaxRequestBodySize  int  // 0 keeps the config value
+	MaxResponseBodySize int  // 0 keeps the config value
+}
+
+type hostPolicy struct {
+	pattern string
+	policy  CapturePolicy
+}
+
+// RoundTripperOption is an option for HTTPRoundTripper
+type RoundTripperOption func(*HTTPRoundTripper)
+
+// WithStreamingCapture enables lazy response capture, keeping the read timing
+// of large or streamed downstream responses intact
+func WithStreamingCapture(enabled bool) RoundTripperOption {
+	return func(rt *HTTPRoundTripper) {
+		rt.StreamResponses = enabled
+	}
+}
+
+// WithHostPolicy sets the capture policy for requests matching pattern, see
+// gotrails.MatchURLPattern. The first matching policy wins.
+func WithHostPolicy(pattern string, policy CapturePolicy) RoundTripperOption {
+	return func(rt *HTTPRoundTripper) {
+		rt.policies = append(rt.policies, hostPolicy{pattern: pattern, policy: policy})
+	}
+}
+
+// policyFor returns the capture policy of the first pattern matching u
+func (rt *HTTPRoundTripper) policyFor(u *url.URL) CapturePolicy {
+	for _, p := range rt.policies {
+		if gotrails.MatchURLPattern(p.pattern, u) {
+			return p.policy
+		}
+	}
+	return CapturePolicy{}
 }
 
 func (rt *HTTPRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -22,41 +73,41 @@ func (rt *HTTPRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 		reqBody any
 	)
 
+	policy := rt.policyFor(req.URL)
+	if policy.Skip {
+		return rt.Base.RoundTrip(req)
+	}
+
 	cfg := gotrails.GetConfig(req.Context())
 	if cfg == nil {
 		cfg = gotrails.DefaultConfig()
 	}
 
-	hf := header.NewFilter(
-		header.WithExcludeHeaders(cfg.ExcludeHeaders),
-		header.WithMaskValue(cfg.MaskValue),
-	)
-	if cfg.IncludeHeaders != nil {
-		hf = header.NewFilter(
-			header.WithIncludeHeaders(cfg.IncludeHeaders),
-			header.WithExcludeHeaders(cfg.ExcludeHeaders),
-			header.WithMaskValue(cfg.MaskValue),
-		)
-	}
-
-	reqReader := body.NewReader(body.WithMaxSize(cfg.MaxRequestBodySize))
-	respReader := body.NewReader(body.WithMaxSize(cfg.MaxResponseBodySize))
-	msk := masker.New(
-		masker.WithFields(cfg.MaskFields),
-		masker.WithMaskValue(cfg.MaskValue),
-		masker.WithEnabled(cfg.EnableMasking),
-	)
+	hf := newHeaderFilter(cfg, gotrails.HeaderDirectionIntegration)
+
+	maxRequestSize := cmp.Or(policy.MaxRequestBodySize, cfg.MaxRequestBodySize)
+	maxResponseSize := cmp.Or(policy.MaxResponseBodySize, cfg.MaxResponseBodySize)
+	reqReader := body.NewReader(body.WithMaxSize(maxRequestSize))
+	respReader := body.NewReader(body.WithMaxSize(maxResponseSize))
+	msk := cfg.HostMasker(req.URL)
+	if msk == nil {
+		msk = cfg.Masker()
+	}
 
-	if req.Body != nil && req.ContentLength != 0 {
-		if bodyBytes, newBody, err := reqReader.ReadAndRestore(req.Body); err == nil {
+	metadataOnly := cfg.IsMetadataOnly()
+	captureBodies := !metadataOnly && !policy.DisableBodies
+
+	if captureBodies && req.Body != nil && req.ContentLength != 0 {
+		if bodyBytes, newBody, truncated, err := reqReader.ReadAndRestoreTruncated(req.Body); err == nil {
 			req.Body = newBody
-			reqBody = parseAndMaskJSON(msk, bodyBytes)
+			reqBody = capturedBody(cfg, msk, req.Header.Get("Content-Type"), bodyBytes, truncated)
 		}
 	}
 
-	start := time.Now()
+	clock := gotrails.ClockFromContext(req.Context())
+	start := clock.Now()
 	resp, err := rt.Base.RoundTrip(req)
-	latencyMs := time.Since(start).Milliseconds()
+	latencyMs := clock.Since(start).Milliseconds()
 
 	if trail := gotrails.GetTrail(req.Context()); trail != nil {
 		integration := gotrails.Integration{
@@ -65,19 +116,54 @@ func (rt *HTTPRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 			LatencyMs: latencyMs,
 			Request: map[string]any{
 				"method": req.Method,
-				"url":    req.URL.String(),
+				"url":    msk.MaskURL(req.URL),
 				"headers": func() map[string][]string {
 					return hf.Filter(req.Header)
 				}(),
 				"body": reqBody,
 			},
 		}
-		if resp != nil {
+		if metadataOnly {
+			u := *req.URL
+			u.User, u.RawQuery, u.Fragment, u.RawFragment = nil, "", "", ""
+			integration.Request = map[string]any{
+				"method":    req.Method,
+				"url":       u.String(),
+				"body_size": max(req.ContentLength, 0),
+			}
+			if resp != nil {
+				integration.Response = map[string]any{
+					"status":    resp.StatusCode,
+					"body_size": max(resp.ContentLength, 0),
+				}
+			}
+		} else if resp != nil && resp.Body != nil && captureBodies && rt.StreamResponses {
+			integration.Response = map[string]any{
+				"status":  resp.StatusCode,
+				"headers": hf.Filter(resp.Header),
+			}
+			applyRetryGroup(req.Context(), &integration)
+			pending := trail.AddPendingIntegration(integration)
+			contentType := resp.Header.Get("Content-Type")
+			resp.Body = newTeeBody(resp.Body, maxResponseSize, cfg.NewBodyDigest(), func(tb *teeBody) {
+				body := tb.capturedBody(cfg, msk, contentType)
+				digest := tb.bodyDigest()
+				pending.Complete(func(i *gotrails.Integration) {
+					response := i.Response.(map[string]any)
+					response["body"] = body
+					response["body_size"] = tb.size
+					if digest != "" {
+						response["body_digest"] = digest
+					}
+				})
+			})
+			return resp, err
+		} else if resp != nil {
 			var respBody any
-			if resp.Body != nil {
-				if bodyBytes, newBody, err := respReader.ReadAndRestore(resp.Body); err == nil {
+			if resp.Body != nil && captureBodies {
+				if bodyBytes, newBody, truncated, err := respReader.ReadAndRestoreTruncated(resp.Body); err == nil {
 					resp.Body = newBody
-					respBody = parseAndMaskJSON(msk, bodyBytes)
+					respBody = capturedBody(cfg, msk, resp.Header.Get("Content-Type"), bodyBytes, truncated)
 				}
 			}
 			integration.Response = map[string]any{
@@ -89,6 +175,7 @@ func (rt *HTTPRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 		if err != nil {
 			integration.Error = err.Error()
 		}
+		applyRetryGroup(req.Context(), &integration)
 		trail.AddIntegration(integration)
 	}
 
@@ -96,11 +183,51 @@ func (rt *HTTPRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 // NewHTTPRoundTripper returns a wrapped http.RoundTripper
-func NewHTTPRoundTripper(base http.RoundTripper) http.RoundTripper {
+func NewHTTPRoundTripper(base http.RoundTripper, opts ...RoundTripperOption) http.RoundTripper {
 	if base == nil {
 		base = http.DefaultTransport
 	}
-	return &HTTPRoundTripper{Base: base}
+	rt := &HTTPRoundTripper{Base: base}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	return rt
+}
+
+// parseAndMaskBody masks an XML body as a string, a form as a map of fields
+// and parses any other body as JSON, streaming large JSON bodies. XML that
+// cannot be masked is dropped rather than kept unmasked.
+func parseAndMaskBody(cfg *gotrails.Config, msk *masker.Masker, contentType string, data []byte) any {
+	if body.IsForm(contentType) {
+		return payload.Form(data, msk)
+	}
+	if len(data) == 0 || !body.IsXML(contentType) {
+		if cfg.EnableMasking && cfg.StreamsMasking(len(data)) {
+			if raw, err := payload.StreamJSON(data, msk); err == nil {
+				return raw
+			}
+		}
+		return parseAndMaskJSON(msk, data)
+	}
+	out, err := msk.MaskXML(data)
+	if err != nil {
+		return nil
+	}
+	return string(out)
+}
+
+// capturedBody returns a captured body masked, or a marker when it was cut at
+// the size limit and can therefore not be parsed and masked
+func capturedBody(cfg *gotrails.Config, msk *masker.Masker, contentType string, data []byte, truncated bool) any {
+	if truncated {
+		return truncatedBody(len(data))
+	}
+	return parseAndMaskBody(cfg, msk, contentType, data)
+}
+
+// truncatedBody is the marker recorded in place of a truncated body
+func truncatedBody(size int) map[string]any {
+	return map[string]any{"truncated": true, "size": size}
 }
 
 func parseAndMaskJSON(msk *masker.Masker, data []byte) any {
@@ -3,6 +3,9 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -37,7 +40,7 @@ func TestHTTPRoundTripperCapturesIntegration(t *testing.T) {
 
 	rt := NewHTTPRoundTripper(base)
 
-	req := httptest.NewRequest(http.MethodPost, "http://example.com/external/charge", bytes.NewBufferString(`{"token":"abc"}`))
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/external/charge?token=abc&currency=USD", bytes.NewBufferString(`{"token":"abc"}`))
 	req.Header.Set("Authorization", "Bearer abc")
 
 	ctx := gotrails.WithTrail(context.Background(), trail)
@@ -57,6 +60,9 @@ func TestHTTPRoundTripperCapturesIntegration(t *testing.T) {
 	if !ok {
 		t.Fatalf("expected request map, got %T", integration.Request)
 	}
+	if got := reqMap["url"]; got != "http://example.com/external/charge?token=%2A%2A%2AMASKED%2A%2A%2A&currency=USD" {
+		t.Fatalf("expected masked token query parameter, got %v", got)
+	}
 	reqBody := reqMap["body"].(map[string]any)
 	if reqBody["token"] != cfg.MaskValue {
 		t.Fatalf("expected masked token, got %v", reqBody["token"])
@@ -73,3 +79,169 @@ func TestHTTPRoundTripperCapturesIntegration(t *testing.T) {
 		t.Fatalf("expected response header X-Resp, got %s", got)
 	}
 }
+
+func TestHTTPRoundTripperGroupsRetries(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+
+	calls := 0
+	rt := NewHTTPRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		status := http.StatusServiceUnavailable
+		if calls == 3 {
+			status = http.StatusOK
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(bytes.NewBufferString(`{}`))}, nil
+	}))
+
+	ctx := WithRetryGroup(gotrails.WithTrail(context.Background(), trail))
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/flaky", nil).WithContext(ctx)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/other", nil)
+	_, _ = rt.RoundTrip(req.WithContext(gotrails.WithTrail(context.Background(), trail)))
+
+	if len(trail.Integrations) != 4 {
+		t.Fatalf("expected 4 integrations, got %d", len(trail.Integrations))
+	}
+	callID := trail.Integrations[0].CallID
+	for i, integration := range trail.Integrations[:3] {
+		if integration.CallID != callID || integration.Attempt != i+1 || integration.Final != (i == 2) {
+			t.Fatalf("unexpected attempt %d: %q %d %v", i, integration.CallID, integration.Attempt, integration.Final)
+		}
+	}
+	if callID == "" || trail.Integrations[3].CallID != "" {
+		t.Fatalf("expected only grouped calls to have a call id, got %+v", trail.Integrations[3])
+	}
+}
+
+func TestHTTPRoundTripperStreamingCapture(t *testing.T) {
+	cfg := gotrails.NewConfig(gotrails.WithBodyDigest(true), gotrails.WithHashAlgorithm(gotrails.HashSHA512))
+	cfg.MaxResponseBodySize = 4
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+
+	rt := NewHTTPRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{"password":"s3cr3t"}`))}, nil
+	}), WithStreamingCapture(true))
+
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/download", nil).WithContext(ctx)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(trail.Integrations) != 1 || trail.Integrations[0].Response.(map[string]any)["status"] != http.StatusOK {
+		t.Fatalf("expected the call recorded when it returns, got %+v", trail.Integrations)
+	}
+
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(data) != `{"password":"s3cr3t"}` {
+		t.Fatalf("expected caller to read the full body, got %q", data)
+	}
+
+	respMap := trail.Integrations[0].Response.(map[string]any)
+	body, _ := respMap["body"].(map[string]any)
+	if body["truncated"] != true || body["size"] != 4 || respMap["body_size"] != int64(21) {
+		t.Fatalf("expected a truncation marker, got %v %v", respMap["body"], respMap["body_size"])
+	}
+	sum := sha512.Sum512([]byte(`{"password":"s3cr3t"}`))
+	if respMap["body_digest"] != "sha512:"+hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected digest: %v", respMap["body_digest"])
+	}
+}
+
+func TestHTTPRoundTripperStreamingReadAfterRelease(t *testing.T) {
+	cfg := gotrails.NewConfig(gotrails.WithPooling(true))
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+
+	rt := NewHTTPRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{"ok":true}`))}, nil
+	}), WithStreamingCapture(true))
+
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/download", nil).WithContext(ctx)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	record := trail.Finalize()
+	trail.Release()
+	reused := gotrails.NewTrail("trace-2", "req-2", cfg)
+	_, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if len(record.Trail.Integrations) != 1 {
+		t.Fatalf("expected the call in the record, got %d integrations", len(record.Trail.Integrations))
+	}
+	if _, ok := record.Trail.Integrations[0].Response.(map[string]any)["body"]; ok {
+		t.Fatal("expected the record unchanged by a late read")
+	}
+	if len(reused.Integrations) != 0 || len(trail.Integrations) != 0 {
+		t.Fatalf("expected no late write to a released trail, got %+v", reused.Integrations)
+	}
+}
+
+func TestHTTPRoundTripperMarksTruncatedBodies(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	cfg.MaxRequestBodySize = 8
+	cfg.MaxResponseBodySize = 8
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+
+	rt := NewHTTPRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{"password":"s3cr3t"}`))}, nil
+	}))
+
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+	req := httptest.NewRequest(http.MethodPost, "http://example.com/login", bytes.NewBufferString(`{"password":"s3cr3t"}`)).WithContext(ctx)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := json.Marshal(trail.Integrations)
+	if bytes.Contains(data, []byte("s3cr3t")) || bytes.Contains(data, []byte("passw")) {
+		t.Fatalf("expected no unmasked bytes, got %s", data)
+	}
+	for _, m := range []any{trail.Integrations[0].Request, trail.Integrations[0].Response} {
+		if body, _ := m.(map[string]any)["body"].(map[string]any); body["truncated"] != true || body["size"] != 8 {
+			t.Fatalf("expected a truncation marker, got %v", m)
+		}
+	}
+}
+
+func TestHTTPRoundTripperHostPolicies(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	rt := NewHTTPRoundTripper(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{"ok":true}`))}, nil
+	}),
+		WithHostPolicy("metrics.internal", CapturePolicy{Skip: true}),
+		WithHostPolicy("*.storage.example.com", CapturePolicy{DisableBodies: true}),
+		WithHostPolicy("api.example.com/upload/*", CapturePolicy{MaxRequestBodySize: 4}),
+	)
+
+	send := func(url, body string) {
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(body)).WithContext(ctx)
+		if _, err := rt.RoundTrip(req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	send("http://metrics.internal/push", `{}`)
+	send("http://eu.storage.example.com/bucket/object", `{"file":"data"}`)
+	send("http://api.example.com/upload/avatar", `{"image":"base64"}`)
+
+	if len(trail.Integrations) != 2 {
+		t.Fatalf("expected skipped host to record nothing, got %d integrations", len(trail.Integrations))
+	}
+	storage := trail.Integrations[0]
+	if storage.Request.(map[string]any)["body"] != nil || storage.Response.(map[string]any)["body"] != nil {
+		t.Fatalf("expected no bodies for storage host, got %+v", storage)
+	}
+	if got, _ := trail.Integrations[1].Request.(map[string]any)["body"].(map[string]any); got["truncated"] != true || got["size"] != 4 {
+		t.Fatalf("expected upload body truncated to 4 bytes, got %v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package kafkago
+
+import (
+	"context"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaMessageWriter is implemented by *kafka.Writer
+type KafkaMessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// KafkaMessageReader is implemented by *kafka.Reader
+type KafkaMessageReader interface {
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+}
+
+// KafkaWriter wraps a kafka-go writer to capture produced messages as integrations
+type KafkaWriter struct {
+	Base  KafkaMessageWriter
+	Topic string // topic configured on the writer, used when messages don't set one
+}
+
+// NewKafkaWriter wraps a kafka-go Writer
+func NewKafkaWriter(w *kafka.Writer) *KafkaWriter {
+	return &KafkaWriter{Base: w, Topic: w.Topic}
+}
+
+// WriteMessages writes messages and records one integration per call
+func (w *KafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	start := time.Now()
+	err := w.Base.WriteMessages(ctx, msgs...)
+	latencyMs := time.Since(start).Milliseconds()
+
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return err
+	}
+
+	cfg := configFromContext(ctx)
+	topic := w.Topic
+	messages := make([]map[string]any, 0, len(msgs))
+	for _, msg := range msgs {
+		if msg.Topic != "" {
+			topic = msg.Topic
+		}
+		messages = append(messages, kafkaMessageFields(cfg, msg, false))
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeKafka,
+		Name:      topic,
+		LatencyMs: latencyMs,
+		Request: map[string]any{
+			"action":   "produce",
+			"topic":    topic,
+			"messages": messages,
+		},
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+
+	return err
+}
+
+// KafkaReader wraps a kafka-go reader to capture consumed messages as integrations
+type KafkaReader struct {
+	Base KafkaMessageReader
+}
+
+// NewKafkaReader wraps a kafka-go Reader
+func NewKafkaReader(r *kafka.Reader) *KafkaReader {
+	return &KafkaReader{Base: r}
+}
+
+// ReadMessage reads and commits the next message, recording it as an integration
+func (r *KafkaReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	start := time.Now()
+	msg, err := r.Base.ReadMessage(ctx)
+	recordKafkaConsume(ctx, msg, time.Since(start).Milliseconds(), err)
+	return msg, err
+}
+
+// FetchMessage reads the next message without committing it, recording it as an integration
+func (r *KafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	start := time.Now()
+	msg, err := r.Base.FetchMessage(ctx)
+	recordKafkaConsume(ctx, msg, time.Since(start).Milliseconds(), err)
+	return msg, err
+}
+
+// recordKafkaConsume adds a consume integration to the trail in context
+func recordKafkaConsume(ctx context.Context, msg kafka.Message, latencyMs int64, err error) {
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeKafka,
+		Name:      msg.Topic,
+		LatencyMs: latencyMs,
+		Request: map[string]any{
+			"action": "consume",
+			"topic":  msg.Topic,
+		},
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	} else {
+		integration.Response = kafkaMessageFields(configFromContext(ctx), msg, true)
+	}
+	trail.AddIntegration(integration)
+}
+
+// kafkaMessageFields describes a kafka-go message for an integration
+func kafkaMessageFields(cfg *gotrails.Config, msg kafka.Message, consumed bool) map[string]any {
+	fields := map[string]any{
+		"size": len(msg.Value),
+	}
+	if consumed {
+		fields["partition"] = msg.Partition
+		fields["offset"] = msg.Offset
+	}
+	if !cfg.IsMetadataOnly() {
+		msk := maskerFromConfig(cfg)
+		fields["key"] = payload.Key(msg.Key, msk)
+		fields["value"] = payload.JSON(msg.Value, msk, cfg.MaxRequestBodySize)
+	}
+	return fields
+}
+
+// configFromContext returns the config in context or the default config
+func configFromContext(ctx context.Context) *gotrails.Config {
+	if cfg := gotrails.GetConfig(ctx); cfg != nil {
+		return cfg
+	}
+	return gotrails.DefaultConfig()
+}
+
+// maskerFromConfig returns the shared masker of the config, nil if masking
+// is disabled
+func maskerFromConfig(cfg *gotrails.Config) *masker.Masker {
+	if !cfg.EnableMasking {
+		return nil
+	}
+	return cfg.Masker()
+}
@@ -0,0 +1,77 @@
+package kafkago
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/segmentio/kafka-go"
+)
+
+type fakeKafkaWriter struct{ err error }
+
+func (w *fakeKafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	return w.err
+}
+
+type fakeKafkaReader struct{ msg kafka.Message }
+
+func (r *fakeKafkaReader) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	return r.msg, nil
+}
+
+func (r *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	return r.msg, nil
+}
+
+func TestKafkaWriterRecordsProduce(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+
+	w := &KafkaWriter{Base: &fakeKafkaWriter{err: errors.New("broker down")}, Topic: "payment.created"}
+	err := w.WriteMessages(ctx, kafka.Message{Key: []byte("pay-123"), Value: []byte(`{"pin":"1234"}`)})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Name != "payment.created" || integration.Error != "broker down" {
+		t.Fatalf("unexpected integration: %+v", integration)
+	}
+	msgs := integration.Request.(map[string]any)["messages"].([]map[string]any)
+	if msgs[0]["key"] != cfg.MaskValue {
+		t.Fatalf("expected masked key, got %v", msgs[0]["key"])
+	}
+	if msgs[0]["value"].(map[string]any)["pin"] != cfg.MaskValue {
+		t.Fatalf("expected masked pin, got %v", msgs[0]["value"])
+	}
+}
+
+func TestKafkaReaderRecordsConsume(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	r := &KafkaReader{Base: &fakeKafkaReader{msg: kafka.Message{
+		Topic:     "payment.created",
+		Partition: 3,
+		Offset:    98123,
+		Key:       []byte("pay-123"),
+	}}}
+	if _, err := r.FetchMessage(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	resp := trail.Integrations[0].Response.(map[string]any)
+	if resp["partition"] != 3 || resp["offset"] != int64(98123) || resp["key"] != cfg.MaskValue {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+}
@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+// Email is a provider-agnostic outgoing email
+type Email struct {
+	From       string
+	To         []string
+	Cc         []string
+	Bcc        []string
+	Subject    string
+	TemplateID string // provider template, if any
+	Body       []byte // full message for SMTP, ignored by most API providers
+}
+
+// Mailer sends an email and returns the provider response (e.g. a message ID
+// or the SMTP server reply)
+type Mailer interface {
+	Send(ctx context.Context, email *Email) (string, error)
+}
+
+// IntegrationMailer wraps a Mailer to capture sent emails as integrations.
+// Recipients are recorded hashed, keeping only their domain readable.
+type IntegrationMailer struct {
+	Base     Mailer
+	Provider string // e.g. "smtp", "ses", "sendgrid"
+}
+
+// NewIntegrationMailer wraps a Mailer
+func NewIntegrationMailer(base Mailer, provider string) Mailer {
+	return &IntegrationMailer{Base: base, Provider: provider}
+}
+
+// Send sends the email and records it as an integration
+func (m *IntegrationMailer) Send(ctx context.Context, email *Email) (string, error) {
+	start := time.Now()
+	resp, err := m.Base.Send(ctx, email)
+	latencyMs := time.Since(start).Milliseconds()
+
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return resp, err
+	}
+
+	request := map[string]any{
+		"recipients_count": len(email.To) + len(email.Cc) + len(email.Bcc),
+	}
+	if !configFromContext(ctx).IsMetadataOnly() {
+		for field, addrs := range map[string][]string{"to": email.To, "cc": email.Cc, "bcc": email.Bcc} {
+			if len(addrs) > 0 {
+				request[field] = hashRecipients(addrs)
+			}
+		}
+		request["subject"] = email.Subject
+	}
+	if email.TemplateID != "" {
+		request["template_id"] = email.TemplateID
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeEmail,
+		Name:      m.Provider + ".send",
+		LatencyMs: latencyMs,
+		Request:   request,
+	}
+	if resp != "" {
+		integration.Response = map[string]any{"provider_response": resp}
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+
+	return resp, err
+}
+
+// hashRecipients replaces the local part of each address with a truncated
+// SHA-256 of the full lowercased address (e.g. "3c9a1f0e2b7d4a65@example.com")
+func hashRecipients(addrs []string) []string {
+	hashed := make([]string, len(addrs))
+	for i, addr := range addrs {
+		addr = strings.ToLower(strings.TrimSpace(addr))
+		sum := sha256.Sum256([]byte(addr))
+		hashed[i] = hex.EncodeToString(sum[:8])
+		if at := strings.LastIndex(addr, "@"); at >= 0 {
+			hashed[i] += addr[at:]
+		}
+	}
+	return hashed
+}
+
+// SMTPMailer is a Mailer sending through net/smtp
+type SMTPMailer struct {
+	Addr string
+	Auth smtp.Auth
+}
+
+// NewSMTPMailer creates a Mailer sending through the SMTP server at addr
+func NewSMTPMailer(addr string, auth smtp.Auth) *SMTPMailer {
+	return &SMTPMailer{Addr: addr, Auth: auth}
+}
+
+// Send sends the email with smtp.SendMail. The body must be a complete RFC 822
+// message; when empty, a minimal message with the subject is sent.
+func (m *SMTPMailer) Send(ctx context.Context, email *Email) (string, error) {
+	rcpts := make([]string, 0, len(email.To)+len(email.Cc)+len(email.Bcc))
+	rcpts = append(rcpts, email.To...)
+	rcpts = append(rcpts, email.Cc...)
+	rcpts = append(rcpts, email.Bcc...)
+
+	msg := email.Body
+	if len(msg) == 0 {
+		var b bytes.Buffer
+		b.WriteString("From: " + email.From + "\r\n")
+		b.WriteString("To: " + strings.Join(email.To, ", ") + "\r\n")
+		b.WriteString("Subject: " + email.Subject + "\r\n\r\n")
+		msg = b.Bytes()
+	}
+
+	// net/smtp does not expose the server reply, so there is no provider response
+	return "", smtp.SendMail(m.Addr, m.Auth, email.From, rcpts, msg)
+}
@@ -0,0 +1,71 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+type mailerFunc func(ctx context.Context, email *Email) (string, error)
+
+func (f mailerFunc) Send(ctx context.Context, email *Email) (string, error) {
+	return f(ctx, email)
+}
+
+func TestIntegrationMailerRecordsEmail(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	mailer := NewIntegrationMailer(mailerFunc(func(ctx context.Context, email *Email) (string, error) {
+		return "msg-123", nil
+	}), "ses")
+
+	_, err := mailer.Send(ctx, &Email{
+		To:         []string{"Jane.Doe@example.com"},
+		Bcc:        []string{"audit@corp.example"},
+		Subject:    "Your receipt",
+		TemplateID: "receipt-v2",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeEmail || integration.Name != "ses.send" {
+		t.Fatalf("unexpected integration: %s %s", integration.Type, integration.Name)
+	}
+	req := integration.Request.(map[string]any)
+	to := req["to"].([]string)
+	if len(to) != 1 || !strings.HasSuffix(to[0], "@example.com") || strings.Contains(to[0], "jane") {
+		t.Fatalf("expected hashed recipient, got %v", to)
+	}
+	if _, ok := req["cc"]; ok {
+		t.Fatal("expected no cc field without cc recipients")
+	}
+	if req["recipients_count"] != 2 || req["template_id"] != "receipt-v2" || req["subject"] != "Your receipt" {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	if integration.Response.(map[string]any)["provider_response"] != "msg-123" {
+		t.Fatalf("unexpected response: %v", integration.Response)
+	}
+}
+
+func TestIntegrationMailerRecordsError(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	mailer := NewIntegrationMailer(mailerFunc(func(ctx context.Context, email *Email) (string, error) {
+		return "", errors.New("554 rejected")
+	}), "smtp")
+	_, _ = mailer.Send(ctx, &Email{To: []string{"a@b.c"}})
+
+	if trail.Integrations[0].Error != "554 rejected" || trail.Integrations[0].Response != nil {
+		t.Fatalf("unexpected integration: %+v", trail.Integrations[0])
+	}
+}
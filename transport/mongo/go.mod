@@ -0,0 +1,18 @@
+module github.com/aizacoders/gotrails/transport/mongo
+
+go 1.24.0
+
+require (
+	github.com/aizacoders/gotrails v0.0.0
+	go.mongodb.org/mongo-driver/v2 v2.3.0
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	go.opentelemetry.io/otel v1.39.0 // indirect
+	go.opentelemetry.io/otel/trace v1.39.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/aizacoders/gotrails => ../..
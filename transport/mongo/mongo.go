@@ -0,0 +1,94 @@
+package mongo
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// mongoCommandKey identifies an in-flight command on a connection
+type mongoCommandKey struct {
+	connectionID string
+	requestID    int64
+}
+
+// mongoCommand is the command state kept between started and finished events
+type mongoCommand struct {
+	collection string
+}
+
+// NewMongoMonitor creates an event.CommandMonitor recording commands as
+// database integrations on the trail in context. Only the command name,
+// database and collection are captured, documents are never recorded.
+//
+//	client, _ := mongo.Connect(options.Client().ApplyURI(uri).SetMonitor(transport.NewMongoMonitor()))
+func NewMongoMonitor() *event.CommandMonitor {
+	var pending sync.Map
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			if gotrails.GetTrail(ctx) == nil {
+				return
+			}
+			key := mongoCommandKey{connectionID: evt.ConnectionID, requestID: evt.RequestID}
+			pending.Store(key, &mongoCommand{collection: mongoCollection(evt)})
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			recordMongo(ctx, &pending, evt.CommandFinishedEvent, nil)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			recordMongo(ctx, &pending, evt.CommandFinishedEvent, evt.Failure)
+		},
+	}
+}
+
+func recordMongo(ctx context.Context, pending *sync.Map, evt event.CommandFinishedEvent, err error) {
+	v, ok := pending.LoadAndDelete(mongoCommandKey{connectionID: evt.ConnectionID, requestID: evt.RequestID})
+	if !ok {
+		return
+	}
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return
+	}
+	cmd := v.(*mongoCommand)
+
+	request := map[string]any{
+		"command":  evt.CommandName,
+		"database": evt.DatabaseName,
+	}
+	if cmd.collection != "" {
+		request["collection"] = cmd.collection
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeDatabase,
+		Name:      "mongodb." + strings.ToLower(evt.CommandName),
+		LatencyMs: evt.Duration.Milliseconds(),
+		Request:   request,
+		Metadata:  map[string]any{"connection_id": evt.ConnectionID},
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+}
+
+// mongoCollection returns the collection a command targets. Most commands
+// carry it as the value of their first element (e.g. {"find": "users"}),
+// getMore carries it in a separate "collection" field.
+func mongoCollection(evt *event.CommandStartedEvent) string {
+	if evt.CommandName == "getMore" {
+		coll, _ := evt.Command.Lookup("collection").StringValueOK()
+		return coll
+	}
+	elem, err := evt.Command.IndexErr(0)
+	if err != nil {
+		return ""
+	}
+	coll, _ := elem.Value().StringValueOK()
+	return coll
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+func TestMongoMonitorRecordsCommand(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	cmd, _ := bson.Marshal(bson.D{{Key: "find", Value: "users"}, {Key: "filter", Value: bson.D{{Key: "email", Value: "a@b.c"}}}})
+	monitor := NewMongoMonitor()
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command:      cmd,
+		DatabaseName: "app",
+		CommandName:  "find",
+		RequestID:    7,
+		ConnectionID: "conn-1",
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{
+			Duration:     5 * time.Millisecond,
+			CommandName:  "find",
+			DatabaseName: "app",
+			RequestID:    7,
+			ConnectionID: "conn-1",
+		},
+		Failure: errors.New("connection reset"),
+	})
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeDatabase || integration.Name != "mongodb.find" {
+		t.Fatalf("unexpected integration: %s %s", integration.Type, integration.Name)
+	}
+	req := integration.Request.(map[string]any)
+	if req["collection"] != "users" || req["database"] != "app" {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	if _, ok := req["filter"]; ok {
+		t.Fatal("expected filter document not to be captured")
+	}
+	if integration.LatencyMs != 5 || integration.Error != "connection reset" {
+		t.Fatalf("unexpected latency or error: %d %q", integration.LatencyMs, integration.Error)
+	}
+}
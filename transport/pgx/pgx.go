@@ -0,0 +1,125 @@
+package pgx
+
+import (
+	"context"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/sqlquery"
+	"github.com/aizacoders/gotrails/transport"
+	"github.com/jackc/pgx/v5"
+)
+
+type pgxQueryKey struct{}
+
+// pgxQuery is the query state carried in context between start and end
+type pgxQuery struct {
+	start    time.Time
+	sql      string
+	argCount int
+}
+
+// PgxTracer implements pgx.QueryTracer and pgx.BatchTracer, recording queries
+// and batches as database integrations on the trail in context.
+// Set it as ConnConfig.Tracer (or pgxpool.Config.ConnConfig.Tracer).
+type PgxTracer struct {
+	opts *sqlquery.Options
+}
+
+// NewPgxTracer creates a new PgxTracer, taking the options of transport.WrapDriver
+func NewPgxTracer(opts ...transport.SQLOption) *PgxTracer {
+	o := &sqlquery.Options{System: "postgres"}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &PgxTracer{opts: o}
+}
+
+// TraceQueryStart records the start of a query
+func (t *PgxTracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, pgxQueryKey{}, &pgxQuery{
+		start:    time.Now(),
+		sql:      data.SQL,
+		argCount: len(data.Args),
+	})
+}
+
+// TraceQueryEnd records the query as an integration
+func (t *PgxTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(pgxQueryKey{}).(*pgxQuery)
+	if !ok {
+		return
+	}
+	t.record(ctx, conn, "query", q.sql, q.argCount, time.Since(q.start), data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchStart records the start of a batch
+func (t *PgxTracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return context.WithValue(ctx, pgxQueryKey{}, &pgxQuery{start: time.Now(), sql: "BATCH"})
+}
+
+// TraceBatchQuery records a single query of a batch as an integration
+func (t *PgxTracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	t.record(ctx, conn, "batch_query", data.SQL, len(data.Args), 0, data.CommandTag.RowsAffected(), data.Err)
+}
+
+// TraceBatchEnd records the batch as an integration
+func (t *PgxTracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	q, ok := ctx.Value(pgxQueryKey{}).(*pgxQuery)
+	if !ok {
+		return
+	}
+	t.record(ctx, conn, "batch", q.sql, -1, time.Since(q.start), -1, data.Err)
+}
+
+func (t *PgxTracer) record(ctx context.Context, conn *pgx.Conn, action, query string, argCount int, latency time.Duration, rowsAffected int64, err error) {
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return
+	}
+	cfg := configFromContext(ctx)
+
+	if t.opts.ScrubLiterals || cfg.IsMetadataOnly() {
+		query = sqlquery.Scrub(query)
+	}
+
+	request := map[string]any{
+		"action": action,
+		"query":  query,
+	}
+	if argCount >= 0 {
+		request["args_count"] = argCount
+	}
+
+	metadata := map[string]any{}
+	if conn != nil {
+		if cc := conn.Config(); cc != nil {
+			metadata["host"] = cc.Host
+			metadata["port"] = cc.Port
+			metadata["database"] = cc.Database
+		}
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeDatabase,
+		Name:      t.opts.System + "." + sqlquery.Operation(query, action),
+		LatencyMs: latency.Milliseconds(),
+		Request:   request,
+		Metadata:  metadata,
+	}
+	if rowsAffected >= 0 && err == nil {
+		integration.Response = map[string]any{"rows_affected": rowsAffected}
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+}
+
+// configFromContext returns the config in context or the default config
+func configFromContext(ctx context.Context) *gotrails.Config {
+	if cfg := gotrails.GetConfig(ctx); cfg != nil {
+		return cfg
+	}
+	return gotrails.DefaultConfig()
+}
@@ -0,0 +1,37 @@
+package pgx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestPgxTracerRecordsQuery(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	tracer := NewPgxTracer()
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+		SQL:  "UPDATE payments SET status = $1 WHERE id = $2",
+		Args: []any{"PAID", "pay-123"},
+	})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 3")})
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Name != "postgres.update" {
+		t.Fatalf("unexpected integration name: %s", integration.Name)
+	}
+	req := integration.Request.(map[string]any)
+	if req["query"] != "UPDATE payments SET status = $1 WHERE id = $2" || req["args_count"] != 2 {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	if integration.Response.(map[string]any)["rows_affected"] != int64(3) {
+		t.Fatalf("unexpected response: %v", integration.Response)
+	}
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisHook implements redis.Hook, recording commands and pipelines as cache
+// integrations on the trail in context. Only the command name and key prefix
+// are captured, values are never recorded.
+//
+//	rdb.AddHook(transport.NewRedisHook())
+type RedisHook struct{}
+
+// NewRedisHook creates a new RedisHook
+func NewRedisHook() *RedisHook {
+	return &RedisHook{}
+}
+
+// DialHook passes dials through unchanged
+func (h *RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook records a single command as an integration
+func (h *RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.record(ctx, cmd.Name(), []redis.Cmder{cmd}, time.Since(start), err)
+		return err
+	}
+}
+
+// ProcessPipelineHook records a pipeline (or transaction) as one integration
+func (h *RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.record(ctx, "pipeline", cmds, time.Since(start), err)
+		return err
+	}
+}
+
+func (h *RedisHook) record(ctx context.Context, name string, cmds []redis.Cmder, latency time.Duration, err error) {
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return
+	}
+	metadataOnly := configFromContext(ctx).IsMetadataOnly()
+
+	commands := make([]map[string]any, 0, len(cmds))
+	for _, cmd := range cmds {
+		c := map[string]any{
+			"command":    cmd.FullName(),
+			"args_count": len(cmd.Args()),
+		}
+		if key := redisKey(cmd); key != "" && !metadataOnly {
+			c["key_prefix"] = redisKeyPrefix(key)
+		}
+		commands = append(commands, c)
+	}
+
+	var request any = commands
+	if len(commands) == 1 {
+		request = commands[0]
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeCache,
+		Name:      "redis." + strings.ToLower(name),
+		LatencyMs: latency.Milliseconds(),
+		Request:   request,
+	}
+	if errors.Is(err, redis.Nil) {
+		integration.Response = map[string]any{"hit": false}
+	} else if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+}
+
+// redisKey returns the argument following the command name, usually the key
+func redisKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return ""
+	}
+	return fmt.Sprint(args[1])
+}
+
+// redisKeyPrefix keeps a key up to its last ':' separator, masking the rest
+// (e.g. "session:user:42" becomes "session:user:*"). Keys without a
+// separator are fully masked.
+func redisKeyPrefix(key string) string {
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		return key[:i+1] + "*"
+	}
+	return "*"
+}
+
+// configFromContext returns the config in context or the default config
+func configFromContext(ctx context.Context) *gotrails.Config {
+	if cfg := gotrails.GetConfig(ctx); cfg != nil {
+		return cfg
+	}
+	return gotrails.DefaultConfig()
+}
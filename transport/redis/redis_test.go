@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisHookRecordsCommand(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	hook := NewRedisHook()
+	process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		return redis.Nil
+	})
+	_ = process(ctx, redis.NewStringCmd(ctx, "get", "session:user:42"))
+
+	if len(trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(trail.Integrations))
+	}
+	integration := trail.Integrations[0]
+	if integration.Type != gotrails.IntegrationTypeCache || integration.Name != "redis.get" {
+		t.Fatalf("unexpected integration: %s %s", integration.Type, integration.Name)
+	}
+	req := integration.Request.(map[string]any)
+	if req["key_prefix"] != "session:user:*" {
+		t.Fatalf("expected masked key prefix, got %v", req["key_prefix"])
+	}
+	if integration.Error != "" || integration.Response.(map[string]any)["hit"] != false {
+		t.Fatalf("expected cache miss without error, got %v %q", integration.Response, integration.Error)
+	}
+}
+
+func TestRedisHookRecordsPipeline(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	process := NewRedisHook().ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+		return nil
+	})
+	_ = process(ctx, []redis.Cmder{
+		redis.NewStatusCmd(ctx, "set", "token:abc", "secret-value"),
+		redis.NewIntCmd(ctx, "incr", "counter"),
+	})
+
+	if len(trail.Integrations) != 1 || trail.Integrations[0].Name != "redis.pipeline" {
+		t.Fatalf("expected one pipeline integration, got %+v", trail.Integrations)
+	}
+	cmds := trail.Integrations[0].Request.([]map[string]any)
+	if len(cmds) != 2 || cmds[0]["key_prefix"] != "token:*" || cmds[1]["key_prefix"] != "*" {
+		t.Fatalf("unexpected commands: %v", cmds)
+	}
+}
@@ -0,0 +1,40 @@
+package transport
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+type retryGroupKey struct{}
+
+// retryGroup counts the attempts of one logical call
+type retryGroup struct {
+	callID   string
+	attempts atomic.Int32
+}
+
+// WithRetryGroup marks ctx as one logical call. Every integration recorded by
+// HTTPRoundTripper with the returned context (or a context derived from it)
+// shares a call ID and gets an increasing attempt number, and only the last
+// attempt is flagged as final. Use it around go-retryablehttp requests or
+// manual retry loops:
+//
+//	ctx = transport.WithRetryGroup(ctx)
+//	req, _ := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, url, body)
+func WithRetryGroup(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryGroupKey{}, &retryGroup{callID: gotrails.GenerateRequestID()})
+}
+
+// applyRetryGroup sets the call ID, attempt number and final flag on the
+// integration when ctx carries a retry group
+func applyRetryGroup(ctx context.Context, integration *gotrails.Integration) {
+	group, ok := ctx.Value(retryGroupKey{}).(*retryGroup)
+	if !ok {
+		return
+	}
+	integration.CallID = group.callID
+	integration.Attempt = int(group.attempts.Add(1))
+	integration.Final = true
+}
@@ -0,0 +1,198 @@
+package sarama
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/masker"
+	"github.com/aizacoders/gotrails/payload"
+	"github.com/aizacoders/gotrails/sink"
+)
+
+// SaramaProducerInterceptor is a sarama.ProducerInterceptor that records produced
+// messages as Kafka integrations and propagates trace IDs via record headers.
+// Sarama does not pass a context to interceptors, so the request context must be
+// attached to each message with WithSaramaContext.
+type SaramaProducerInterceptor struct{}
+
+// NewSaramaProducerInterceptor creates a new SaramaProducerInterceptor
+func NewSaramaProducerInterceptor() *SaramaProducerInterceptor {
+	return &SaramaProducerInterceptor{}
+}
+
+// saramaCarrier is the message Metadata set by WithSaramaContext
+type saramaCarrier struct {
+	ctx       context.Context // carries the child trail
+	done      func()
+	traceID   string // of the request trail
+	requestID string
+	metadata  any // of the caller
+}
+
+// WithSaramaContext attaches ctx to the message so the interceptor can find
+// the trail. The produce is recorded on a child trail of the request trail,
+// flushed when the interceptor has run, as the request trail may be finalized
+// by then. Any Metadata already set is restored by the interceptor, so the
+// producer results carry it as usual.
+func WithSaramaContext(ctx context.Context, msg *sarama.ProducerMessage) *sarama.ProducerMessage {
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return msg
+	}
+	detached, done := gotrails.DetachTrail(ctx)
+	msg.Metadata = &saramaCarrier{
+		ctx:       detached,
+		done:      done,
+		traceID:   trail.TraceID,
+		requestID: trail.RequestID,
+		metadata:  msg.Metadata,
+	}
+	return msg
+}
+
+// OnSend records the message on the trail and adds trace headers
+func (i *SaramaProducerInterceptor) OnSend(msg *sarama.ProducerMessage) {
+	carrier, ok := msg.Metadata.(*saramaCarrier)
+	if !ok {
+		return
+	}
+	msg.Metadata = carrier.metadata
+	defer carrier.done()
+
+	ctx := carrier.ctx
+	trail := gotrails.GetTrail(ctx)
+	cfg := configFromContext(ctx)
+
+	setSaramaHeader(msg, cfg.TraceIDHeader, carrier.traceID)
+	setSaramaHeader(msg, cfg.RequestIDHeader, carrier.requestID)
+
+	request := map[string]any{
+		"action": "produce",
+		"topic":  msg.Topic,
+	}
+	if msg.Value != nil {
+		request["size"] = msg.Value.Length()
+	}
+	if !cfg.IsMetadataOnly() {
+		msk := maskerFromConfig(cfg)
+		if key := encodeSarama(msg.Key); key != nil {
+			request["key"] = payload.Key(key, msk)
+		}
+		request["value"] = payload.JSON(encodeSarama(msg.Value), msk, cfg.MaxRequestBodySize)
+	}
+
+	trail.AddIntegration(gotrails.Integration{
+		Type:    gotrails.IntegrationTypeKafka,
+		Name:    msg.Topic,
+		Request: request,
+	})
+}
+
+// SaramaConsumerInterceptor is a sarama.ConsumerInterceptor that creates a trail
+// per consumed message, continuing the trace ID carried in the record headers,
+// and writes it to the sink. Use consumer.WrapHandler instead when the handler
+// itself should be part of the trail.
+type SaramaConsumerInterceptor struct {
+	cfg  *gotrails.Config
+	sink sink.Sink
+}
+
+// NewSaramaConsumerInterceptor creates a new SaramaConsumerInterceptor. It
+// panics if the config is invalid, see gotrails.Config.Validate.
+func NewSaramaConsumerInterceptor(cfg *gotrails.Config, s sink.Sink) *SaramaConsumerInterceptor {
+	if cfg == nil {
+		cfg = gotrails.DefaultConfig()
+	}
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	return &SaramaConsumerInterceptor{cfg: cfg, sink: s}
+}
+
+// OnConsume records the consumed message as a Kafka integration on a new trail
+func (i *SaramaConsumerInterceptor) OnConsume(msg *sarama.ConsumerMessage) {
+	headers := make(http.Header, len(msg.Headers))
+	for _, h := range msg.Headers {
+		if h != nil {
+			headers.Add(string(h.Key), string(h.Value))
+		}
+	}
+
+	traceID := gotrails.ExtractTraceIDFromHeader(headers, i.cfg)
+	requestID := gotrails.ExtractRequestIDFromHeader(headers, i.cfg)
+	trail := gotrails.NewTrail(traceID, requestID, i.cfg)
+	if trail == nil {
+		return
+	}
+
+	response := map[string]any{
+		"partition": msg.Partition,
+		"offset":    msg.Offset,
+		"size":      len(msg.Value),
+	}
+	if !i.cfg.IsMetadataOnly() {
+		msk := maskerFromConfig(i.cfg)
+		response["key"] = payload.Key(msg.Key, msk)
+		response["value"] = payload.JSON(msg.Value, msk, i.cfg.MaxRequestBodySize)
+	}
+
+	trail.AddIntegration(gotrails.Integration{
+		Type: gotrails.IntegrationTypeKafka,
+		Name: msg.Topic,
+		Request: map[string]any{
+			"action": "consume",
+			"topic":  msg.Topic,
+		},
+		Response: response,
+	})
+
+	if record := trail.Finalize(); trail.Sampled() {
+		_ = i.sink.Write(context.Background(), record)
+	}
+	trail.Release()
+}
+
+// setSaramaHeader sets a record header unless it is already present
+func setSaramaHeader(msg *sarama.ProducerMessage, key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	for _, h := range msg.Headers {
+		if strings.EqualFold(string(h.Key), key) {
+			return
+		}
+	}
+	msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// encodeSarama encodes a sarama key or value, nil if absent or not encodable
+func encodeSarama(e sarama.Encoder) []byte {
+	if e == nil {
+		return nil
+	}
+	b, err := e.Encode()
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+// configFromContext returns the config in context or the default config
+func configFromContext(ctx context.Context) *gotrails.Config {
+	if cfg := gotrails.GetConfig(ctx); cfg != nil {
+		return cfg
+	}
+	return gotrails.DefaultConfig()
+}
+
+// maskerFromConfig returns the shared masker of the config, nil if masking
+// is disabled
+func maskerFromConfig(cfg *gotrails.Config) *masker.Masker {
+	if !cfg.EnableMasking {
+		return nil
+	}
+	return cfg.Masker()
+}
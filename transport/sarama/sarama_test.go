@@ -0,0 +1,105 @@
+package sarama
+
+import (
+	"context"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/sink"
+)
+
+type lastTrailSink struct {
+	sink.NoopSink
+	trail *gotrails.Trail
+}
+
+func (s *lastTrailSink) Write(ctx context.Context, record *gotrails.TrailRecord) error {
+	s.trail = record.Trail
+	return nil
+}
+
+func TestSaramaProducerInterceptorPropagatesTrace(t *testing.T) {
+	cfg := gotrails.NewConfig()
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	s := &lastTrailSink{}
+	ctx := gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg)
+	ctx = gotrails.WithFlush(ctx, s.Write)
+
+	msg := WithSaramaContext(ctx, &sarama.ProducerMessage{
+		Topic: "payment.created",
+		Key:   sarama.StringEncoder("pay-123"),
+		Value: sarama.StringEncoder(`{"secret":"s3cr3t"}`),
+	})
+	NewSaramaProducerInterceptor().OnSend(msg)
+
+	headers := map[string]string{}
+	for _, h := range msg.Headers {
+		headers[string(h.Key)] = string(h.Value)
+	}
+	if headers[cfg.TraceIDHeader] != "trace-1" || headers[cfg.RequestIDHeader] != "req-1" {
+		t.Fatalf("expected trace headers, got %v", headers)
+	}
+
+	if s.trail == nil || s.trail.ParentTraceID != "trace-1" {
+		t.Fatalf("expected a flushed child trail, got %+v", s.trail)
+	}
+	if len(s.trail.Integrations) != 1 {
+		t.Fatalf("expected 1 integration, got %d", len(s.trail.Integrations))
+	}
+	req := s.trail.Integrations[0].Request.(map[string]any)
+	if req["key"] != cfg.MaskValue || req["value"].(map[string]any)["secret"] != cfg.MaskValue {
+		t.Fatalf("unexpected request: %v", req)
+	}
+}
+
+func TestSaramaProducerInterceptorRestoresMetadata(t *testing.T) {
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	msg := WithSaramaContext(ctx, &sarama.ProducerMessage{Topic: "payment.created", Metadata: "pay-123"})
+	NewSaramaProducerInterceptor().OnSend(msg)
+
+	if msg.Metadata != "pay-123" {
+		t.Fatalf("expected the caller's metadata, got %v", msg.Metadata)
+	}
+}
+
+func TestSaramaProducerInterceptorAfterRequestFinalized(t *testing.T) {
+	cfg := gotrails.NewConfig(gotrails.WithImmutable(true))
+	trail := gotrails.NewTrail("trace-1", "req-1", cfg)
+	s := &lastTrailSink{}
+	ctx := gotrails.WithFlush(gotrails.WithConfig(gotrails.WithTrail(context.Background(), trail), cfg), s.Write)
+
+	msg := WithSaramaContext(ctx, &sarama.ProducerMessage{Topic: "payment.created"})
+	trail.Finalize()
+	NewSaramaProducerInterceptor().OnSend(msg)
+
+	if len(trail.Integrations) != 0 {
+		t.Fatalf("expected the finalized trail untouched, got %v", trail.Integrations)
+	}
+	if s.trail == nil || len(s.trail.Integrations) != 1 {
+		t.Fatalf("expected the produce on the child trail, got %+v", s.trail)
+	}
+}
+
+func TestSaramaConsumerInterceptorContinuesTrace(t *testing.T) {
+	s := &lastTrailSink{}
+	NewSaramaConsumerInterceptor(gotrails.NewConfig(), s).OnConsume(&sarama.ConsumerMessage{
+		Topic:     "payment.created",
+		Partition: 1,
+		Offset:    42,
+		Headers:   []*sarama.RecordHeader{{Key: []byte("X-Trace-ID"), Value: []byte("trace-1")}},
+	})
+
+	if s.trail == nil {
+		t.Fatal("expected trail in sink")
+	}
+	if s.trail.TraceID != "trace-1" {
+		t.Fatalf("expected trace id from headers, got %s", s.trail.TraceID)
+	}
+	resp := s.trail.Integrations[0].Response.(map[string]any)
+	if resp["partition"] != int32(1) || resp["offset"] != int64(42) {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+}
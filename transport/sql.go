@@ -0,0 +1,338 @@
+package transport
+
+import (
+	"context"
+	"database/sql/driver"
+	"time"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/internal/sqlquery"
+)
+
+// SQLOption is an option for the database/sql instrumentation
+type SQLOption func(*sqlquery.Options)
+
+// WithSQLSystem sets the database system name used as integration name prefix (e.g. "postgres")
+func WithSQLSystem(name string) SQLOption {
+	return func(o *sqlquery.Options) {
+		o.System = name
+	}
+}
+
+// WithSQLScrubLiterals replaces string and numeric literals in captured queries with ?
+func WithSQLScrubLiterals(enabled bool) SQLOption {
+	return func(o *sqlquery.Options) {
+		o.ScrubLiterals = enabled
+	}
+}
+
+func newSQLOptions(opts []SQLOption) *sqlquery.Options {
+	o := &sqlquery.Options{System: "sql"}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WrapDriver wraps a database/sql driver so queries, statements and
+// transactions are recorded as database integrations on the trail in context.
+// Register the result with sql.Register or open it through sql.OpenDB with WrapConnector.
+func WrapDriver(d driver.Driver, opts ...SQLOption) driver.Driver {
+	return &sqlDriver{Driver: d, opts: newSQLOptions(opts)}
+}
+
+// WrapConnector wraps a driver.Connector for use with sql.OpenDB
+func WrapConnector(c driver.Connector, opts ...SQLOption) driver.Connector {
+	return &sqlConnector{Connector: c, opts: newSQLOptions(opts)}
+}
+
+type sqlDriver struct {
+	driver.Driver
+	opts *sqlquery.Options
+}
+
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, opts: d.opts}, nil
+}
+
+// OpenConnector implements driver.DriverContext when the wrapped driver does
+func (d *sqlDriver) OpenConnector(name string) (driver.Connector, error) {
+	dc, ok := d.Driver.(driver.DriverContext)
+	if !ok {
+		return &dsnConnector{name: name, driver: d}, nil
+	}
+	c, err := dc.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConnector{Connector: c, opts: d.opts, driver: d}, nil
+}
+
+type sqlConnector struct {
+	driver.Connector
+	opts   *sqlquery.Options
+	driver driver.Driver
+}
+
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn{Conn: conn, opts: c.opts}, nil
+}
+
+func (c *sqlConnector) Driver() driver.Driver {
+	if c.driver != nil {
+		return c.driver
+	}
+	return &sqlDriver{Driver: c.Connector.Driver(), opts: c.opts}
+}
+
+// dsnConnector is a connector for drivers without driver.DriverContext
+type dsnConnector struct {
+	name   string
+	driver *sqlDriver
+}
+
+func (c *dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type sqlConn struct {
+	driver.Conn
+	opts *sqlquery.Options
+}
+
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	recordSQL(ctx, c.opts, "prepare", query, -1, nil, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStmt{Stmt: stmt, opts: c.opts, query: query}, nil
+}
+
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var (
+		tx  driver.Tx
+		err error
+	)
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = bt.BeginTx(ctx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	recordSQL(ctx, c.opts, "begin", "BEGIN", -1, nil, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, ctx: ctx, opts: c.opts}, nil
+}
+
+func (c *sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	recordSQL(ctx, c.opts, "exec", query, len(args), res, time.Since(start), err)
+	return res, err
+}
+
+func (c *sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	recordSQL(ctx, c.opts, "query", query, len(args), nil, time.Since(start), err)
+	return rows, err
+}
+
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *sqlConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *sqlConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlStmt struct {
+	driver.Stmt
+	opts  *sqlquery.Options
+	query string
+}
+
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), toNamedValues(args))
+}
+
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), toNamedValues(args))
+}
+
+func (s *sqlStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var (
+		res driver.Result
+		err error
+	)
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(toValues(args))
+	}
+	recordSQL(ctx, s.opts, "exec", s.query, len(args), res, time.Since(start), err)
+	return res, err
+}
+
+func (s *sqlStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var (
+		rows driver.Rows
+		err  error
+	)
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(toValues(args))
+	}
+	recordSQL(ctx, s.opts, "query", s.query, len(args), nil, time.Since(start), err)
+	return rows, err
+}
+
+func (s *sqlStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nvc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nvc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type sqlTx struct {
+	driver.Tx
+	ctx  context.Context
+	opts *sqlquery.Options
+}
+
+func (t *sqlTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	recordSQL(t.ctx, t.opts, "commit", "COMMIT", -1, nil, time.Since(start), err)
+	return err
+}
+
+func (t *sqlTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	recordSQL(t.ctx, t.opts, "rollback", "ROLLBACK", -1, nil, time.Since(start), err)
+	return err
+}
+
+// recordSQL adds a database integration to the trail in context.
+// argCount < 0 means the operation takes no arguments.
+func recordSQL(ctx context.Context, opts *sqlquery.Options, action, query string, argCount int, res driver.Result, latency time.Duration, err error) {
+	trail := gotrails.GetTrail(ctx)
+	if trail == nil {
+		return
+	}
+	cfg := configFromContext(ctx)
+
+	if opts.ScrubLiterals || cfg.IsMetadataOnly() {
+		query = sqlquery.Scrub(query)
+	}
+
+	request := map[string]any{
+		"action": action,
+		"query":  query,
+	}
+	if argCount >= 0 {
+		request["args_count"] = argCount
+	}
+
+	integration := gotrails.Integration{
+		Type:      gotrails.IntegrationTypeDatabase,
+		Name:      opts.System + "." + sqlquery.Operation(query, action),
+		LatencyMs: latency.Milliseconds(),
+		Request:   request,
+	}
+	if res != nil && err == nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			integration.Response = map[string]any{"rows_affected": n}
+		}
+	}
+	if err != nil {
+		integration.Error = err.Error()
+	}
+	trail.AddIntegration(integration)
+}
+
+func toNamedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func toValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, nv := range args {
+		values[i] = nv.Value
+	}
+	return values
+}
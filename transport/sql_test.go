@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aizacoders/gotrails/gotrails"
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "FAIL" {
+		return nil, errors.New("syntax error")
+	}
+	return driver.RowsAffected(2), nil
+}
+
+type fakeStmt struct{}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) { return fakeRows{}, nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"id"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestWrapDriverRecordsIntegrations(t *testing.T) {
+	sql.Register("gotrails-fake", WrapDriver(fakeDriver{}, WithSQLSystem("postgres"), WithSQLScrubLiterals(true)))
+	db, err := sql.Open("gotrails-fake", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	trail := gotrails.NewTrail("trace-1", "req-1", gotrails.NewConfig())
+	ctx := gotrails.WithTrail(context.Background(), trail)
+
+	if _, err := db.ExecContext(ctx, "UPDATE payments SET status = 'PAID' WHERE amount = 150000 AND id = $1", "pay-123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, "FAIL"); err == nil {
+		t.Fatal("expected error")
+	}
+	rows, err := db.QueryContext(ctx, "SELECT id FROM payments WHERE id = $1", "pay-123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, i := range trail.Integrations {
+		names = append(names, i.Name)
+	}
+	want := []string{"postgres.update", "postgres.fail", "postgres.select", "postgres.select", "postgres.begin", "postgres.commit"}
+	if len(names) != len(want) {
+		t.Fatalf("expected integrations %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected integrations %v, got %v", want, names)
+		}
+	}
+
+	update := trail.Integrations[0]
+	req := update.Request.(map[string]any)
+	if req["query"] != "UPDATE payments SET status = ? WHERE amount = ? AND id = $1" {
+		t.Fatalf("expected scrubbed query, got %v", req["query"])
+	}
+	if req["args_count"] != 1 {
+		t.Fatalf("expected 1 arg, got %v", req["args_count"])
+	}
+	if update.Response.(map[string]any)["rows_affected"] != int64(2) {
+		t.Fatalf("expected rows affected, got %v", update.Response)
+	}
+	if trail.Integrations[1].Error != "syntax error" {
+		t.Fatalf("expected error, got %q", trail.Integrations[1].Error)
+	}
+}
@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/aizacoders/gotrails/gotrails"
+	"github.com/aizacoders/gotrails/masker"
+)
+
+// teeBody wraps a response body, capturing up to maxSize bytes and feeding
+// every byte the caller reads to the digest, if any. done is called once,
+// when the body reaches EOF, fails or is closed.
+type teeBody struct {
+	body      io.ReadCloser
+	maxSize   int
+	buf       bytes.Buffer
+	digest    *gotrails.BodyDigest
+	size      int64
+	eof       bool
+	truncated bool
+	once      sync.Once
+	done      func(*teeBody)
+}
+
+func newTeeBody(body io.ReadCloser, maxSize int, digest *gotrails.BodyDigest, done func(*teeBody)) *teeBody {
+	return &teeBody{body: body, maxSize: maxSize, digest: digest, done: done}
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	if n > 0 {
+		t.size += int64(n)
+		if t.digest != nil {
+			t.digest.Write(p[:n])
+		}
+		if room := t.maxSize - t.buf.Len(); room > 0 {
+			t.buf.Write(p[:min(n, room)])
+		}
+		if t.buf.Len() < int(t.size) {
+			t.truncated = true
+		}
+	}
+	if err == io.EOF {
+		t.eof = true
+	}
+	if err != nil {
+		t.finish()
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	err := t.body.Close()
+	t.finish()
+	return err
+}
+
+func (t *teeBody) finish() {
+	t.once.Do(func() { t.done(t) })
+}
+
+// bodyDigest returns the digest of the complete body, "" when there is no
+// digest or the body was not read to the end
+func (t *teeBody) bodyDigest() string {
+	if t.digest == nil || !t.eof {
+		return ""
+	}
+	return t.digest.Sum()
+}
+
+// capturedBody returns the captured bytes as a masked JSON value or XML
+// string. A truncated body cannot be masked and is replaced by a marker.
+func (t *teeBody) capturedBody(cfg *gotrails.Config, msk *masker.Masker, contentType string) any {
+	return capturedBody(cfg, msk, contentType, t.buf.Bytes(), t.truncated)
+}